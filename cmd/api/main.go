@@ -87,6 +87,15 @@ func main() {
 			auth.POST("/login", c.AuthHandler.Login)
 		}
 
+		// Supported formats, open to everyone
+		v1.GET("/capabilities", c.PublicHandler.Capabilities)
+
+		// Public Routes, authorised by a URL signature instead of a token
+		public := v1.Group("/public")
+		{
+			public.GET("/images/:id/render", c.PublicHandler.Render)
+		}
+
 		// Protected Routes
 		protected := v1.Group("/")
 		protected.Use(c.AuthMiddleware.Handle())
@@ -98,8 +107,32 @@ func main() {
 			{
 				images.POST("", c.ImageHandler.Upload)
 				images.POST("/:id/transform", c.ImageHandler.Transform)
+				images.POST("/:id/transform/batch", c.ImageHandler.TransformBatch)
 				images.GET("", c.ImageHandler.List)
+				images.GET("/trash", c.ImageHandler.ListTrash)
 				images.GET("/:id", c.ImageHandler.Get)
+				images.GET("/:id/metadata", c.ImageHandler.GetMetadata)
+				images.DELETE("/:id", c.ImageHandler.Delete)
+				images.POST("/:id/restore", c.ImageHandler.Restore)
+				images.GET("/:id/original", c.ImageHandler.Original)
+				images.GET("/:id/variants/:variantId/content", c.ImageHandler.VariantContent)
+				images.DELETE("/:id/variants/:variantId", c.ImageHandler.DeleteVariant)
+				images.GET("/:id/render", c.ImageHandler.Render)
+				images.POST("/:id/signed-urls", c.ImageHandler.SignRenderURL)
+				images.GET("/:id/jobs/:jobId", c.ImageHandler.GetJob)
+				images.PUT("/:id/focal-point", c.ImageHandler.SetFocalPoint)
+				images.DELETE("/:id/focal-point", c.ImageHandler.ClearFocalPoint)
+			}
+
+			// Preset Routes
+			presets := protected.Group("/presets")
+			{
+				presets.POST("", c.PresetHandler.Create)
+				presets.GET("", c.PresetHandler.List)
+				presets.GET("/:name", c.PresetHandler.Get)
+				presets.GET("/:name/versions", c.PresetHandler.Versions)
+				presets.PUT("/:name", c.PresetHandler.Update)
+				presets.DELETE("/:name", c.PresetHandler.Delete)
 			}
 		}
 	}
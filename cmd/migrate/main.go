@@ -3,16 +3,22 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"log"
 	"os"
 	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"image-processing-service/internal/adapters/persistence"
+	appImage "image-processing-service/internal/application/image"
 	"image-processing-service/internal/config"
 	"image-processing-service/internal/database"
 )
 
+// rehashBatchSize is how many variants a rehash reads at a time.
+const rehashBatchSize = 500
+
 func loadEnv() {
 	file, err := os.Open(".env")
 	if err != nil {
@@ -42,6 +48,9 @@ func loadEnv() {
 }
 
 func main() {
+	rehash := flag.Bool("rehash", false, "rehash stored variants to the current spec hash version after migrating")
+	flag.Parse()
+
 	// 1. Load Env
 	loadEnv()
 
@@ -76,4 +85,15 @@ func main() {
 	}
 
 	log.Println("All migrations applied successfully.")
+
+	// 5. Rehash variants
+	if !*rehash {
+		return
+	}
+	uc := appImage.NewRehashVariantsUseCase(persistence.NewPostgresImageRepository(pool))
+	result, err := uc.Execute(ctx, rehashBatchSize)
+	if err != nil {
+		log.Fatalf("Rehash failed after %d variants: %v", result.Rehashed+result.Duplicates, err)
+	}
+	log.Printf("Rehashed %d variants; %d duplicates kept their old hash.", result.Rehashed, result.Duplicates)
 }
@@ -1,30 +1,111 @@
 package dto
 
-import "image"
+import (
+	"time"
+
+	"image-processing-service/internal/domain/image"
+)
 
 type ImageMetadataResponse struct {
 	Size     int64  `json:"size"`
 	MimeType string `json:"mime_type"`
 	Width    int    `json:"width"`
 	Height   int    `json:"height"`
+	// Animation is set for animated GIFs and WebPs.
+	Animation *image.Animation `json:"animation,omitempty"`
 }
 
 type UploadResponse struct {
 	ID          string                `json:"id"`
 	OriginalURL string                `json:"original_url"`
 	Metadata    ImageMetadataResponse `json:"metadata"`
+	// Jobs reports the eager specs of the upload and the eager presets.
+	Jobs []TransformBatchResult `json:"jobs,omitempty"`
 }
 
 type TransformResponse struct {
 	ID         string `json:"id"`
 	VariantKey string `json:"variant_key"`
+	SpecHash   string `json:"spec_hash"`
 	MimeType   string `json:"mime_type"`
 	Width      int    `json:"width"`
 	Height     int    `json:"height"`
 	Size       int64  `json:"size"`
+	// Quality is the encoder quality the variant was saved at; omitted for
+	// lossless formats.
+	Quality int `json:"quality,omitempty"`
+}
+
+// TransformRequest is a TransformationSpec, or the name of one of the
+// caller's presets whose fields the given spec fields replace.
+type TransformRequest struct {
+	image.TransformationSpec
+	Preset string `json:"preset,omitempty"`
+	// PresetVersion pins a version of Preset; the latest is used when zero.
+	PresetVersion int `json:"preset_version,omitempty" binding:"min=0"`
+}
+
+// TransformBatchRequest lists the specs of a batch transform, or names a
+// built-in set of specs in their place.
+type TransformBatchRequest struct {
+	Specs  []TransformRequest `json:"specs" binding:"dive"`
+	Preset string             `json:"preset"`
+}
+
+// EagerSpecs holds the eager form field of an upload for validation.
+type EagerSpecs struct {
+	Specs []TransformRequest `binding:"dive"`
+}
+
+type TransformBatchResponse struct {
+	Results []TransformBatchResult `json:"results"`
+}
+
+// TransformBatchResult is the outcome of the spec at Index: a variant when
+// it succeeded, a job when it was queued, an error when it failed.
+type TransformBatchResult struct {
+	Index       int                `json:"index"`
+	SpecHash    string             `json:"spec_hash,omitempty"`
+	Status      string             `json:"status"`
+	Variant     *TransformResponse `json:"variant,omitempty"`
+	JobID       string             `json:"job_id,omitempty"`
+	StatusURL   string             `json:"status_url,omitempty"`
+	DuplicateOf *int               `json:"duplicate_of,omitempty"`
+	Error       string             `json:"error,omitempty"`
 }
 
 type ListImagesResponse struct {
 	Images []*image.Image `json:"images"`
 	Total  int            `json:"total"`
+	// NextCursor and Next continue the listing; both are omitted on the
+	// last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+}
+
+type TransformAcceptedResponse struct {
+	Message   string `json:"message"`
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
+}
+
+type JobResponse struct {
+	ID          string     `json:"id"`
+	ImageID     string     `json:"image_id"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	SpecHash    string     `json:"spec_hash"`
+	VariantID   string     `json:"variant_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type SignedURLResponse struct {
+	URL       string    `json:"url"`
+	KeyID     string    `json:"key_id"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/preset"
+)
+
+type CreatePresetRequest struct {
+	Name  string                   `json:"name" binding:"required"`
+	Spec  image.TransformationSpec `json:"spec"`
+	Eager bool                     `json:"eager"`
+}
+
+// UpdatePresetRequest changes the fields that are given.
+type UpdatePresetRequest struct {
+	Spec  *image.TransformationSpec `json:"spec"`
+	Eager *bool                     `json:"eager"`
+}
+
+type ListPresetsResponse struct {
+	Presets []*preset.Preset `json:"presets"`
+}
+
+type PresetVersionsResponse struct {
+	Versions []*preset.Preset `json:"versions"`
+}
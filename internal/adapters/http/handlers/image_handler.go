@@ -1,16 +1,26 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
 
 	"image-processing-service/internal/adapters/http/dto"
 	appImage "image-processing-service/internal/application/image"
 	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
 	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
 )
 
 type ImageHandler struct {
@@ -19,14 +29,44 @@ type ImageHandler struct {
 	syncTransformUC  *appImage.TransformImageSyncUseCase
 	getUC            *appImage.GetImageUseCase
 	listUC           *appImage.ListImagesUseCase
+	getJobUC         *appImage.GetJobUseCase
+	setFocalPointUC  *appImage.SetFocalPointUseCase
+	renderUC         *appImage.RenderImageUseCase
+	signRenderURLUC  *appImage.SignRenderURLUseCase
+	publicBaseURL    string
+	contentUC        *appImage.GetImageContentUseCase
+	deleteUC         *appImage.DeleteImageUseCase
+	deleteVariantUC  *appImage.DeleteVariantUseCase
+	listTrashUC      *appImage.ListTrashUseCase
+	restoreUC        *appImage.RestoreImageUseCase
+	batchTransformUC *appImage.TransformImageBatchUseCase
+	metadataUC       *appImage.GetImageMetadataUseCase
 }
 
+// renderCacheControl lets the requesting browser keep rendered variants for
+// a day. They are private because the request is authenticated; signed URLs
+// are the public alternative. The ETag changes whenever the rendered bytes
+// would.
+const renderCacheControl = "private, max-age=86400"
+
 func NewImageHandler(
 	uploadUC *appImage.UploadImageUseCase,
 	asyncTransformUC *appImage.AsyncTransformImageUseCase,
 	syncTransformUC *appImage.TransformImageSyncUseCase,
 	getUC *appImage.GetImageUseCase,
 	listUC *appImage.ListImagesUseCase,
+	getJobUC *appImage.GetJobUseCase,
+	setFocalPointUC *appImage.SetFocalPointUseCase,
+	renderUC *appImage.RenderImageUseCase,
+	signRenderURLUC *appImage.SignRenderURLUseCase,
+	publicBaseURL string,
+	contentUC *appImage.GetImageContentUseCase,
+	deleteUC *appImage.DeleteImageUseCase,
+	deleteVariantUC *appImage.DeleteVariantUseCase,
+	listTrashUC *appImage.ListTrashUseCase,
+	restoreUC *appImage.RestoreImageUseCase,
+	batchTransformUC *appImage.TransformImageBatchUseCase,
+	metadataUC *appImage.GetImageMetadataUseCase,
 ) *ImageHandler {
 	return &ImageHandler{
 		uploadUC:         uploadUC,
@@ -34,6 +74,18 @@ func NewImageHandler(
 		syncTransformUC:  syncTransformUC,
 		getUC:            getUC,
 		listUC:           listUC,
+		getJobUC:         getJobUC,
+		setFocalPointUC:  setFocalPointUC,
+		renderUC:         renderUC,
+		signRenderURLUC:  signRenderURLUC,
+		publicBaseURL:    publicBaseURL,
+		contentUC:        contentUC,
+		deleteUC:         deleteUC,
+		deleteVariantUC:  deleteVariantUC,
+		listTrashUC:      listTrashUC,
+		restoreUC:        restoreUC,
+		batchTransformUC: batchTransformUC,
+		metadataUC:       metadataUC,
 	}
 }
 
@@ -45,6 +97,7 @@ func NewImageHandler(
 // @Produce json
 // @Security BearerAuth
 // @Param file formData file true "Image file to upload"
+// @Param eager formData string false "JSON array of specs or presets to queue once the image is saved"
 // @Success 201 {object} dto.UploadResponse "Image uploaded successfully"
 // @Failure 400 {object} map[string]interface{} "Invalid request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
@@ -64,6 +117,18 @@ func (h *ImageHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	var eager dto.EagerSpecs
+	if raw := c.PostForm("eager"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &eager.Specs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "eager must be a JSON array of transformation specs"})
+			return
+		}
+		if err := binding.Validator.ValidateStruct(&eager); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid eager transformation spec"})
+			return
+		}
+	}
+
 	file, err := fileHeader.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open file"})
@@ -79,43 +144,60 @@ func (h *ImageHandler) Upload(c *gin.Context) {
 		File:     file,
 		Size:     fileHeader.Size,
 		MimeType: fileHeader.Header.Get("Content-Type"),
+		Eager:    batchSpecs(eager.Specs),
 	}
 
-	img, err := h.uploadUC.Execute(c.Request.Context(), input)
+	result, err := h.uploadUC.Execute(c.Request.Context(), input)
 	if err != nil {
+		if errors.Is(err, appImage.ErrInvalidBatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("upload failed: %v", err)})
 		return
 	}
 
+	img := result.Image
 	c.JSON(http.StatusCreated, dto.UploadResponse{
 		ID:          string(img.ID),
-		OriginalURL: img.OriginalKey,
+		OriginalURL: fmt.Sprintf("/api/v1/images/%s/original", img.ID),
 		Metadata: dto.ImageMetadataResponse{
-			Size:     img.Size,
-			MimeType: img.MimeType,
-			Width:    img.Width,
-			Height:   img.Height,
+			Size:      img.Size,
+			MimeType:  img.MimeType,
+			Width:     img.Width,
+			Height:    img.Height,
+			Animation: img.Animation,
 		},
+		Jobs: batchResults(img.ID, result.Jobs),
 	})
 }
 
 // Transform handles image transformation
 // @Summary Transform an image
-// @Description Apply transformations to an image. Use sync=true for immediate response.
+// @Description Apply transformations to an image. Use sync=true for immediate response. Name one of your presets in "preset" to start from its spec; other fields given replace the preset's.
 // @Tags images
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Image ID"
 // @Param sync query boolean false "Perform transformation synchronously"
-// @Param spec body image.TransformationSpec true "Transformation metadata"
+// @Param spec body dto.TransformRequest true "Transformation spec, or a preset with overrides"
 // @Success 200 {object} dto.TransformResponse "Transformation result (sync)"
-// @Success 202 {object} map[string]interface{} "Transformation accepted (async)"
+// @Success 202 {object} dto.TransformAcceptedResponse "Transformation accepted (async)"
 // @Failure 400 {object} map[string]interface{} "Invalid request"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 422 {object} map[string]interface{} "Operation not supported by the configured processor"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /images/{id}/transform [post]
 func (h *ImageHandler) Transform(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := user.UserID(userIDStr.(string))
+
 	// Parse ID
 	idStr := c.Param("id")
 	if idStr == "" {
@@ -124,43 +206,459 @@ func (h *ImageHandler) Transform(c *gin.Context) {
 	}
 
 	// Parse Body (Spec)
-	var spec image.TransformationSpec
-	if err := c.ShouldBindJSON(&spec); err != nil {
+	var req dto.TransformRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transformation spec"})
 		return
 	}
 
 	imageID := image.ImageID(idStr)
 	isSync := c.Query("sync") == "true"
+	preset := appImage.PresetRef{Name: req.Preset, Version: req.PresetVersion}
 
 	if isSync {
 		input := appImage.SyncTransformInput{
 			ImageID: imageID,
-			Spec:    spec,
+			OwnerID: userID,
+			Spec:    req.TransformationSpec,
+			Preset:  preset,
+			Accept:  c.GetHeader("Accept"),
 		}
 		result, err := h.syncTransformUC.Execute(c.Request.Context(), input)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("sync transform failed: %v", err)})
+			transformError(c, err, "sync transform failed")
 			return
 		}
+		varyAccept(c, result.Negotiated)
 		c.JSON(http.StatusOK, result)
 		return
 	}
 
 	input := appImage.AsyncTransformInput{
 		ImageID: imageID,
-		Spec:    spec,
+		OwnerID: userID,
+		Spec:    req.TransformationSpec,
+		Preset:  preset,
+		Accept:  c.GetHeader("Accept"),
 	}
 	result, err := h.asyncTransformUC.Execute(c.Request.Context(), input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("transform failed: %v", err)})
+		transformError(c, err, "transform failed")
+		return
+	}
+
+	varyAccept(c, result.Negotiated)
+
+	c.JSON(http.StatusAccepted, dto.TransformAcceptedResponse{
+		Message:   "Transformation accepted",
+		JobID:     result.ID,
+		Status:    string(result.Status),
+		StatusURL: fmt.Sprintf("/api/v1/images/%s/jobs/%s", imageID, result.ID),
+	})
+}
+
+// TransformBatch handles transforming an image with several specs at once
+// @Summary Transform an image with a batch of specs
+// @Description Render or queue several specs, or a named preset, of one image. Specs with the same hash are processed once. Use sync=true to render before responding; otherwise one grouped job is queued. Specs that cannot be rendered fail on their own in the results.
+// @Tags images
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param sync query boolean false "Perform transformations synchronously"
+// @Param batch body dto.TransformBatchRequest true "Specs or preset"
+// @Success 200 {object} dto.TransformBatchResponse "Results (sync)"
+// @Success 202 {object} dto.TransformBatchResponse "Results (async)"
+// @Failure 400 {object} map[string]interface{} "Invalid batch"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/transform/batch [post]
+func (h *ImageHandler) TransformBatch(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := user.UserID(userIDStr.(string))
+
+	var req dto.TransformBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transformation batch"})
+		return
+	}
+
+	imageID := image.ImageID(c.Param("id"))
+	isSync := c.Query("sync") == "true"
+
+	result, err := h.batchTransformUC.Execute(c.Request.Context(), appImage.BatchTransformInput{
+		ImageID: imageID,
+		OwnerID: userID,
+		Specs:   batchSpecs(req.Specs),
+		Preset:  req.Preset,
+		Async:   !isSync,
+		Accept:  c.GetHeader("Accept"),
+	})
+	if err != nil {
+		if errors.Is(err, appImage.ErrInvalidBatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		transformError(c, err, "batch transform failed")
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"message":    "Transformation accepted",
-		"variant_id": result.ID,
+	status := http.StatusAccepted
+	if isSync {
+		status = http.StatusOK
+	}
+	varyAccept(c, result.Negotiated)
+	c.JSON(status, dto.TransformBatchResponse{Results: batchResults(imageID, result.Results)})
+}
+
+func batchSpecs(reqs []dto.TransformRequest) []appImage.BatchSpec {
+	specs := make([]appImage.BatchSpec, len(reqs))
+	for i, r := range reqs {
+		specs[i] = appImage.BatchSpec{
+			Spec:   r.TransformationSpec,
+			Preset: appImage.PresetRef{Name: r.Preset, Version: r.PresetVersion},
+		}
+	}
+	return specs
+}
+
+func batchResults(imageID image.ImageID, results []appImage.BatchItemResult) []dto.TransformBatchResult {
+	out := make([]dto.TransformBatchResult, len(results))
+	for i, r := range results {
+		item := dto.TransformBatchResult{
+			Index:       r.Index,
+			SpecHash:    r.SpecHash,
+			Status:      string(r.Status),
+			JobID:       r.JobID,
+			DuplicateOf: r.DuplicateOf,
+			Error:       r.Error,
+		}
+		if r.Variant != nil {
+			item.Variant = &dto.TransformResponse{
+				ID:         r.Variant.ID,
+				VariantKey: r.Variant.VariantKey,
+				SpecHash:   r.Variant.SpecHash,
+				MimeType:   r.Variant.MimeType,
+				Width:      r.Variant.Width,
+				Height:     r.Variant.Height,
+				Size:       r.Variant.Size,
+				Quality:    r.Variant.Quality,
+			}
+		}
+		if r.JobID != "" {
+			item.StatusURL = fmt.Sprintf("/api/v1/images/%s/jobs/%s", imageID, r.JobID)
+		}
+		out[i] = item
+	}
+	return out
+}
+
+// Render handles on-the-fly transformation URLs
+// @Summary Render an image variant
+// @Description Parse query parameters into a transformation spec and stream the variant, rendering it on first use. See docs/api.md for the parameters.
+// @Tags images
+// @Produce image/jpeg,image/png,image/webp,image/gif,image/avif,image/heif,image/tiff
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param w query int false "Resize width"
+// @Param h query int false "Resize height"
+// @Param fit query string false "Resize fit mode"
+// @Param fmt query string false "Output format, or auto to pick one from the Accept header"
+// @Param q query int false "Output quality"
+// @Success 200 {file} binary "Variant bytes"
+// @Success 304 "Not modified"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 422 {object} map[string]interface{} "Operation not supported by the configured processor"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/render [get]
+func (h *ImageHandler) Render(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	spec, err := parseRenderQuery(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.renderUC.Execute(c.Request.Context(), appImage.RenderInput{
+		ImageID:     image.ImageID(c.Param("id")),
+		OwnerID:     user.UserID(userIDStr.(string)),
+		Spec:        *spec,
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+		Accept:      c.GetHeader("Accept"),
 	})
+	if err != nil {
+		transformError(c, err, "render failed")
+		return
+	}
+
+	writeRender(c, result, renderCacheControl)
+}
+
+// writeRender streams a rendered variant, or answers 304 when the client's
+// copy is current.
+func writeRender(c *gin.Context, result *appImage.RenderOutput, cacheControl string) {
+	c.Header("ETag", result.ETag)
+	c.Header("Cache-Control", cacheControl)
+	varyAccept(c, result.Variant.Negotiated)
+	if result.Content == nil {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	defer func() {
+		_ = result.Content.Close()
+	}()
+
+	c.DataFromReader(http.StatusOK, result.Variant.Size, result.Variant.MimeType, result.Content, nil)
+}
+
+// varyAccept tells caches the response depends on the Accept header when
+// format auto was negotiated from it.
+func varyAccept(c *gin.Context, negotiated bool) {
+	if negotiated {
+		c.Writer.Header().Add("Vary", "Accept")
+	}
+}
+
+// SignRenderURL handles minting public render URLs
+// @Summary Create a signed render URL
+// @Description Sign the render parameters in the query string into a public URL that works without authentication until it expires
+// @Tags images
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param expires_in query int false "Lifetime in seconds; defaults to URL_SIGNING_DEFAULT_TTL"
+// @Param w query int false "Resize width"
+// @Param h query int false "Resize height"
+// @Param fmt query string false "Output format, or auto to pick one from the Accept header"
+// @Success 201 {object} dto.SignedURLResponse "Signed URL"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 503 {object} map[string]interface{} "URL signing is not configured"
+// @Router /images/{id}/signed-urls [post]
+func (h *ImageHandler) SignRenderURL(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	query := c.Request.URL.Query()
+	spec, err := parseRenderQuery(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ttl time.Duration
+	if v := query.Get("expires_in"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expires_in: not an integer"})
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	imageID := image.ImageID(c.Param("id"))
+	result, err := h.signRenderURLUC.Execute(c.Request.Context(), appImage.SignRenderURLInput{
+		ImageID: imageID,
+		OwnerID: user.UserID(userIDStr.(string)),
+		Spec:    *spec,
+		TTL:     ttl,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, appImage.ErrImageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		case errors.Is(err, appImage.ErrInvalidTTL):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, appImage.ErrSigningDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign URL"})
+		}
+		return
+	}
+
+	query.Del("expires_in")
+	query.Set(signedExpiresParam, strconv.FormatInt(result.ExpiresAt.Unix(), 10))
+	query.Set(signedKeyIDParam, result.KeyID)
+	query.Set(signedSignatureParam, result.Signature)
+
+	c.JSON(http.StatusCreated, dto.SignedURLResponse{
+		URL:       fmt.Sprintf("%s/api/v1/public/images/%s/render?%s", h.publicBaseURL, imageID, query.Encode()),
+		KeyID:     result.KeyID,
+		ExpiresAt: result.ExpiresAt,
+	})
+}
+
+// transformError answers a failed transform request. Specs that cannot be
+// rendered are the client's fault; anything else is reported with prefix.
+func transformError(c *gin.Context, err error, prefix string) {
+	var formatErr *appImage.UnsupportedFormatError
+	switch {
+	case errors.Is(err, appImage.ErrImageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+	case errors.Is(err, appImage.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	case errors.Is(err, appImage.ErrWatermarkImageNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "watermark image not found"})
+	case errors.Is(err, appImage.ErrPresetNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, image.ErrCropOutOfBounds), errors.Is(err, image.ErrFrameOutOfRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &formatErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "formats": formatErr.Available})
+	case errors.Is(err, ports.ErrUnsupportedOperation), errors.Is(err, image.ErrMaxBytesUnreachable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s: %v", prefix, err)})
+	}
+}
+
+// SetFocalPoint handles setting the focal point of an image
+// @Summary Set an image's focal point
+// @Description Store the point, in relative coordinates, that focal crops and cover resizes centre on
+// @Tags images
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param focal_point body image.FocalPoint true "Focal point"
+// @Success 200 {object} image.Image "Updated image"
+// @Failure 400 {object} map[string]interface{} "Invalid focal point"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/focal-point [put]
+func (h *ImageHandler) SetFocalPoint(c *gin.Context) {
+	h.setFocalPoint(c, true)
+}
+
+// ClearFocalPoint handles removing the focal point of an image
+// @Summary Clear an image's focal point
+// @Description Remove the focal point so focal crops and cover resizes centre on the image
+// @Tags images
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Success 200 {object} image.Image "Updated image"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/focal-point [delete]
+func (h *ImageHandler) ClearFocalPoint(c *gin.Context) {
+	h.setFocalPoint(c, false)
+}
+
+func (h *ImageHandler) setFocalPoint(c *gin.Context, set bool) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	input := appImage.SetFocalPointInput{
+		ImageID: image.ImageID(c.Param("id")),
+		OwnerID: user.UserID(userIDStr.(string)),
+	}
+	if set {
+		var fp image.FocalPoint
+		if err := c.ShouldBindJSON(&fp); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid focal point"})
+			return
+		}
+		input.FocalPoint = &fp
+	}
+
+	img, err := h.setFocalPointUC.Execute(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, appImage.ErrImageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		case errors.Is(err, image.ErrInvalidFocalPoint):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set focal point"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, img)
+}
+
+// GetJob handles fetching the status of an async transformation
+// @Summary Get transformation job status
+// @Description Poll the state of an asynchronous transformation job
+// @Tags images
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param jobId path string true "Job ID"
+// @Success 200 {object} dto.JobResponse "Job status"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Job not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/jobs/{jobId} [get]
+func (h *ImageHandler) GetJob(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	idStr := c.Param("id")
+	jobIDStr := c.Param("jobId")
+	if _, err := uuid.Parse(jobIDStr); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+
+	input := appImage.GetJobInput{
+		ImageID: image.ImageID(idStr),
+		JobID:   job.JobID(jobIDStr),
+		OwnerID: user.UserID(userIDStr.(string)),
+	}
+	j, err := h.getJobUC.Execute(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, appImage.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job"})
+		return
+	}
+
+	resp := dto.JobResponse{
+		ID:          string(j.ID),
+		ImageID:     string(j.ImageID),
+		Status:      string(j.Status),
+		Attempts:    j.Attempts,
+		Error:       j.Error,
+		SpecHash:    j.SpecHash,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+		StartedAt:   j.StartedAt,
+		CompletedAt: j.CompletedAt,
+	}
+	if j.VariantID != nil {
+		resp.VariantID = j.VariantID.String()
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 // Get handles fetching image details
@@ -173,36 +671,361 @@ func (h *ImageHandler) Transform(c *gin.Context) {
 // @Success 200 {object} image.Image "Image details"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /images/{id} [get]
 func (h *ImageHandler) Get(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	idStr := c.Param("id")
 	if idStr == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "image id required"})
 		return
 	}
 
-	img, err := h.getUC.Execute(c.Request.Context(), image.ImageID(idStr))
+	img, err := h.getUC.Execute(c.Request.Context(), appImage.GetImageInput{
+		ImageID: image.ImageID(idStr),
+		OwnerID: user.UserID(userIDStr.(string)),
+	})
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		switch {
+		case errors.Is(err, appImage.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		case errors.Is(err, appImage.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get image"})
+		}
 		return
 	}
-	if img == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+
+	c.JSON(http.StatusOK, img)
+}
+
+// GetMetadata handles fetching the photo metadata of an image
+// @Summary Get image metadata
+// @Description Fetch the camera, capture time, GPS position and colour profile read from the original on upload. Fields the original does not record are omitted; the object is empty for images uploaded before metadata was read.
+// @Tags images
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Success 200 {object} image.PhotoMetadata "Image metadata"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/metadata [get]
+func (h *ImageHandler) GetMetadata(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	metadata, err := h.metadataUC.Execute(c.Request.Context(), appImage.GetImageMetadataInput{
+		ImageID: image.ImageID(c.Param("id")),
+		OwnerID: user.UserID(userIDStr.(string)),
+	})
+	if err != nil {
+		if errors.Is(err, appImage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get image metadata"})
+		return
+	}
+
+	c.JSON(http.StatusOK, metadata)
+}
+
+// Delete handles deleting an image
+// @Summary Delete an image
+// @Description Move an image to the trash, where it can be restored until the retention passes. With permanent=true, delete it with its variants and jobs right away, also from the trash; stored objects that cannot be removed immediately are retried in the background.
+// @Tags images
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param permanent query boolean false "Skip the trash"
+// @Success 204 "Image deleted"
+// @Failure 400 {object} map[string]interface{} "Invalid permanent"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id} [delete]
+func (h *ImageHandler) Delete(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	permanent := false
+	if v := c.Query("permanent"); v != "" {
+		var err error
+		if permanent, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "permanent must be true or false"})
+			return
+		}
+	}
+
+	err := h.deleteUC.Execute(c.Request.Context(), appImage.DeleteImageInput{
+		ImageID:   image.ImageID(c.Param("id")),
+		OwnerID:   user.UserID(userIDStr.(string)),
+		Permanent: permanent,
+	})
+	if err != nil {
+		if errors.Is(err, appImage.ErrImageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete image"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListTrash handles listing trashed images
+// @Summary List trashed images
+// @Description List the user's deleted images that can still be restored, most recently deleted first
+// @Tags images
+// @Produce json
+// @Security BearerAuth
+// @Param offset query int false "Offset for pagination" default(0)
+// @Param limit query int false "Limit for pagination" default(10)
+// @Success 200 {object} appImage.ListTrashOutput "Trashed images with their purge time"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/trash [get]
+func (h *ImageHandler) ListTrash(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	input := appImage.ListTrashInput{
+		OwnerID: user.UserID(userIDStr.(string)),
+	}
+	input.Offset, _ = strconv.Atoi(c.Query("offset"))
+	input.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	result, err := h.listTrashUC.Execute(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Restore handles restoring a trashed image
+// @Summary Restore a trashed image
+// @Description Take a deleted image out of the trash before its retention passes
+// @Tags images
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Success 200 {object} image.Image "Restored image"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not in the trash"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/restore [post]
+func (h *ImageHandler) Restore(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	img, err := h.restoreUC.Execute(c.Request.Context(), appImage.RestoreImageInput{
+		ImageID: image.ImageID(c.Param("id")),
+		OwnerID: user.UserID(userIDStr.(string)),
+	})
+	if err != nil {
+		if errors.Is(err, appImage.ErrImageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore image"})
 		return
 	}
 
 	c.JSON(http.StatusOK, img)
 }
 
+// DeleteVariant handles deleting a variant
+// @Summary Delete a variant
+// @Description Delete one variant of an image and its stored object. Requesting the same transformation again renders it anew.
+// @Tags images
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param variantId path string true "Variant ID"
+// @Success 204 "Variant deleted"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image or variant not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/variants/{variantId} [delete]
+func (h *ImageHandler) DeleteVariant(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+
+	err = h.deleteVariantUC.Execute(c.Request.Context(), appImage.DeleteVariantInput{
+		ImageID:   image.ImageID(c.Param("id")),
+		OwnerID:   user.UserID(userIDStr.(string)),
+		VariantID: variantID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, appImage.ErrImageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		case errors.Is(err, appImage.ErrVariantNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete variant"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Original handles downloading an original image
+// @Summary Download an original image
+// @Description Stream the uploaded bytes. Supports If-None-Match, and Range requests when storage can seek; add download=true for an attachment.
+// @Tags images
+// @Produce image/jpeg,image/png,image/webp,image/gif,image/avif,image/heif,image/tiff
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param download query boolean false "Send as an attachment"
+// @Success 200 {file} binary "Image bytes"
+// @Success 206 {file} binary "Requested range"
+// @Success 304 "Not modified"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 416 {object} map[string]interface{} "Range not satisfiable"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/original [get]
+func (h *ImageHandler) Original(c *gin.Context) {
+	h.content(c, uuid.Nil)
+}
+
+// VariantContent handles downloading a variant
+// @Summary Download a variant
+// @Description Stream the bytes of a rendered variant. Supports If-None-Match, and Range requests when storage can seek; add download=true for an attachment.
+// @Tags images
+// @Produce image/jpeg,image/png,image/webp,image/gif,image/avif,image/heif,image/tiff
+// @Security BearerAuth
+// @Param id path string true "Image ID"
+// @Param variantId path string true "Variant ID"
+// @Param download query boolean false "Send as an attachment"
+// @Success 200 {file} binary "Variant bytes"
+// @Success 206 {file} binary "Requested range"
+// @Success 304 "Not modified"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Image or variant not found"
+// @Failure 416 {object} map[string]interface{} "Range not satisfiable"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /images/{id}/variants/{variantId}/content [get]
+func (h *ImageHandler) VariantContent(c *gin.Context) {
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		return
+	}
+	h.content(c, variantID)
+}
+
+func (h *ImageHandler) content(c *gin.Context, variantID uuid.UUID) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	result, err := h.contentUC.Execute(c.Request.Context(), appImage.GetImageContentInput{
+		ImageID:     image.ImageID(c.Param("id")),
+		OwnerID:     user.UserID(userIDStr.(string)),
+		VariantID:   variantID,
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, appImage.ErrImageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		case errors.Is(err, appImage.ErrVariantNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "variant not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get image content"})
+		}
+		return
+	}
+
+	c.Header("ETag", result.ETag)
+	c.Header("Cache-Control", renderCacheControl)
+	if result.Content == nil {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	defer func() {
+		_ = result.Content.Close()
+	}()
+
+	disposition := "inline"
+	if download, _ := strconv.ParseBool(c.Query("download")); download {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": result.Filename}))
+	c.Header("Content-Type", result.MimeType)
+
+	// Range requests need to seek. Storage backends that only stream send
+	// the whole file instead, without buffering it.
+	content, ok := result.Content.(io.ReadSeeker)
+	if !ok {
+		size := result.Size
+		if size <= 0 {
+			size = -1
+		}
+		c.DataFromReader(http.StatusOK, size, result.MimeType, result.Content, map[string]string{
+			"Accept-Ranges": "none",
+			"Last-Modified": result.CreatedAt.UTC().Format(http.TimeFormat),
+		})
+		return
+	}
+	http.ServeContent(c.Writer, c.Request, "", result.CreatedAt, content)
+}
+
 // List handles listing user images
 // @Summary List user images
-// @Description List all images owned by the user with pagination
+// @Description List the user's images with filters, sorting and offset or cursor pagination. Follow next to fetch the following page.
 // @Tags images
 // @Produce json
 // @Security BearerAuth
-// @Param offset query int false "Offset for pagination" default(0)
-// @Param limit query int false "Limit for pagination" default(10)
+// @Param offset query int false "Offset for pagination, ignored with a cursor" default(0)
+// @Param limit query int false "Limit for pagination, at most 100" default(10)
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param mime query string false "Comma-separated MIME types"
+// @Param created_after query string false "RFC 3339 timestamp or date, inclusive"
+// @Param created_before query string false "RFC 3339 timestamp or date, exclusive"
+// @Param min_width query int false "Minimum width"
+// @Param max_width query int false "Maximum width"
+// @Param min_height query int false "Minimum height"
+// @Param max_height query int false "Maximum height"
+// @Param filename query string false "Case-insensitive filename substring"
+// @Param sort query string false "Sort field" Enums(created_at, size, filename) default(created_at)
+// @Param order query string false "Sort order; desc by default except for filename" Enums(asc, desc)
 // @Success 200 {object} dto.ListImagesResponse "List of images"
+// @Failure 400 {object} map[string]interface{} "Invalid filter, sort or cursor"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /images [get]
@@ -214,18 +1037,39 @@ func (h *ImageHandler) List(c *gin.Context) {
 	}
 	userID := user.UserID(userIDStr.(string))
 
-	input := appImage.ListImagesInput{
-		OwnerID: userID,
-		Offset:  0,
-		Limit:   10,
+	query := c.Request.URL.Query()
+	criteria, err := parseListQuery(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	// TODO: Parse offset/limit from query params if needed
+	criteria.OwnerID = userID
 
-	result, err := h.listUC.Execute(c.Request.Context(), input)
+	result, err := h.listUC.Execute(c.Request.Context(), appImage.ListImagesInput{
+		Criteria: criteria,
+		Cursor:   query.Get("cursor"),
+	})
 	if err != nil {
+		if errors.Is(err, image.ErrInvalidListCriteria) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list images"})
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	resp := dto.ListImagesResponse{
+		Images:     result.Images,
+		Total:      result.Total,
+		NextCursor: result.NextCursor,
+	}
+	if result.NextCursor != "" {
+		// Keep the filters and sort; the cursor replaces the offset
+		query.Del("offset")
+		query.Set("cursor", result.NextCursor)
+		resp.Next = c.Request.URL.Path + "?" + query.Encode()
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"next\"", resp.Next))
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
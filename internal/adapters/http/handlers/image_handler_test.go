@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	appImage "image-processing-service/internal/application/image"
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+type fakeImageRepository struct {
+	ports.ImageRepository
+	img *image.Image
+}
+
+func (r *fakeImageRepository) GetByID(ctx context.Context, id image.ImageID) (*image.Image, error) {
+	return r.img, nil
+}
+
+// streamingStorage returns bodies that cannot seek, like Cloudinary's.
+type streamingStorage struct {
+	ports.ObjectStorage
+	data string
+}
+
+func (s *streamingStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(s.data)), nil
+}
+
+func TestOriginalStreamsContentThatCannotSeek(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	img := &image.Image{
+		ID:          "img-1",
+		OwnerID:     "user-1",
+		Filename:    "photo.jpg",
+		OriginalKey: "originals/img-1",
+		MimeType:    "image/jpeg",
+		Size:        10,
+		CreatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	h := &ImageHandler{contentUC: appImage.NewGetImageContentUseCase(
+		&fakeImageRepository{img: img},
+		&streamingStorage{data: "0123456789"},
+	)}
+	r := gin.New()
+	r.GET("/images/:id/original", func(c *gin.Context) {
+		c.Set("userID", "user-1")
+		h.Original(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/images/img-1/original", nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0123456789", w.Body.String())
+	assert.Equal(t, "10", w.Header().Get("Content-Length"))
+	assert.Equal(t, "none", w.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, `"img-1"`, w.Header().Get("ETag"))
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"image-processing-service/internal/domain/image"
+)
+
+// parseListQuery builds ListCriteria from the query string of GET /images.
+// The owner and the cursor's position are filled in by the caller and the
+// use case.
+//
+//	offset, limit, cursor
+//	mime                        comma-separated MIME types
+//	created_after, created_before  RFC 3339 timestamps or dates
+//	min_width, max_width, min_height, max_height
+//	filename                    case-insensitive substring
+//	sort, order                 created_at, size or filename; asc or desc
+func parseListQuery(q url.Values) (image.ListCriteria, error) {
+	p := queryParser{values: q}
+	c := image.ListCriteria{
+		Offset:           p.int("offset"),
+		Limit:            p.int("limit"),
+		MinWidth:         p.int("min_width"),
+		MaxWidth:         p.int("max_width"),
+		MinHeight:        p.int("min_height"),
+		MaxHeight:        p.int("max_height"),
+		FilenameContains: q.Get("filename"),
+		CreatedAfter:     p.time("created_after"),
+		CreatedBefore:    p.time("created_before"),
+		SortBy:           image.SortField(q.Get("sort")),
+	}
+	for _, mime := range strings.Split(q.Get("mime"), ",") {
+		if mime = strings.TrimSpace(mime); mime != "" {
+			c.MimeTypes = append(c.MimeTypes, mime)
+		}
+	}
+
+	// Newest and largest first, names alphabetically, unless asked otherwise
+	if c.SortBy == "" {
+		c.SortBy = image.SortByCreatedAt
+	}
+	switch q.Get("order") {
+	case "":
+		c.Descending = c.SortBy != image.SortByFilename
+	case "asc":
+	case "desc":
+		c.Descending = true
+	default:
+		p.fail("order", "must be asc or desc")
+	}
+
+	return c, p.err
+}
+
+// time accepts RFC 3339 timestamps and plain dates, which mean midnight UTC.
+func (p *queryParser) time(key string) *time.Time {
+	v := p.values.Get(key)
+	if v == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t
+		}
+	}
+	p.fail(key, "not an RFC 3339 timestamp or date")
+	return nil
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"image-processing-service/internal/adapters/http/dto"
+	appImage "image-processing-service/internal/application/image"
+	"image-processing-service/internal/domain/preset"
+	"image-processing-service/internal/domain/user"
+)
+
+// PresetHandler serves the user's named transformation presets.
+type PresetHandler struct {
+	createUC *appImage.CreatePresetUseCase
+	updateUC *appImage.UpdatePresetUseCase
+	getUC    *appImage.GetPresetUseCase
+	listUC   *appImage.ListPresetsUseCase
+	deleteUC *appImage.DeletePresetUseCase
+}
+
+func NewPresetHandler(
+	createUC *appImage.CreatePresetUseCase,
+	updateUC *appImage.UpdatePresetUseCase,
+	getUC *appImage.GetPresetUseCase,
+	listUC *appImage.ListPresetsUseCase,
+	deleteUC *appImage.DeletePresetUseCase,
+) *PresetHandler {
+	return &PresetHandler{
+		createUC: createUC,
+		updateUC: updateUC,
+		getUC:    getUC,
+		listUC:   listUC,
+		deleteUC: deleteUC,
+	}
+}
+
+// Create handles saving a new preset
+// @Summary Create a preset
+// @Description Save a transformation spec under a name for use as "preset" in transform requests. Eager presets are queued for every image you upload.
+// @Tags presets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param preset body dto.CreatePresetRequest true "Name and spec"
+// @Success 201 {object} preset.Preset "Version 1 of the preset"
+// @Failure 400 {object} map[string]interface{} "Invalid or reserved name, invalid spec or too many eager presets"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Name already used"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /presets [post]
+func (h *PresetHandler) Create(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req dto.CreatePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid preset"})
+		return
+	}
+
+	p, err := h.createUC.Execute(c.Request.Context(), appImage.PresetInput{
+		OwnerID: user.UserID(userIDStr.(string)),
+		Name:    req.Name,
+		Spec:    req.Spec,
+		Eager:   req.Eager,
+	})
+	if err != nil {
+		presetError(c, err, "failed to create preset")
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// Update handles revising a preset
+// @Summary Update a preset
+// @Description Save a new spec as the next version of a preset and/or change whether it is eager. Earlier versions stay available; variants rendered from them are kept.
+// @Tags presets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Preset name"
+// @Param preset body dto.UpdatePresetRequest true "New spec and eager flag, both optional"
+// @Success 200 {object} preset.Preset "Latest version of the preset"
+// @Failure 400 {object} map[string]interface{} "Invalid spec or too many eager presets"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Preset not found"
+// @Failure 409 {object} map[string]interface{} "Preset changed concurrently"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /presets/{name} [put]
+func (h *PresetHandler) Update(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req dto.UpdatePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid preset"})
+		return
+	}
+
+	p, err := h.updateUC.Execute(c.Request.Context(), appImage.UpdatePresetInput{
+		OwnerID: user.UserID(userIDStr.(string)),
+		Name:    c.Param("name"),
+		Spec:    req.Spec,
+		Eager:   req.Eager,
+	})
+	if err != nil {
+		presetError(c, err, "failed to update preset")
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// Get handles fetching a preset
+// @Summary Get a preset
+// @Description Fetch the latest version of a preset, or the one given by version
+// @Tags presets
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Preset name"
+// @Param version query int false "Version to fetch"
+// @Success 200 {object} preset.Preset "Preset"
+// @Failure 400 {object} map[string]interface{} "Invalid version"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Preset not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /presets/{name} [get]
+func (h *PresetHandler) Get(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	version := 0
+	if v := c.Query("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "version must be a positive integer"})
+			return
+		}
+		version = n
+	}
+
+	p, err := h.getUC.Execute(c.Request.Context(), user.UserID(userIDStr.(string)), c.Param("name"), version)
+	if err != nil {
+		presetError(c, err, "failed to get preset")
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// Versions handles listing the versions of a preset
+// @Summary List preset versions
+// @Description List every version of a preset, newest first
+// @Tags presets
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Preset name"
+// @Success 200 {object} dto.PresetVersionsResponse "Versions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Preset not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /presets/{name}/versions [get]
+func (h *PresetHandler) Versions(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	versions, err := h.getUC.Versions(c.Request.Context(), user.UserID(userIDStr.(string)), c.Param("name"))
+	if err != nil {
+		presetError(c, err, "failed to list preset versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.PresetVersionsResponse{Versions: versions})
+}
+
+// List handles listing presets
+// @Summary List presets
+// @Description List the latest version of each of the user's presets, by name
+// @Tags presets
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.ListPresetsResponse "Presets"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /presets [get]
+func (h *PresetHandler) List(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	presets, err := h.listUC.Execute(c.Request.Context(), user.UserID(userIDStr.(string)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list presets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ListPresetsResponse{Presets: presets})
+}
+
+// Delete handles deleting a preset
+// @Summary Delete a preset
+// @Description Delete a preset with all its versions. Variants rendered from it are kept.
+// @Tags presets
+// @Security BearerAuth
+// @Param name path string true "Preset name"
+// @Success 204 "Preset deleted"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Preset not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /presets/{name} [delete]
+func (h *PresetHandler) Delete(c *gin.Context) {
+	userIDStr, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.deleteUC.Execute(c.Request.Context(), user.UserID(userIDStr.(string)), c.Param("name")); err != nil {
+		presetError(c, err, "failed to delete preset")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// presetError answers a failed preset request, hiding internal errors
+// behind message.
+func presetError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, appImage.ErrPresetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "preset not found"})
+	case errors.Is(err, appImage.ErrPresetExists), errors.Is(err, appImage.ErrPresetConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, preset.ErrInvalidName), errors.Is(err, appImage.ErrPresetNameReserved), errors.Is(err, appImage.ErrTooManyEagerPresets):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
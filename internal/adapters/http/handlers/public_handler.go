@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	appImage "image-processing-service/internal/application/image"
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// Query parameters that carry the signature of a public render URL.
+const (
+	signedExpiresParam   = "exp"
+	signedKeyIDParam     = "kid"
+	signedSignatureParam = "sig"
+)
+
+// maxPublicCacheAge caps how long caches keep a signed render, which is
+// never longer than the URL stays valid.
+const maxPublicCacheAge = 24 * time.Hour
+
+// PublicHandler serves the routes that need no authentication.
+type PublicHandler struct {
+	renderSignedUC *appImage.RenderSignedImageUseCase
+	capabilitiesUC *appImage.GetCapabilitiesUseCase
+}
+
+func NewPublicHandler(renderSignedUC *appImage.RenderSignedImageUseCase, capabilitiesUC *appImage.GetCapabilitiesUseCase) *PublicHandler {
+	return &PublicHandler{
+		renderSignedUC: renderSignedUC,
+		capabilitiesUC: capabilitiesUC,
+	}
+}
+
+// Capabilities handles listing the supported image formats
+// @Summary List supported formats
+// @Description List the formats images can be uploaded in (input) and rendered to with the spec's format (output). Output formats depend on how the server was built. No authentication is needed.
+// @Tags public
+// @Produce json
+// @Success 200 {object} ports.ProcessorCapabilities "Supported formats"
+// @Router /capabilities [get]
+func (h *PublicHandler) Capabilities(c *gin.Context) {
+	c.JSON(http.StatusOK, h.capabilitiesUC.Execute())
+}
+
+// Render handles signed public render URLs
+// @Summary Render an image from a signed URL
+// @Description Stream the variant described by a URL minted with POST /images/{id}/signed-urls. No authentication is needed.
+// @Tags public
+// @Produce image/jpeg,image/png,image/webp,image/gif,image/avif,image/heif,image/tiff
+// @Param id path string true "Image ID"
+// @Param exp query int true "Expiry as a Unix timestamp"
+// @Param kid query string true "Signing key ID"
+// @Param sig query string true "Signature"
+// @Success 200 {file} binary "Variant bytes"
+// @Success 304 "Not modified"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 403 {object} map[string]interface{} "Invalid or expired signature"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /public/images/{id}/render [get]
+func (h *PublicHandler) Render(c *gin.Context) {
+	query := c.Request.URL.Query()
+	expires, err := strconv.ParseInt(query.Get(signedExpiresParam), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid signature"})
+		return
+	}
+
+	spec, err := parseRenderQuery(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expiresAt := time.Unix(expires, 0).UTC()
+	result, err := h.renderSignedUC.Execute(c.Request.Context(), appImage.RenderSignedInput{
+		ImageID:     image.ImageID(c.Param("id")),
+		Spec:        *spec,
+		ExpiresAt:   expiresAt,
+		KeyID:       query.Get(signedKeyIDParam),
+		Signature:   query.Get(signedSignatureParam),
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+		Accept:      c.GetHeader("Accept"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ports.ErrInvalidSignature):
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid signature"})
+		case errors.Is(err, appImage.ErrSignatureExpired):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, appImage.ErrSigningDisabled):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		default:
+			transformError(c, err, "render failed")
+		}
+		return
+	}
+
+	maxAge := min(time.Until(expiresAt), maxPublicCacheAge)
+	writeRender(c, result, fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appImage "image-processing-service/internal/application/image"
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+type fakeProcessor struct {
+	ports.ImageProcessor
+	caps ports.ProcessorCapabilities
+}
+
+func (p *fakeProcessor) Capabilities() ports.ProcessorCapabilities {
+	return p.caps
+}
+
+func TestCapabilities(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	caps := ports.ProcessorCapabilities{Input: []string{"jpeg", "png", "gif"}, Output: []string{"jpeg", "png"}}
+	h := NewPublicHandler(nil, appImage.NewGetCapabilitiesUseCase(&fakeProcessor{caps: caps}))
+	r := gin.New()
+	r.GET("/capabilities", h.Capabilities)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/capabilities", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got ports.ProcessorCapabilities
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, caps, got)
+}
+
+func TestTransformErrorRejectsUnrenderableSpecs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		err     error
+		message error
+		formats []string
+	}{
+		{"format", fmt.Errorf("render: %w", &appImage.UnsupportedFormatError{Format: "avif", Available: []string{"jpeg", "png"}}), ports.ErrUnsupportedOperation, []string{"jpeg", "png"}},
+		{"operation", fmt.Errorf("%w: smart crop", ports.ErrUnsupportedOperation), ports.ErrUnsupportedOperation, nil},
+		{"max bytes", fmt.Errorf("%w: 900 bytes at quality 1, 500 allowed", image.ErrMaxBytesUnreachable), image.ErrMaxBytesUnreachable, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			transformError(c, tt.err, "transform failed")
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			var body struct {
+				Error   string   `json:"error"`
+				Formats []string `json:"formats"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Contains(t, body.Error, tt.message.Error())
+			assert.Equal(t, tt.formats, body.Formats)
+		})
+	}
+}
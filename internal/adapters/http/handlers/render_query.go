@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+
+	"image-processing-service/internal/domain/image"
+)
+
+// parseRenderQuery builds a TransformationSpec from render URL parameters
+// and validates it like a JSON spec. Unknown parameters are ignored so
+// clients can add cache busters.
+//
+//	w, h          resize box; fit, bg and we (without enlargement) refine it
+//	crop          x,y,w,h or, with gravity, w,h
+//	rot, flip, flop
+//	blur, sharpen, gamma, brightness, contrast, saturation, gray, sepia,
+//	tint, invert  filters; tint is a colour like bg
+//	fp            focal point override as x,y
+//	fmt, q        output format and quality, either may be auto
+//	max_bytes     size budget
+//	frame         single frame of an animation
+//	max_frames    frame cap of an animation
+//	strip         metadata to strip: exif, icc, xmp or all, comma separated
+//	gps           keep the GPS position in kept EXIF
+func parseRenderQuery(q url.Values) (*image.TransformationSpec, error) {
+	p := queryParser{values: q}
+	spec := &image.TransformationSpec{}
+
+	if q.Has("w") || q.Has("h") {
+		spec.Resize = &image.ResizeSpec{
+			Width:              p.int("w"),
+			Height:             p.int("h"),
+			Fit:                q.Get("fit"),
+			Background:         hexParam(q.Get("bg")),
+			WithoutEnlargement: p.bool("we"),
+		}
+	}
+
+	if q.Has("crop") {
+		n := p.ints("crop")
+		crop := &image.CropSpec{Gravity: q.Get("gravity")}
+		switch {
+		case len(n) == 4 && crop.Gravity == "":
+			crop.X, crop.Y, crop.Width, crop.Height = n[0], n[1], n[2], n[3]
+		case len(n) == 2 && crop.Gravity != "":
+			crop.Width, crop.Height = n[0], n[1]
+		default:
+			p.fail("crop", "must be x,y,w,h, or w,h with gravity")
+		}
+		spec.Crop = crop
+	}
+
+	if q.Has("rot") {
+		rot := p.int("rot")
+		spec.Rotate = &rot
+	}
+	if q.Has("flip") {
+		flip := p.bool("flip")
+		spec.Flip = &flip
+	}
+	if q.Has("flop") {
+		flop := p.bool("flop")
+		spec.Mirror = &flop
+	}
+
+	if hasAny(q, filterParams) {
+		spec.Filters = &image.FilterSpec{
+			Blur:       p.int("blur"),
+			Sharpen:    p.float("sharpen"),
+			Gamma:      p.float("gamma"),
+			Brightness: p.float("brightness"),
+			Contrast:   p.float("contrast"),
+			Saturation: p.float("saturation"),
+			Grayscale:  p.bool("gray"),
+			Sepia:      p.bool("sepia"),
+			Tint:       hexParam(q.Get("tint")),
+			Invert:     p.bool("invert"),
+		}
+	}
+
+	if q.Has("fp") {
+		n := p.floats("fp")
+		if len(n) != 2 {
+			p.fail("fp", "must be x,y")
+		} else {
+			spec.FocalPoint = &image.FocalPoint{X: n[0], Y: n[1]}
+		}
+	}
+
+	if q.Has("fmt") {
+		format := q.Get("fmt")
+		spec.Format = &format
+	}
+	if q.Has("q") {
+		quality, err := image.ParseQuality(q.Get("q"))
+		if err != nil {
+			p.fail("q", `must be 1 to 100 or "auto"`)
+		}
+		spec.Quality = &quality
+	}
+	spec.MaxBytes = p.int("max_bytes")
+
+	if q.Has("frame") {
+		frame := p.int("frame")
+		spec.Frame = &frame
+	}
+	spec.MaxFrames = p.int("max_frames")
+
+	if q.Has("strip") || q.Has("gps") {
+		spec.Metadata = &image.MetadataSpec{}
+		p.strip(spec.Metadata)
+		if q.Has("gps") {
+			gps := p.bool("gps")
+			spec.Metadata.GPS = &gps
+		}
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err := binding.Validator.ValidateStruct(spec); err != nil {
+		return nil, fmt.Errorf("invalid transformation spec: %w", err)
+	}
+	return spec, nil
+}
+
+// filterParams are the parameters that set a filter.
+var filterParams = []string{"blur", "sharpen", "gamma", "brightness", "contrast", "saturation", "gray", "sepia", "tint", "invert"}
+
+func hasAny(q url.Values, keys []string) bool {
+	for _, key := range keys {
+		if q.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryParser converts query parameters, keeping the first error.
+type queryParser struct {
+	values url.Values
+	err    error
+}
+
+func (p *queryParser) fail(key, reason string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("invalid %s: %s", key, reason)
+	}
+}
+
+func (p *queryParser) int(key string) int {
+	v := p.values.Get(key)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		p.fail(key, "not an integer")
+	}
+	return n
+}
+
+func (p *queryParser) float(key string) float64 {
+	v := p.values.Get(key)
+	if v == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		p.fail(key, "not a number")
+	}
+	return f
+}
+
+func (p *queryParser) bool(key string) bool {
+	v := p.values.Get(key)
+	if v == "" {
+		return p.values.Has(key)
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.fail(key, "not a boolean")
+	}
+	return b
+}
+
+func (p *queryParser) ints(key string) []int {
+	var out []int
+	for _, f := range p.floats(key) {
+		if f != float64(int(f)) {
+			p.fail(key, "not a list of integers")
+			return nil
+		}
+		out = append(out, int(f))
+	}
+	return out
+}
+
+func (p *queryParser) floats(key string) []float64 {
+	var out []float64
+	for _, part := range strings.Split(p.values.Get(key), ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			p.fail(key, "not a list of numbers")
+			return nil
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// strip sets the metadata the strip parameter lists to be stripped.
+func (p *queryParser) strip(m *image.MetadataSpec) {
+	stripped := false
+	for _, kind := range strings.Split(p.values.Get("strip"), ",") {
+		switch strings.TrimSpace(kind) {
+		case "":
+		case "exif":
+			m.EXIF = &stripped
+		case "icc":
+			m.ICC = &stripped
+		case "xmp":
+			m.XMP = &stripped
+		case "all":
+			m.EXIF, m.ICC, m.XMP = &stripped, &stripped, &stripped
+		default:
+			p.fail("strip", "must list exif, icc, xmp or all")
+		}
+	}
+}
+
+// hexParam accepts colours with or without the leading #, which has to be
+// escaped in URLs.
+func hexParam(v string) string {
+	if v != "" && !strings.HasPrefix(v, "#") {
+		return "#" + v
+	}
+	return v
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/domain/image"
+)
+
+func TestParseRenderQueryFilters(t *testing.T) {
+	q, err := url.ParseQuery("blur=2&sharpen=1.5&gamma=2.2&brightness=0.2&contrast=-0.5&saturation=1&gray=1&sepia=true&tint=704214&invert")
+	require.NoError(t, err)
+
+	spec, err := parseRenderQuery(q)
+	require.NoError(t, err)
+	assert.Equal(t, &image.FilterSpec{
+		Blur:       2,
+		Sharpen:    1.5,
+		Gamma:      2.2,
+		Brightness: 0.2,
+		Contrast:   -0.5,
+		Saturation: 1,
+		Grayscale:  true,
+		Sepia:      true,
+		Tint:       "#704214",
+		Invert:     true,
+	}, spec.Filters)
+}
+
+func TestParseRenderQueryRejectsInvalidFilters(t *testing.T) {
+	for _, raw := range []string{"brightness=2", "gamma=x", "tint=12345", "invert=maybe"} {
+		t.Run(raw, func(t *testing.T) {
+			q, err := url.ParseQuery(raw)
+			require.NoError(t, err)
+			_, err = parseRenderQuery(q)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseRenderQueryWithoutFilters(t *testing.T) {
+	spec, err := parseRenderQuery(url.Values{"w": {"100"}, "cb": {"1"}})
+	require.NoError(t, err)
+	assert.Nil(t, spec.Filters)
+}
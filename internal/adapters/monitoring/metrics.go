@@ -1,6 +1,8 @@
 package monitoring
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -28,7 +30,39 @@ var (
 			Name: "image_transformations_total",
 			Help: "Total number of image transformations.",
 		},
-		[]string{"type", "status"}, // type: sync, async; status: success, failure
+		[]string{"type", "status"}, // type: sync, async, worker; status: success, failure
+	)
+
+	QueueRetriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "image_processing_queue_retries_total",
+			Help: "Total number of jobs scheduled for a delayed retry.",
+		},
+		[]string{"attempt"},
+	)
+
+	QueueDeadLetteredTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "image_processing_queue_dead_lettered_total",
+			Help: "Total number of jobs moved to the dead-letter queue.",
+		},
+		[]string{"reason"}, // reason: exhausted, permanent, malformed
+	)
+
+	JobAttempts = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "image_processing_job_attempts",
+			Help:    "Number of attempts a job took before it succeeded or was dead-lettered.",
+			Buckets: []float64{1, 2, 3, 4, 5, 7, 10},
+		},
+	)
+
+	StorageDeletionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "image_processing_storage_deletions_total",
+			Help: "Total number of stored object deletions attempted for deleted images and variants.",
+		},
+		[]string{"result"}, // result: deleted, retry, orphaned
 	)
 
 	QueueDepth = promauto.NewGauge(
@@ -48,6 +82,22 @@ func RecordTransformation(tType, status string) {
 	TransformationsTotal.WithLabelValues(tType, status).Inc()
 }
 
+func RecordQueueRetry(attempt int) {
+	QueueRetriesTotal.WithLabelValues(strconv.Itoa(attempt)).Inc()
+}
+
+func RecordDeadLetter(reason string) {
+	QueueDeadLetteredTotal.WithLabelValues(reason).Inc()
+}
+
+func RecordJobAttempts(attempts int) {
+	JobAttempts.Observe(float64(attempts))
+}
+
+func RecordStorageDeletion(result string) {
+	StorageDeletionsTotal.WithLabelValues(result).Inc()
+}
+
 func UpdateQueueDepth(depth float64) {
 	QueueDepth.Set(depth)
 }
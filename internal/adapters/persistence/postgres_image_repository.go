@@ -2,8 +2,11 @@ package persistence
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -24,10 +27,20 @@ func NewPostgresImageRepository(db *pgxpool.Pool) *PostgresImageRepository {
 }
 
 func (r *PostgresImageRepository) Save(ctx context.Context, img *image.Image) error {
+	var metadata []byte
+	if img.Photo != nil {
+		var err error
+		if metadata, err = json.Marshal(img.Photo); err != nil {
+			return fmt.Errorf("failed to marshal image metadata: %w", err)
+		}
+	}
+
 	query := `
-		INSERT INTO images (id, owner_id, filename, original_key, size, mime_type, width, height, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO images (id, owner_id, filename, original_key, size, mime_type, width, height, created_at, focal_x, focal_y, has_alpha, frame_count, loop_count, duration_ms, metadata, orientation)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
+	focalX, focalY := focalColumns(img.FocalPoint)
+	frames, loops, duration := animationColumns(img.Animation)
 	_, err := r.db.Exec(ctx, query,
 		img.ID,
 		img.OwnerID,
@@ -38,6 +51,14 @@ func (r *PostgresImageRepository) Save(ctx context.Context, img *image.Image) er
 		img.Width,
 		img.Height,
 		img.CreatedAt,
+		focalX,
+		focalY,
+		img.HasAlpha,
+		frames,
+		loops,
+		duration,
+		metadata,
+		orientationColumn(img.Orientation),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to save image: %w", err)
@@ -45,10 +66,49 @@ func (r *PostgresImageRepository) Save(ctx context.Context, img *image.Image) er
 	return nil
 }
 
+// Update persists the mutable fields of an image.
+func (r *PostgresImageRepository) Update(ctx context.Context, img *image.Image) error {
+	query := `
+		UPDATE images
+		SET focal_x = $2, focal_y = $3
+		WHERE id = $1
+	`
+	focalX, focalY := focalColumns(img.FocalPoint)
+	_, err := r.db.Exec(ctx, query, img.ID, focalX, focalY)
+	if err != nil {
+		return fmt.Errorf("failed to update image: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an image; its variants and jobs cascade.
+func (r *PostgresImageRepository) Delete(ctx context.Context, id image.ImageID) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM images WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresImageRepository) DeleteVariant(ctx context.Context, imageID image.ImageID, variantID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM variants WHERE id = $1 AND image_id = $2`, variantID, imageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete variant: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 func (r *PostgresImageRepository) SaveVariant(ctx context.Context, imageID image.ImageID, variant *image.Variant) error {
+	var spec []byte
+	if variant.Spec != nil {
+		var err error
+		if spec, err = json.Marshal(variant.Spec); err != nil {
+			return fmt.Errorf("failed to marshal variant spec: %w", err)
+		}
+	}
+
 	query := `
-		INSERT INTO variants (id, image_id, variant_key, spec_hash, size, mime_type, width, height, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO variants (id, image_id, variant_key, spec_hash, size, mime_type, width, height, created_at, spec, spec_hash_version, quality)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, 0))
 		ON CONFLICT (image_id, spec_hash) DO NOTHING
 	`
 	_, err := r.db.Exec(ctx, query,
@@ -61,6 +121,9 @@ func (r *PostgresImageRepository) SaveVariant(ctx context.Context, imageID image
 		variant.Width,
 		variant.Height,
 		variant.CreatedAt,
+		spec,
+		variant.SpecHashVersion,
+		variant.Quality,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to save variant: %w", err)
@@ -68,42 +131,101 @@ func (r *PostgresImageRepository) SaveVariant(ctx context.Context, imageID image
 	return nil
 }
 
-func (r *PostgresImageRepository) GetByID(ctx context.Context, id image.ImageID) (*image.Image, error) {
-	imgQuery := `
-		SELECT id, owner_id, filename, original_key, size, mime_type, width, height, created_at
-		FROM images
-		WHERE id = $1
+// ListStaleVariants returns variants with a known spec whose hash predates
+// version, ordered by ID and starting after the given one.
+func (r *PostgresImageRepository) ListStaleVariants(ctx context.Context, version int, after uuid.UUID, limit int) ([]*image.Variant, error) {
+	query := `
+		SELECT id, variant_key, spec_hash, size, mime_type, width, height, created_at, spec, spec_hash_version
+		FROM variants
+		WHERE spec_hash_version < $1 AND spec IS NOT NULL AND id > $2
+		ORDER BY id
+		LIMIT $3
 	`
-	row := r.db.QueryRow(ctx, imgQuery, id)
+	rows, err := r.db.Query(ctx, query, version, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale variants: %w", err)
+	}
+	defer rows.Close()
 
-	var img image.Image
-	var idStr, ownerIDStr string
-	err := row.Scan(
-		&idStr,
-		&ownerIDStr,
-		&img.Filename,
-		&img.OriginalKey,
-		&img.Size,
-		&img.MimeType,
-		&img.Width,
-		&img.Height,
-		&img.CreatedAt,
-	)
+	var variants []*image.Variant
+	for rows.Next() {
+		var v image.Variant
+		var spec []byte
+		if err := rows.Scan(
+			&v.ID,
+			&v.VariantKey,
+			&v.SpecHash,
+			&v.Size,
+			&v.MimeType,
+			&v.Width,
+			&v.Height,
+			&v.CreatedAt,
+			&spec,
+			&v.SpecHashVersion,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan variant: %w", err)
+		}
+		if err := json.Unmarshal(spec, &v.Spec); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal variant spec: %w", err)
+		}
+		variants = append(variants, &v)
+	}
+	return variants, rows.Err()
+}
+
+// RehashVariant moves a variant and the jobs that rendered it to a new spec
+// hash in one statement.
+func (r *PostgresImageRepository) RehashVariant(ctx context.Context, id uuid.UUID, specHash string, version int) (bool, error) {
+	query := `
+		WITH rehashed AS (
+			UPDATE variants v SET spec_hash = $2, spec_hash_version = $3
+			WHERE v.id = $1 AND NOT EXISTS (
+				SELECT 1 FROM variants o
+				WHERE o.image_id = v.image_id AND o.spec_hash = $2 AND o.id <> v.id
+			)
+			RETURNING v.id
+		), jobs AS (
+			UPDATE transform_jobs SET spec_hash = $2
+			WHERE variant_id IN (SELECT id FROM rehashed)
+		)
+		SELECT count(*) FROM rehashed
+	`
+	var n int
+	if err := r.db.QueryRow(ctx, query, id, specHash, version).Scan(&n); err != nil {
+		return false, fmt.Errorf("failed to rehash variant: %w", err)
+	}
+	return n > 0, nil
+}
+
+// imageColumns are the columns scanImage reads, in order.
+const imageColumns = `id, owner_id, filename, original_key, size, mime_type, width, height, created_at, focal_x, focal_y, deleted_at, has_alpha, frame_count, loop_count, duration_ms, metadata, orientation`
+
+// GetByID returns an image that is not in the trash, or nil.
+func (r *PostgresImageRepository) GetByID(ctx context.Context, id image.ImageID) (*image.Image, error) {
+	return r.getImage(ctx, `id = $1 AND deleted_at IS NULL`, id)
+}
+
+// GetTrashed returns an image that is in the trash, or nil.
+func (r *PostgresImageRepository) GetTrashed(ctx context.Context, id image.ImageID) (*image.Image, error) {
+	return r.getImage(ctx, `id = $1 AND deleted_at IS NOT NULL`, id)
+}
+
+func (r *PostgresImageRepository) getImage(ctx context.Context, where string, args ...any) (*image.Image, error) {
+	imgQuery := `SELECT ` + imageColumns + ` FROM images WHERE ` + where
+	img, err := scanImage(r.db.QueryRow(ctx, imgQuery, args...))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
-	img.ID = image.ImageID(idStr)
-	img.OwnerID = user.UserID(ownerIDStr)
 
 	varQuery := `
-		SELECT id, variant_key, spec_hash, size, mime_type, width, height, created_at
+		SELECT id, variant_key, spec_hash, size, mime_type, width, height, created_at, COALESCE(quality, 0)
 		FROM variants
 		WHERE image_id = $1
 	`
-	rows, err := r.db.Query(ctx, varQuery, idStr)
+	rows, err := r.db.Query(ctx, varQuery, img.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get variants: %w", err)
 	}
@@ -122,6 +244,7 @@ func (r *PostgresImageRepository) GetByID(ctx context.Context, id image.ImageID)
 			&v.Width,
 			&v.Height,
 			&v.CreatedAt,
+			&v.Quality,
 		); err != nil {
 			return nil, err
 		}
@@ -129,25 +252,105 @@ func (r *PostgresImageRepository) GetByID(ctx context.Context, id image.ImageID)
 		img.Variants = append(img.Variants, v)
 	}
 
-	return &img, nil
+	return img, nil
+}
+
+// List returns the images matching the criteria, excluding the trash. The
+// owner_id indexes on each sort field serve both the filters and the
+// keyset condition.
+func (r *PostgresImageRepository) List(ctx context.Context, c image.ListCriteria) ([]*image.Image, int, error) {
+	q := &queryBuilder{}
+	where := []string{
+		"owner_id = " + q.arg(c.OwnerID),
+		"deleted_at IS NULL",
+	}
+	if len(c.MimeTypes) > 0 {
+		where = append(where, "mime_type = ANY("+q.arg(c.MimeTypes)+")")
+	}
+	if c.CreatedAfter != nil {
+		where = append(where, "created_at >= "+q.arg(*c.CreatedAfter))
+	}
+	if c.CreatedBefore != nil {
+		where = append(where, "created_at < "+q.arg(*c.CreatedBefore))
+	}
+	if c.MinWidth > 0 {
+		where = append(where, "width >= "+q.arg(c.MinWidth))
+	}
+	if c.MaxWidth > 0 {
+		where = append(where, "width <= "+q.arg(c.MaxWidth))
+	}
+	if c.MinHeight > 0 {
+		where = append(where, "height >= "+q.arg(c.MinHeight))
+	}
+	if c.MaxHeight > 0 {
+		where = append(where, "height <= "+q.arg(c.MaxHeight))
+	}
+	if c.FilenameContains != "" {
+		where = append(where, "filename ILIKE '%' || "+q.arg(likeEscaper.Replace(c.FilenameContains))+" || '%'")
+	}
+
+	// Count total before the keyset condition narrows the rows
+	countQuery := `SELECT COUNT(*) FROM images WHERE ` + strings.Join(where, " AND ")
+	var total int
+	if err := r.db.QueryRow(ctx, countQuery, q.args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count images: %w", err)
+	}
+
+	column, direction := sortColumns[c.SortBy], "ASC"
+	if c.Descending {
+		direction = "DESC"
+	}
+	offset := c.Offset
+	if c.After != nil {
+		var value any
+		switch c.SortBy {
+		case image.SortBySize:
+			value = c.After.Size
+		case image.SortByFilename:
+			value = c.After.Filename
+		default:
+			value = c.After.CreatedAt
+		}
+		cmp := ">"
+		if c.Descending {
+			cmp = "<"
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", column, cmp, q.arg(value), q.arg(c.After.ID)))
+		offset = 0
+	}
+
+	listQuery := `SELECT ` + imageColumns + ` FROM images WHERE ` + strings.Join(where, " AND ") +
+		fmt.Sprintf(` ORDER BY %s %s, id %s LIMIT %s OFFSET %s`, column, direction, direction, q.arg(c.Limit), q.arg(offset))
+	rows, err := r.db.Query(ctx, listQuery, q.args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list images: %w", err)
+	}
+	defer rows.Close()
+
+	images := make([]*image.Image, 0)
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		images = append(images, img)
+	}
+
+	return images, total, rows.Err()
 }
 
-func (r *PostgresImageRepository) List(ctx context.Context, ownerID user.UserID, offset, limit int) ([]*image.Image, int, error) {
+// ListTrashed returns the owner's images in the trash, most recently
+// deleted first.
+func (r *PostgresImageRepository) ListTrashed(ctx context.Context, ownerID user.UserID, offset, limit int) ([]*image.Image, int, error) {
 	// Count total
-	countQuery := `SELECT COUNT(*) FROM images WHERE owner_id = $1`
+	countQuery := `SELECT COUNT(*) FROM images WHERE owner_id = $1 AND deleted_at IS NOT NULL`
 	var total int
 	if err := r.db.QueryRow(ctx, countQuery, ownerID).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	// Fetch items
-	listQuery := `
-		SELECT id, owner_id, filename, original_key, size, mime_type, width, height, created_at
-		FROM images
-		WHERE owner_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
+	listQuery := `SELECT ` + imageColumns + ` FROM images WHERE owner_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT $2 OFFSET $3`
 	rows, err := r.db.Query(ctx, listQuery, ownerID, limit, offset)
 	if err != nil {
 		return nil, 0, err
@@ -156,32 +359,77 @@ func (r *PostgresImageRepository) List(ctx context.Context, ownerID user.UserID,
 
 	images := make([]*image.Image, 0)
 	for rows.Next() {
-		var img image.Image
-		var idStr, ownerIDStr string
-		if err := rows.Scan(
-			&idStr,
-			&ownerIDStr,
-			&img.Filename,
-			&img.OriginalKey,
-			&img.Size,
-			&img.MimeType,
-			&img.Width,
-			&img.Height,
-			&img.CreatedAt,
-		); err != nil {
+		img, err := scanImage(rows)
+		if err != nil {
 			return nil, 0, err
 		}
-		img.ID = image.ImageID(idStr)
-		img.OwnerID = user.UserID(ownerIDStr)
-		images = append(images, &img)
+		images = append(images, img)
 	}
 
 	return images, total, nil
 }
 
+// Trash moves an image to the trash; it reports false when the image does
+// not exist or is already trashed.
+func (r *PostgresImageRepository) Trash(ctx context.Context, id image.ImageID, deletedAt time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE images SET deleted_at = $2 WHERE id = $1 AND deleted_at IS NULL`, id, deletedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to trash image: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Restore takes an image out of the trash if it was deleted after
+// deletedAfter, so it cannot race the purger.
+func (r *PostgresImageRepository) Restore(ctx context.Context, id image.ImageID, deletedAfter time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE images SET deleted_at = NULL WHERE id = $1 AND deleted_at > $2`, id, deletedAfter)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore image: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListExpiredTrash returns images trashed at or before deletedBefore,
+// oldest first.
+func (r *PostgresImageRepository) ListExpiredTrash(ctx context.Context, deletedBefore time.Time, limit int) ([]image.ImageID, error) {
+	query := `
+		SELECT id
+		FROM images
+		WHERE deleted_at <= $1
+		ORDER BY deleted_at
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, deletedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired trash: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]image.ImageID, 0)
+	for rows.Next() {
+		var idStr string
+		if err := rows.Scan(&idStr); err != nil {
+			return nil, fmt.Errorf("failed to scan expired trash: %w", err)
+		}
+		ids = append(ids, image.ImageID(idStr))
+	}
+	return ids, rows.Err()
+}
+
+// Purge removes a trashed image deleted at or before deletedBefore; its
+// variants and jobs cascade. It reports false when the image was restored
+// in the meantime.
+func (r *PostgresImageRepository) Purge(ctx context.Context, id image.ImageID, deletedBefore time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM images WHERE id = $1 AND deleted_at <= $2`, id, deletedBefore)
+	if err != nil {
+		return false, fmt.Errorf("failed to purge image: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 func (r *PostgresImageRepository) GetVariantBySpecHash(ctx context.Context, imageID image.ImageID, specHash string) (*image.Variant, error) {
 	query := `
-		SELECT id, variant_key, spec_hash, size, mime_type, width, height, created_at
+		SELECT id, variant_key, spec_hash, size, mime_type, width, height, created_at, COALESCE(quality, 0)
 		FROM variants
 		WHERE image_id = $1 AND spec_hash = $2
 	`
@@ -198,6 +446,7 @@ func (r *PostgresImageRepository) GetVariantBySpecHash(ctx context.Context, imag
 		&v.Width,
 		&v.Height,
 		&v.CreatedAt,
+		&v.Quality,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -208,3 +457,112 @@ func (r *PostgresImageRepository) GetVariantBySpecHash(ctx context.Context, imag
 	v.ID = vId
 	return &v, nil
 }
+
+// sortColumns maps sort fields to their columns.
+var sortColumns = map[image.SortField]string{
+	image.SortByCreatedAt: "created_at",
+	image.SortBySize:      "size",
+	image.SortByFilename:  "filename",
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// queryBuilder numbers the placeholders of a query built from parts.
+type queryBuilder struct {
+	args []any
+}
+
+func (q *queryBuilder) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// scanImage reads the imageColumns of a row; variants are not loaded.
+func scanImage(row pgx.Row) (*image.Image, error) {
+	var img image.Image
+	var idStr, ownerIDStr string
+	var focalX, focalY *float64
+	var frames, loops, duration *int
+	var metadata []byte
+	var orientation *int
+	err := row.Scan(
+		&idStr,
+		&ownerIDStr,
+		&img.Filename,
+		&img.OriginalKey,
+		&img.Size,
+		&img.MimeType,
+		&img.Width,
+		&img.Height,
+		&img.CreatedAt,
+		&focalX,
+		&focalY,
+		&img.DeletedAt,
+		&img.HasAlpha,
+		&frames,
+		&loops,
+		&duration,
+		&metadata,
+		&orientation,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if metadata != nil {
+		if err := json.Unmarshal(metadata, &img.Photo); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal image metadata: %w", err)
+		}
+	}
+	img.ID = image.ImageID(idStr)
+	img.OwnerID = user.UserID(ownerIDStr)
+	img.FocalPoint = focalPoint(focalX, focalY)
+	img.Animation = animation(frames, loops, duration)
+	if orientation != nil {
+		img.Orientation = *orientation
+	}
+	return &img, nil
+}
+
+// orientationColumn stores the unknown orientation 0 as NULL.
+func orientationColumn(orientation int) *int {
+	if orientation == 0 {
+		return nil
+	}
+	return &orientation
+}
+
+func focalColumns(fp *image.FocalPoint) (*float64, *float64) {
+	if fp == nil {
+		return nil, nil
+	}
+	return &fp.X, &fp.Y
+}
+
+func focalPoint(x, y *float64) *image.FocalPoint {
+	if x == nil || y == nil {
+		return nil
+	}
+	return &image.FocalPoint{X: *x, Y: *y}
+}
+
+func animationColumns(a *image.Animation) (*int, *int, *int) {
+	if a == nil {
+		return nil, nil, nil
+	}
+	return &a.Frames, &a.LoopCount, &a.DurationMS
+}
+
+func animation(frames, loops, duration *int) *image.Animation {
+	if frames == nil {
+		return nil
+	}
+	a := &image.Animation{Frames: *frames}
+	if loops != nil {
+		a.LoopCount = *loops
+	}
+	if duration != nil {
+		a.DurationMS = *duration
+	}
+	return a
+}
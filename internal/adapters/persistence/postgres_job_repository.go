@@ -0,0 +1,138 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/domain/user"
+)
+
+type PostgresJobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresJobRepository(db *pgxpool.Pool) *PostgresJobRepository {
+	return &PostgresJobRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresJobRepository) Save(ctx context.Context, j *job.Job) error {
+	spec, err := json.Marshal(j.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job spec: %w", err)
+	}
+
+	query := `
+		INSERT INTO transform_jobs (id, image_id, owner_id, spec, spec_hash, status, attempts, error, variant_id, created_at, updated_at, started_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+	_, err = r.db.Exec(ctx, query,
+		j.ID,
+		j.ImageID,
+		j.OwnerID,
+		spec,
+		j.SpecHash,
+		j.Status,
+		j.Attempts,
+		nullableString(j.Error),
+		j.VariantID,
+		j.CreatedAt,
+		j.UpdatedAt,
+		j.StartedAt,
+		j.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresJobRepository) GetByID(ctx context.Context, id job.JobID) (*job.Job, error) {
+	query := `
+		SELECT id, image_id, owner_id, spec, spec_hash, status, attempts, error, variant_id, created_at, updated_at, started_at, completed_at
+		FROM transform_jobs
+		WHERE id = $1
+	`
+	row := r.db.QueryRow(ctx, query, id)
+
+	var j job.Job
+	var idStr, imageIDStr, ownerIDStr, status string
+	var spec []byte
+	var errMsg *string
+	var variantID *uuid.UUID
+	err := row.Scan(
+		&idStr,
+		&imageIDStr,
+		&ownerIDStr,
+		&spec,
+		&j.SpecHash,
+		&status,
+		&j.Attempts,
+		&errMsg,
+		&variantID,
+		&j.CreatedAt,
+		&j.UpdatedAt,
+		&j.StartedAt,
+		&j.CompletedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	var s image.TransformationSpec
+	if err := json.Unmarshal(spec, &s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job spec: %w", err)
+	}
+
+	j.ID = job.JobID(idStr)
+	j.ImageID = image.ImageID(imageIDStr)
+	j.OwnerID = user.UserID(ownerIDStr)
+	j.Spec = &s
+	j.Status = job.Status(status)
+	j.VariantID = variantID
+	if errMsg != nil {
+		j.Error = *errMsg
+	}
+	return &j, nil
+}
+
+func (r *PostgresJobRepository) Update(ctx context.Context, j *job.Job) error {
+	query := `
+		UPDATE transform_jobs
+		SET status = $2, attempts = $3, error = $4, variant_id = $5, updated_at = $6, started_at = $7, completed_at = $8
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query,
+		j.ID,
+		j.Status,
+		j.Attempts,
+		nullableString(j.Error),
+		j.VariantID,
+		j.UpdatedAt,
+		j.StartedAt,
+		j.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	return nil
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
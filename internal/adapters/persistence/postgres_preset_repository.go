@@ -0,0 +1,175 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"image-processing-service/internal/domain/preset"
+	"image-processing-service/internal/domain/user"
+)
+
+type PostgresPresetRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresPresetRepository(db *pgxpool.Pool) *PostgresPresetRepository {
+	return &PostgresPresetRepository{
+		db: db,
+	}
+}
+
+// Create inserts the preset and its first version in one statement, so a
+// name taken concurrently leaves no orphaned version behind.
+func (r *PostgresPresetRepository) Create(ctx context.Context, p *preset.Preset) (bool, error) {
+	spec, err := json.Marshal(p.Spec)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal preset spec: %w", err)
+	}
+
+	query := `
+		WITH created AS (
+			INSERT INTO presets (id, owner_id, name, version, spec, eager, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (owner_id, name) DO NOTHING
+			RETURNING id, version, spec, updated_at
+		)
+		INSERT INTO preset_versions (preset_id, version, spec, created_at)
+		SELECT id, version, spec, updated_at FROM created
+	`
+	tag, err := r.db.Exec(ctx, query, p.ID, p.OwnerID, p.Name, p.Version, spec, p.Eager, p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to create preset: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Get reads the latest version from presets and older ones from
+// preset_versions.
+func (r *PostgresPresetRepository) Get(ctx context.Context, ownerID user.UserID, name string, version int) (*preset.Preset, error) {
+	query := `
+		SELECT id, owner_id, name, version, spec, eager, created_at, updated_at
+		FROM presets
+		WHERE owner_id = $1 AND name = $2
+	`
+	args := []any{ownerID, name}
+	if version > 0 {
+		query = `
+			SELECT p.id, p.owner_id, p.name, v.version, v.spec, p.eager, p.created_at, v.created_at
+			FROM presets p
+			JOIN preset_versions v ON v.preset_id = p.id
+			WHERE p.owner_id = $1 AND p.name = $2 AND v.version = $3
+		`
+		args = append(args, version)
+	}
+
+	p, err := scanPreset(r.db.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get preset: %w", err)
+	}
+	return p, nil
+}
+
+func (r *PostgresPresetRepository) List(ctx context.Context, ownerID user.UserID) ([]*preset.Preset, error) {
+	query := `
+		SELECT id, owner_id, name, version, spec, eager, created_at, updated_at
+		FROM presets
+		WHERE owner_id = $1
+		ORDER BY name
+	`
+	return r.list(ctx, "failed to list presets", query, ownerID)
+}
+
+func (r *PostgresPresetRepository) ListVersions(ctx context.Context, ownerID user.UserID, name string) ([]*preset.Preset, error) {
+	query := `
+		SELECT p.id, p.owner_id, p.name, v.version, v.spec, p.eager, p.created_at, v.created_at
+		FROM presets p
+		JOIN preset_versions v ON v.preset_id = p.id
+		WHERE p.owner_id = $1 AND p.name = $2
+		ORDER BY v.version DESC
+	`
+	return r.list(ctx, "failed to list preset versions", query, ownerID, name)
+}
+
+func (r *PostgresPresetRepository) list(ctx context.Context, failure, query string, args ...any) ([]*preset.Preset, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", failure, err)
+	}
+	defer rows.Close()
+
+	presets := make([]*preset.Preset, 0)
+	for rows.Next() {
+		p, err := scanPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", failure, err)
+		}
+		presets = append(presets, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", failure, err)
+	}
+	return presets, nil
+}
+
+// Revise only moves presets still at the previous version, which makes
+// concurrent revisions of the same preset fail instead of overwriting each
+// other.
+func (r *PostgresPresetRepository) Revise(ctx context.Context, p *preset.Preset) (bool, error) {
+	spec, err := json.Marshal(p.Spec)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal preset spec: %w", err)
+	}
+
+	query := `
+		WITH revised AS (
+			UPDATE presets SET version = $2, spec = $3, updated_at = $4
+			WHERE id = $1 AND version = $2 - 1
+			RETURNING id, version, spec, updated_at
+		)
+		INSERT INTO preset_versions (preset_id, version, spec, created_at)
+		SELECT id, version, spec, updated_at FROM revised
+	`
+	tag, err := r.db.Exec(ctx, query, p.ID, p.Version, spec, p.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to revise preset: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *PostgresPresetRepository) SetEager(ctx context.Context, id preset.PresetID, eager bool) error {
+	if _, err := r.db.Exec(ctx, `UPDATE presets SET eager = $2 WHERE id = $1`, id, eager); err != nil {
+		return fmt.Errorf("failed to update preset: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresPresetRepository) Delete(ctx context.Context, ownerID user.UserID, name string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM presets WHERE owner_id = $1 AND name = $2`, ownerID, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete preset: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func scanPreset(row pgx.Row) (*preset.Preset, error) {
+	var p preset.Preset
+	var idStr, ownerIDStr string
+	var spec []byte
+	if err := row.Scan(&idStr, &ownerIDStr, &p.Name, &p.Version, &spec, &p.Eager, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(spec, &p.Spec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal preset spec: %w", err)
+	}
+	p.ID = preset.PresetID(idStr)
+	p.OwnerID = user.UserID(ownerIDStr)
+	return &p, nil
+}
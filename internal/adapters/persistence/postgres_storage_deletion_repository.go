@@ -0,0 +1,88 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"image-processing-service/internal/ports"
+)
+
+type PostgresStorageDeletionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresStorageDeletionRepository(db *pgxpool.Pool) *PostgresStorageDeletionRepository {
+	return &PostgresStorageDeletionRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresStorageDeletionRepository) Add(ctx context.Context, keys []string, notBefore time.Time) error {
+	query := `
+		INSERT INTO storage_deletions (object_key, next_attempt_at)
+		SELECT unnest($1::text[]), $2
+		ON CONFLICT (object_key) DO NOTHING
+	`
+	if _, err := r.db.Exec(ctx, query, keys, notBefore); err != nil {
+		return fmt.Errorf("failed to schedule storage deletions: %w", err)
+	}
+	return nil
+}
+
+// Claim pushes next_attempt_at past the lease in the same statement that
+// selects the rows, skipping rows another process is claiming.
+func (r *PostgresStorageDeletionRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]ports.StorageDeletion, error) {
+	query := `
+		UPDATE storage_deletions
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE object_key IN (
+			SELECT object_key
+			FROM storage_deletions
+			WHERE next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING object_key, attempts
+	`
+	rows, err := r.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim storage deletions: %w", err)
+	}
+	defer rows.Close()
+
+	deletions := make([]ports.StorageDeletion, 0)
+	for rows.Next() {
+		var d ports.StorageDeletion
+		if err := rows.Scan(&d.Key, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan storage deletion: %w", err)
+		}
+		deletions = append(deletions, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim storage deletions: %w", err)
+	}
+	return deletions, nil
+}
+
+func (r *PostgresStorageDeletionRepository) Reschedule(ctx context.Context, key string, next time.Time, lastErr string) error {
+	query := `
+		UPDATE storage_deletions
+		SET next_attempt_at = $2, last_error = $3
+		WHERE object_key = $1
+	`
+	if _, err := r.db.Exec(ctx, query, key, next, lastErr); err != nil {
+		return fmt.Errorf("failed to reschedule storage deletion: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresStorageDeletionRepository) Remove(ctx context.Context, key string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM storage_deletions WHERE object_key = $1`, key); err != nil {
+		return fmt.Errorf("failed to remove storage deletion: %w", err)
+	}
+	return nil
+}
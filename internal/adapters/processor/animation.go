@@ -0,0 +1,428 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+
+	domainImage "image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// animation is a decoded animated image. Frames are composited onto the
+// full canvas, so each can be rendered on its own.
+type animation struct {
+	frames []*image.NRGBA
+	// delays holds the display time of each frame in milliseconds.
+	delays []int
+	// loops is how often the animation plays; zero plays it forever.
+	loops int
+}
+
+// keepsAnimation reports whether spec renders an animated source as an
+// animation: it picks no single frame and its output format can animate.
+func keepsAnimation(spec *domainImage.TransformationSpec, format string) bool {
+	return spec.Frame == nil && (format == domainImage.FormatGIF || format == domainImage.FormatWebP)
+}
+
+// maxAnimationBytes bounds the memory decoding an animation takes: the kept
+// frames, each a full RGBA canvas, and for GIF the palette indices of every
+// frame up to the last one kept.
+const maxAnimationBytes = 256 << 20
+
+// frameSpan is a kept frame: source frame from, which also shows for the
+// dropped frames up to to.
+type frameSpan struct {
+	from, to int
+}
+
+// selectFrames picks the frames of an n-frame animation a render keeps.
+// With pick >= 0 that frame alone is kept. Otherwise at most maxFrames are,
+// zero meaning all, spread evenly over the animation; each kept frame also
+// shows for the frames dropped after it, so the animation keeps its
+// duration.
+func selectFrames(n, pick, maxFrames int) ([]frameSpan, error) {
+	if pick >= 0 {
+		if pick >= n {
+			return nil, fmt.Errorf("%w: frame %d of %d", domainImage.ErrFrameOutOfRange, pick, n)
+		}
+		return []frameSpan{{pick, pick + 1}}, nil
+	}
+	kept := n
+	if maxFrames > 0 && maxFrames < n {
+		kept = maxFrames
+	}
+	spans := make([]frameSpan, kept)
+	for i := range spans {
+		spans[i] = frameSpan{i * n / kept, (i + 1) * n / kept}
+	}
+	return spans, nil
+}
+
+// checkAnimationSize rejects animations whose kept frames on a w×h canvas,
+// plus indexed bytes of palette indices, exceed maxAnimationBytes.
+func checkAnimationSize(kept, w, h, indexed int) error {
+	if int64(kept)*int64(w)*int64(h)*4+int64(indexed) > maxAnimationBytes {
+		return fmt.Errorf("%w: %d frames of %dx%d are too large to render; keep fewer with max_frames or pick one with frame", ports.ErrUnsupportedOperation, kept, w, h)
+	}
+	return nil
+}
+
+// checkStillFrame rejects specs picking a frame past the only one of a
+// still source.
+func checkStillFrame(spec *domainImage.TransformationSpec) error {
+	if spec.Frame != nil && *spec.Frame > 0 {
+		return fmt.Errorf("%w: frame %d of 1", domainImage.ErrFrameOutOfRange, *spec.Frame)
+	}
+	return nil
+}
+
+// decodeAnimationError passes on the errors of decoding an animation that
+// are the spec's doing and wraps the rest as a bad source.
+func decodeAnimationError(err error) error {
+	if errors.Is(err, domainImage.ErrFrameOutOfRange) || errors.Is(err, ports.ErrUnsupportedOperation) {
+		return err
+	}
+	return fmt.Errorf("failed to decode source image: %w", err)
+}
+
+// compositor draws the source frames of an animation onto its canvas in
+// turn and keeps copies of the frames the spans select.
+type compositor struct {
+	canvas *image.NRGBA
+	spans  []frameSpan
+	a      *animation
+	next   int
+}
+
+func newCompositor(canvas image.Rectangle, spans []frameSpan, loops int) *compositor {
+	return &compositor{
+		canvas: image.NewNRGBA(canvas),
+		spans:  spans,
+		a:      &animation{loops: loops, delays: make([]int, len(spans))},
+	}
+}
+
+// done reports whether every kept frame has been drawn.
+func (c *compositor) done(i int) bool {
+	return i >= c.spans[len(c.spans)-1].to
+}
+
+// drawn records source frame i, just drawn onto the canvas and showing for
+// delay milliseconds.
+func (c *compositor) drawn(i, delay int) {
+	for c.next < len(c.spans) && i >= c.spans[c.next].to {
+		c.next++
+	}
+	if c.next == len(c.spans) || i < c.spans[c.next].from {
+		return
+	}
+	if i == c.spans[c.next].from {
+		c.a.frames = append(c.a.frames, toNRGBA(c.canvas))
+	}
+	c.a.delays[c.next] += delay
+}
+
+// renderAnimation renders every frame with spec, in place. Smart crops are
+// placed on the first frame and cut from every frame at the same spot, so
+// the crop does not jump around.
+func renderAnimation(a *animation, spec *domainImage.TransformationSpec, assets *ports.TransformAssets) error {
+	p := &StdLibImageProcessor{}
+	spec = pinSmartCrop(spec, a.frames[0])
+	for i, frame := range a.frames {
+		out, err := p.render(frame, spec, assets, 0, false)
+		if err != nil {
+			return err
+		}
+		a.frames[i] = out
+	}
+	return nil
+}
+
+// pinSmartCrop returns spec with a smart crop replaced by the area it picks
+// on first.
+func pinSmartCrop(spec *domainImage.TransformationSpec, first *image.NRGBA) *domainImage.TransformationSpec {
+	c := spec.Crop
+	if c == nil || !c.IsSmart() {
+		return spec
+	}
+	oriented := orient(first, spec, 0, false)
+	if c.CheckBounds(oriented.Bounds().Dx(), oriented.Bounds().Dy()) != nil {
+		return spec
+	}
+	pinned, crop := *spec, *c
+	crop.X, crop.Y = smartCrop(oriented, c.Width, c.Height, c.Gravity)
+	crop.Gravity = ""
+	pinned.Crop = &crop
+	return &pinned
+}
+
+// probeAnimation returns the animation of an animated GIF or WebP, or nil
+// for anything else. Neither is decoded.
+func probeAnimation(buf []byte) *domainImage.Animation {
+	if isAnimatedWebP(buf) {
+		if m, err := probeWebPAnimation(buf); err == nil {
+			return m
+		}
+		return nil
+	}
+	if !isGIF(buf) {
+		return nil
+	}
+	g, err := scanGIF(buf)
+	if err != nil || len(g.frames) < 2 {
+		return nil
+	}
+	m := &domainImage.Animation{Frames: len(g.frames), LoopCount: gifPlays(g.loopCount)}
+	for _, f := range g.frames {
+		m.DurationMS += f.delay
+	}
+	return m
+}
+
+func isGIF(buf []byte) bool {
+	return bytes.HasPrefix(buf, []byte("GIF8"))
+}
+
+// isAnimatedGIF reports whether buf is a GIF of more than one frame. It
+// only walks the block structure; no frame is decoded.
+func isAnimatedGIF(buf []byte) bool {
+	if !isGIF(buf) {
+		return false
+	}
+	g, err := scanGIF(buf)
+	return err == nil && len(g.frames) > 1
+}
+
+var errMalformedGIF = errors.New("malformed gif")
+
+// gifLayout is the block structure of a GIF.
+type gifLayout struct {
+	width, height int
+	// loopCount is as in gif.GIF: -1 without a NETSCAPE2.0 extension.
+	loopCount int
+	frames    []gifFrame
+}
+
+// gifFrame is an image descriptor of a GIF.
+type gifFrame struct {
+	bounds image.Rectangle
+	// delay is the display time in milliseconds.
+	delay int
+	// end is the offset just past the frame's image data.
+	end int
+}
+
+// scanGIF walks the blocks of a GIF, see
+// https://www.w3.org/Graphics/GIF/spec-gif89a.txt, without decoding any
+// image data.
+func scanGIF(buf []byte) (*gifLayout, error) {
+	if len(buf) < 13 || !isGIF(buf) {
+		return nil, errMalformedGIF
+	}
+	g := &gifLayout{
+		width:     int(binary.LittleEndian.Uint16(buf[6:])),
+		height:    int(binary.LittleEndian.Uint16(buf[8:])),
+		loopCount: -1,
+	}
+	pos := 13 + colorTableSize(buf[10])
+	delay := 0
+	for {
+		if pos >= len(buf) {
+			return nil, errMalformedGIF
+		}
+		switch buf[pos] {
+		case 0x21: // extension
+			if pos+1 >= len(buf) {
+				return nil, errMalformedGIF
+			}
+			label, data := buf[pos+1], pos+2
+			end, err := skipSubBlocks(buf, data)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case label == 0xf9 && end-data >= 6 && buf[data] == 4:
+				delay = int(binary.LittleEndian.Uint16(buf[data+2:])) * 10
+			case label == 0xff && end-data >= 17 && buf[data] == 11 &&
+				string(buf[data+1:data+12]) == "NETSCAPE2.0" && buf[data+12] == 3 && buf[data+13] == 1:
+				g.loopCount = int(binary.LittleEndian.Uint16(buf[data+14:]))
+			}
+			pos = end
+		case 0x2c: // image descriptor
+			if pos+10 > len(buf) {
+				return nil, errMalformedGIF
+			}
+			d := buf[pos+1:]
+			x, y := int(binary.LittleEndian.Uint16(d[0:])), int(binary.LittleEndian.Uint16(d[2:]))
+			w, h := int(binary.LittleEndian.Uint16(d[4:])), int(binary.LittleEndian.Uint16(d[6:]))
+			// Skip the local colour table and the LZW minimum code size.
+			end, err := skipSubBlocks(buf, pos+10+colorTableSize(d[8])+1)
+			if err != nil {
+				return nil, err
+			}
+			g.frames = append(g.frames, gifFrame{bounds: image.Rect(x, y, x+w, y+h), delay: delay, end: end})
+			delay = 0
+			pos = end
+		case 0x3b: // trailer
+			return g, nil
+		default:
+			return nil, errMalformedGIF
+		}
+	}
+}
+
+// colorTableSize is the size of the colour table a packed field of the
+// logical screen or an image descriptor announces.
+func colorTableSize(packed byte) int {
+	if packed&0x80 == 0 {
+		return 0
+	}
+	return 3 << (packed&0x07 + 1)
+}
+
+// skipSubBlocks returns the offset after the sub-blocks starting at pos
+// and their terminator.
+func skipSubBlocks(buf []byte, pos int) (int, error) {
+	for {
+		if pos >= len(buf) {
+			return 0, errMalformedGIF
+		}
+		n := int(buf[pos])
+		pos++
+		if n == 0 {
+			return pos, nil
+		}
+		pos += n
+	}
+}
+
+// gifPlays converts a GIF loop count, the number of repeats with -1 for
+// none, to how often the animation plays.
+func gifPlays(loopCount int) int {
+	switch {
+	case loopCount < 0:
+		return 1
+	case loopCount == 0:
+		return 0
+	default:
+		return loopCount + 1
+	}
+}
+
+// decodeGIFAnimation composites the frames of a GIF that selectFrames
+// keeps, honouring each frame's disposal. Frames after the last kept one are
+// not decoded.
+func decodeGIFAnimation(buf []byte, pick, maxFrames int) (*animation, error) {
+	layout, err := scanGIF(buf)
+	if err != nil || len(layout.frames) == 0 {
+		return nil, errMalformedGIF
+	}
+	spans, err := selectFrames(len(layout.frames), pick, maxFrames)
+	if err != nil {
+		return nil, err
+	}
+	last := spans[len(spans)-1].to
+	bounds := image.Rect(0, 0, layout.width, layout.height)
+	indexed := 0
+	for _, f := range layout.frames[:last] {
+		bounds = bounds.Union(f.bounds)
+		indexed += f.bounds.Dx() * f.bounds.Dy()
+	}
+	if err := checkAnimationSize(len(spans), bounds.Dx(), bounds.Dy(), indexed); err != nil {
+		return nil, err
+	}
+
+	// Cut the GIF after the last frame needed.
+	end := layout.frames[last-1].end
+	truncated := append(buf[:end:end], 0x3b)
+	g, err := gif.DecodeAll(bytes.NewReader(truncated))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gif: %w", err)
+	}
+
+	c := newCompositor(bounds, spans, gifPlays(layout.loopCount))
+	for i, frame := range g.Image {
+		if c.done(i) {
+			break
+		}
+		var previous *image.NRGBA
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = toNRGBA(c.canvas)
+		}
+
+		draw.Draw(c.canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		c.drawn(i, g.Delay[i]*10)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(c.canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			draw.Draw(c.canvas, c.canvas.Bounds(), previous, image.Point{}, draw.Src)
+		}
+	}
+	if len(c.a.frames) != len(spans) {
+		return nil, errMalformedGIF
+	}
+	return c.a, nil
+}
+
+// encodeGIFAnimation encodes the frames as a GIF. Each frame covers the
+// canvas and clears it when done, so transparent areas do not show the
+// frame before.
+func encodeGIFAnimation(a *animation) ([]byte, error) {
+	g := &gif.GIF{LoopCount: gifLoopCount(a.loops)}
+	for i, frame := range a.frames {
+		g.Image = append(g.Image, quantize(frame))
+		g.Delay = append(g.Delay, (a.delays[i]+5)/10)
+		g.Disposal = append(g.Disposal, gif.DisposalBackground)
+	}
+
+	var out bytes.Buffer
+	if err := gif.EncodeAll(&out, g); err != nil {
+		return nil, fmt.Errorf("failed to encode gif: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// gifLoopCount is the inverse of gifPlays.
+func gifLoopCount(plays int) int {
+	switch plays {
+	case 0:
+		return 0
+	case 1:
+		return -1
+	default:
+		return plays - 1
+	}
+}
+
+// quantize dithers img onto the Plan 9 palette, like gif.Encode, keeping
+// pixels that are mostly transparent transparent.
+func quantize(img *image.NRGBA) *image.Paletted {
+	pal := append(color.Palette{}, palette.Plan9[:255]...)
+	transparent := uint8(len(pal))
+	pal = append(pal, color.Transparent)
+
+	b := img.Bounds()
+	dst := image.NewPaletted(b, pal)
+	draw.FloydSteinberg.Draw(dst, b, img, b.Min)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.NRGBAAt(x, y).A < 0x80 {
+				dst.SetColorIndex(x, y, transparent)
+			}
+		}
+	}
+	return dst
+}
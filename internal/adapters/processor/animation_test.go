@@ -0,0 +1,233 @@
+package processor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domainImage "image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+var (
+	red   = color.NRGBA{R: 255, A: 255}
+	green = color.NRGBA{G: 255, A: 255}
+	blue  = color.NRGBA{B: 255, A: 255}
+	white = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// testGIF encodes a 4×4 GIF with a frame of each colour, frame i showing
+// for 10·(i+1) centiseconds.
+func testGIF(t testing.TB, loopCount int, colors ...color.NRGBA) []byte {
+	t.Helper()
+	pal := color.Palette{}
+	for _, c := range colors {
+		pal = append(pal, c)
+	}
+	g := &gif.GIF{LoopCount: loopCount}
+	for i := range colors {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), pal)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i)
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10*(i+1))
+	}
+	var out bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&out, g))
+	return out.Bytes()
+}
+
+// withComment inserts a comment extension holding text before the first
+// frame of a GIF.
+func withComment(buf []byte, text string) []byte {
+	pos := 13 + colorTableSize(buf[10])
+	ext := append([]byte{0x21, 0xfe, byte(len(text))}, text...)
+	ext = append(ext, 0)
+	out := append([]byte{}, buf[:pos]...)
+	out = append(out, ext...)
+	return append(out, buf[pos:]...)
+}
+
+func TestScanGIF(t *testing.T) {
+	g, err := scanGIF(testGIF(t, 2, red, green, blue))
+	require.NoError(t, err)
+	assert.Equal(t, 4, g.width)
+	assert.Equal(t, 4, g.height)
+	assert.Equal(t, 2, g.loopCount)
+	require.Len(t, g.frames, 3)
+	for i, f := range g.frames {
+		assert.Equal(t, image.Rect(0, 0, 4, 4), f.bounds)
+		assert.Equal(t, 100*(i+1), f.delay)
+	}
+	assert.Less(t, g.frames[0].end, g.frames[1].end)
+
+	g, err = scanGIF(testGIF(t, -1, red, green))
+	require.NoError(t, err)
+	assert.Equal(t, -1, g.loopCount, "without a NETSCAPE2.0 extension")
+}
+
+func TestIsAnimatedGIF(t *testing.T) {
+	still := testGIF(t, 0, red)
+	assert.False(t, isAnimatedGIF(still))
+	assert.False(t, isAnimatedGIF(withComment(still, "\x21\xf9\x04\x00\x21\xf9\x04\x00")),
+		"graphic control bytes in a comment are not frames")
+	assert.True(t, isAnimatedGIF(testGIF(t, 0, red, green)))
+	assert.True(t, isAnimatedGIF(withComment(testGIF(t, 0, red, green), "hello")))
+	assert.False(t, isAnimatedGIF([]byte("\x89PNG\r\n\x1a\n")))
+}
+
+func TestScanGIFMalformed(t *testing.T) {
+	buf := testGIF(t, 0, red, green, blue)
+	for n := range len(buf) {
+		_, err := scanGIF(buf[:n])
+		assert.ErrorIs(t, err, errMalformedGIF, "truncated to %d bytes", n)
+	}
+
+	bad := append([]byte{}, buf...)
+	bad[13+colorTableSize(bad[10])] = 0x42
+	_, err := scanGIF(bad)
+	assert.ErrorIs(t, err, errMalformedGIF, "unknown block")
+
+	// A colour table larger than the file.
+	bad = append([]byte{}, buf[:13]...)
+	bad[10] = 0x87
+	_, err = scanGIF(append(bad, 0x3b))
+	assert.ErrorIs(t, err, errMalformedGIF)
+}
+
+func FuzzScanGIF(f *testing.F) {
+	f.Add(testGIF(f, 0, red, green))
+	f.Add(withComment(testGIF(f, -1, red), "\x21\xf9\x04"))
+	f.Add([]byte("GIF89a"))
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		g, err := scanGIF(buf)
+		if err != nil {
+			return
+		}
+		end := 0
+		for _, frame := range g.frames {
+			if frame.end <= end || frame.end > len(buf) {
+				t.Fatalf("frame ends at %d after %d in %d bytes", frame.end, end, len(buf))
+			}
+			end = frame.end
+		}
+	})
+}
+
+func TestSelectFrames(t *testing.T) {
+	tests := []struct {
+		name         string
+		n, pick, max int
+		want         []frameSpan
+	}{
+		{"all", 3, -1, 0, []frameSpan{{0, 1}, {1, 2}, {2, 3}}},
+		{"max above the frames", 3, -1, 5, []frameSpan{{0, 1}, {1, 2}, {2, 3}}},
+		{"max", 4, -1, 2, []frameSpan{{0, 2}, {2, 4}}},
+		{"uneven max", 5, -1, 2, []frameSpan{{0, 2}, {2, 5}}},
+		{"max of one", 4, -1, 1, []frameSpan{{0, 4}}},
+		{"pick", 4, 2, 0, []frameSpan{{2, 3}}},
+		{"pick ignores max", 4, 3, 2, []frameSpan{{3, 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectFrames(tt.n, tt.pick, tt.max)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := selectFrames(4, 4, 0)
+	assert.ErrorIs(t, err, domainImage.ErrFrameOutOfRange)
+}
+
+func firstPixels(a *animation) []color.NRGBA {
+	var out []color.NRGBA
+	for _, f := range a.frames {
+		out = append(out, f.NRGBAAt(0, 0))
+	}
+	return out
+}
+
+func TestDecodeGIFAnimation(t *testing.T) {
+	buf := testGIF(t, 2, red, green, blue, white)
+
+	a, err := decodeGIFAnimation(buf, -1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []color.NRGBA{red, green, blue, white}, firstPixels(a))
+	assert.Equal(t, []int{100, 200, 300, 400}, a.delays)
+	assert.Equal(t, 3, a.loops)
+	assert.Equal(t, image.Rect(0, 0, 4, 4), a.frames[0].Bounds())
+
+	a, err = decodeGIFAnimation(buf, -1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []color.NRGBA{red, blue}, firstPixels(a))
+	assert.Equal(t, []int{300, 700}, a.delays, "kept frames show for the dropped ones")
+
+	a, err = decodeGIFAnimation(buf, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []color.NRGBA{blue}, firstPixels(a))
+	assert.Equal(t, []int{300}, a.delays)
+
+	_, err = decodeGIFAnimation(buf, 4, 0)
+	assert.ErrorIs(t, err, domainImage.ErrFrameOutOfRange)
+}
+
+func TestDecodeGIFAnimationDisposal(t *testing.T) {
+	pal := color.Palette{red, green}
+	full := image.NewPaletted(image.Rect(0, 0, 4, 4), pal)
+	corner := image.NewPaletted(image.Rect(0, 0, 2, 2), pal)
+	for i := range corner.Pix {
+		corner.Pix[i] = 1
+	}
+	var out bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&out, &gif.GIF{
+		Image:    []*image.Paletted{full, corner, full},
+		Delay:    []int{1, 1, 1},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone},
+	}))
+
+	a, err := decodeGIFAnimation(out.Bytes(), -1, 0)
+	require.NoError(t, err)
+	require.Len(t, a.frames, 3)
+	assert.Equal(t, green, a.frames[1].NRGBAAt(0, 0), "drawn over the first frame")
+	assert.Equal(t, red, a.frames[1].NRGBAAt(3, 3), "first frame shows through")
+}
+
+func TestDecodeGIFAnimationTooLarge(t *testing.T) {
+	buf := testGIF(t, 0, red, green)
+	// Claim a 65535×65535 logical screen; nothing may be decoded.
+	buf[6], buf[7], buf[8], buf[9] = 0xff, 0xff, 0xff, 0xff
+
+	_, err := decodeGIFAnimation(buf, -1, 0)
+	assert.ErrorIs(t, err, ports.ErrUnsupportedOperation)
+	_, err = decodeGIFAnimation(buf, 0, 0)
+	assert.ErrorIs(t, err, ports.ErrUnsupportedOperation)
+}
+
+func TestProbeAnimation(t *testing.T) {
+	assert.Equal(t, &domainImage.Animation{Frames: 3, LoopCount: 3, DurationMS: 600},
+		probeAnimation(testGIF(t, 2, red, green, blue)))
+	assert.Equal(t, &domainImage.Animation{Frames: 2, LoopCount: 1, DurationMS: 300},
+		probeAnimation(testGIF(t, -1, red, green)))
+	assert.Nil(t, probeAnimation(testGIF(t, 0, red)))
+	assert.Nil(t, probeAnimation([]byte("GIF89a")))
+}
+
+func TestEncodeGIFAnimation(t *testing.T) {
+	a, err := decodeGIFAnimation(testGIF(t, 0, red, green, blue), -1, 0)
+	require.NoError(t, err)
+
+	buf, err := encodeGIFAnimation(a)
+	require.NoError(t, err)
+	got, err := decodeGIFAnimation(buf, -1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, firstPixels(a), firstPixels(got))
+	assert.Equal(t, a.delays, got.delays)
+	assert.Equal(t, a.loops, got.loops)
+}
@@ -0,0 +1,154 @@
+//go:build cgo
+
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	stdimage "image"
+	"image/png"
+
+	"github.com/h2non/bimg"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// libvips as bound by bimg loads only the first frame of an image, so
+// animations are decoded and encoded here and every frame is rendered by
+// the pure-Go pipeline. libvips still decodes and encodes the frames of
+// animated WebP.
+
+// animates reports whether buf is an animation spec has to read frames
+// from: it picks a frame, or keeps every frame.
+func (p *BimgProcessor) animates(buf []byte, srcType bimg.ImageType, spec *image.TransformationSpec) bool {
+	if !isAnimatedGIF(buf) && !isAnimatedWebP(buf) {
+		return false
+	}
+	return spec.Frame != nil || keepsAnimation(spec, outputFormat(bimg.ImageTypeName(srcType), spec))
+}
+
+// transformAnimation renders an animated GIF or WebP. A picked frame is
+// rendered like a still source; otherwise every frame is kept.
+func (p *BimgProcessor) transformAnimation(ctx context.Context, buf []byte, srcType bimg.ImageType, spec *image.TransformationSpec, assets *ports.TransformAssets) (*ports.ProcessedImage, error) {
+	pick := -1
+	if spec.Frame != nil {
+		pick = *spec.Frame
+	}
+	var a *animation
+	var err error
+	if srcType == bimg.GIF {
+		a, err = decodeGIFAnimation(buf, pick, spec.MaxFrames)
+	} else {
+		a, err = decodeWebPAnimation(buf, pick, spec.MaxFrames, decodeStill)
+	}
+	if err != nil {
+		return nil, decodeAnimationError(err)
+	}
+	format := outputFormat(bimg.ImageTypeName(srcType), spec)
+
+	if spec.Frame != nil {
+		var still bytes.Buffer
+		if err := png.Encode(&still, a.frames[0]); err != nil {
+			return nil, fmt.Errorf("failed to encode frame: %w", err)
+		}
+		single := *spec
+		single.Frame = nil
+		single.Format = &format
+		return p.Transform(ctx, &still, &single, assets)
+	}
+
+	if !p.Capabilities().CanOutput(format) {
+		return nil, fmt.Errorf("%w: %s output", ports.ErrUnsupportedOperation, format)
+	}
+	if err := renderAnimation(a, spec, assets); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	quality := 0
+	if format == image.FormatGIF {
+		data, err = encodeGIFAnimation(a)
+	} else {
+		data, quality, err = p.encodeWebPAnimation(a, spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ports.ProcessedImage{
+		Data:     data,
+		MimeType: image.MimeTypeFor(format),
+		Width:    a.frames[0].Bounds().Dx(),
+		Height:   a.frames[0].Bounds().Dy(),
+		Size:     int64(len(data)),
+		Quality:  quality,
+	}, nil
+}
+
+// encodeWebPAnimation encodes the frames as an animated WebP at the quality
+// spec asks for. Quality auto scores the first frame only.
+func (p *BimgProcessor) encodeWebPAnimation(a *animation, spec *image.TransformationSpec) ([]byte, int, error) {
+	stills := make([][]byte, len(a.frames))
+	for i, frame := range a.frames {
+		var out bytes.Buffer
+		if err := png.Encode(&out, frame); err != nil {
+			return nil, 0, fmt.Errorf("failed to encode frame: %w", err)
+		}
+		stills[i] = out.Bytes()
+	}
+	encode := func(quality int) ([]byte, error) {
+		return encodeWebPAnimation(a, func(i int) ([]byte, error) {
+			out, err := bimg.NewImage(stills[i]).Process(bimg.Options{Type: bimg.WEBP, Quality: quality, NoAutoRotate: true})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode frame: %w", err)
+			}
+			return out, nil
+		})
+	}
+
+	if !searchesQuality(spec) {
+		quality := image.DefaultQuality
+		if spec.Quality != nil {
+			quality = int(*spec.Quality)
+		}
+		data, err := encode(quality)
+		return data, quality, err
+	}
+
+	var reference *stdimage.NRGBA
+	search := &qualitySearch{
+		encode: encode,
+		similarity: func(out []byte) (float64, error) {
+			if reference == nil {
+				var err error
+				if reference, err = comparable(stills[0]); err != nil {
+					return 0, err
+				}
+			}
+			first, err := firstWebPFrame(out)
+			if err != nil {
+				return 0, err
+			}
+			candidate, err := comparable(first)
+			if err != nil {
+				return 0, err
+			}
+			return ssim(reference, candidate), nil
+		},
+	}
+	return search.run(spec)
+}
+
+// decodeStill decodes a still image with libvips.
+func decodeStill(buf []byte) (*stdimage.NRGBA, error) {
+	out, err := bimg.NewImage(buf).Process(bimg.Options{Type: bimg.PNG, NoAutoRotate: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame: %w", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame: %w", err)
+	}
+	return toNRGBA(decoded), nil
+}
@@ -0,0 +1,37 @@
+//go:build !cgo
+
+package processor
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// BimgAvailable reports whether this build links libvips.
+const BimgAvailable = false
+
+var errBimgUnavailable = errors.New("bimg processor requires a cgo build with libvips")
+
+// BimgProcessor is a placeholder in builds without cgo. Select
+// StdLibImageProcessor instead.
+type BimgProcessor struct{}
+
+func NewBimgProcessor() *BimgProcessor {
+	return &BimgProcessor{}
+}
+
+func (p *BimgProcessor) Transform(ctx context.Context, srcReader io.Reader, spec *image.TransformationSpec, assets *ports.TransformAssets) (*ports.ProcessedImage, error) {
+	return nil, errBimgUnavailable
+}
+
+func (p *BimgProcessor) ExtractMetadata(ctx context.Context, reader io.Reader) (*ports.ImageMetadata, error) {
+	return nil, errBimgUnavailable
+}
+
+func (p *BimgProcessor) Capabilities() ports.ProcessorCapabilities {
+	return ports.ProcessorCapabilities{}
+}
@@ -0,0 +1,191 @@
+//go:build cgo
+
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	"github.com/h2non/bimg"
+
+	domainImage "image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+const (
+	watermarkFont = "sans bold 24"
+	// textCanvasPadding matches the offset libvips renders watermark text at.
+	textCanvasPadding = 100
+)
+
+// finish applies the watermark, if any, and encodes the result as outType.
+// libvips can only tile text watermarks, so text is rendered into a
+// transparent overlay first and placed like an image watermark.
+func (p *BimgProcessor) finish(buf []byte, wm *domainImage.WatermarkSpec, assets *ports.TransformAssets, outType bimg.ImageType, quality int) ([]byte, error) {
+	options := bimg.Options{Type: outType, Quality: quality, NoAutoRotate: true, StripMetadata: true}
+	if wm != nil {
+		watermark, err := watermarkOptions(buf, wm, assets)
+		if err != nil {
+			return nil, err
+		}
+		options.WatermarkImage = watermark
+	}
+
+	out, err := bimg.NewImage(buf).Process(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return out, nil
+}
+
+// watermarkOptions builds the overlay for wm and positions it on buf. The
+// zero value is returned if the watermark renders nothing.
+func watermarkOptions(buf []byte, wm *domainImage.WatermarkSpec, assets *ports.TransformAssets) (bimg.WatermarkImage, error) {
+	size, err := bimg.Size(buf)
+	if err != nil {
+		return bimg.WatermarkImage{}, fmt.Errorf("failed to get image size: %w", err)
+	}
+
+	var overlay []byte
+	if wm.ImageID != "" {
+		if assets == nil || len(assets.WatermarkImage) == 0 {
+			return bimg.WatermarkImage{}, errWatermarkImageMissing
+		}
+		overlay, err = fitOverlay(assets.WatermarkImage, size.Width/2, size.Height/2)
+	} else {
+		overlay, err = renderText(wm.Text, size.Width)
+	}
+	if err != nil || overlay == nil {
+		return bimg.WatermarkImage{}, err
+	}
+
+	osize, err := bimg.Size(overlay)
+	if err != nil {
+		return bimg.WatermarkImage{}, fmt.Errorf("failed to get watermark size: %w", err)
+	}
+	left, top := gravityOffset(wm.EffectiveGravity(), size.Width, size.Height, osize.Width, osize.Height,
+		watermarkMargin(size.Width, size.Height))
+
+	return bimg.WatermarkImage{
+		Left:    left,
+		Top:     top,
+		Buf:     overlay,
+		Opacity: float32(wm.EffectiveOpacity()),
+	}, nil
+}
+
+// fitOverlay scales an overlay image down to fit within maxW×maxH, keeping
+// its aspect ratio and alpha channel. Overlays are never enlarged.
+func fitOverlay(buf []byte, maxW, maxH int) ([]byte, error) {
+	size, err := bimg.Size(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watermark image: %w", err)
+	}
+
+	scale := math.Min(1, math.Min(float64(maxW)/float64(size.Width), float64(maxH)/float64(size.Height)))
+	out, err := bimg.NewImage(buf).Process(bimg.Options{
+		Width:  max(1, int(math.Round(float64(size.Width)*scale))),
+		Height: max(1, int(math.Round(float64(size.Height)*scale))),
+		Force:  true,
+		Type:   bimg.PNG,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scale watermark image: %w", err)
+	}
+	return out, nil
+}
+
+// renderText renders text as white glyphs on a transparent PNG sized for an
+// image of the given width. It returns nil if the text renders no pixels.
+func renderText(text string, width int) ([]byte, error) {
+	// Glyphs are about a twentieth of the image width and wrap at 80%.
+	textPx := max(8, width/20)
+	wrapWidth := max(textPx, width*8/10)
+	charsPerLine := max(1, wrapWidth*2/textPx)
+	lines := len([]rune(text))/charsPerLine + 2
+
+	// libvips paints the text into an existing image, so start from an
+	// opaque black canvas and use the painted luminance as the alpha mask.
+	canvas := image.NewRGBA(image.Rect(0, 0, wrapWidth+2*textCanvasPadding, lines*textPx*3/2+2*textCanvasPadding))
+	for i := 3; i < len(canvas.Pix); i += 4 {
+		canvas.Pix[i] = 0xff
+	}
+	var raw bytes.Buffer
+	if err := png.Encode(&raw, canvas); err != nil {
+		return nil, fmt.Errorf("failed to encode text canvas: %w", err)
+	}
+
+	painted, err := bimg.NewImage(raw.Bytes()).Process(bimg.Options{
+		Type: bimg.PNG,
+		Watermark: bimg.Watermark{
+			Text:        text,
+			Font:        watermarkFont,
+			Width:       wrapWidth,
+			DPI:         textPx * 72 / 24,
+			Margin:      textCanvasPadding,
+			Opacity:     1,
+			NoReplicate: true,
+			Background:  bimg.Color{R: 255, G: 255, B: 255},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render watermark text: %w", err)
+	}
+
+	mask, err := png.Decode(bytes.NewReader(painted))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode watermark text: %w", err)
+	}
+
+	// Crop to the painted glyphs and turn luminance into alpha.
+	bounds := image.Rectangle{}
+	b := mask.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, _, _, _ := mask.At(x, y).RGBA(); r > 0 {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if bounds.Empty() {
+		return nil, nil
+	}
+
+	overlay := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, _, _, _ := mask.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			overlay.SetNRGBA(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: uint8(r >> 8)})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, overlay); err != nil {
+		return nil, fmt.Errorf("failed to encode watermark text: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// filterPNG runs the colour filters on a PNG and re-encodes it losslessly.
+func filterPNG(buf []byte, f *domainImage.FilterSpec) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image for filters: %w", err)
+	}
+
+	dst, err := applyColorFilters(src, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := enc.Encode(&out, dst); err != nil {
+		return nil, fmt.Errorf("failed to encode filtered image: %w", err)
+	}
+	return out.Bytes(), nil
+}
@@ -1,9 +1,18 @@
+//go:build cgo
+
 package processor
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"fmt"
+	stdimage "image"
+	"image/png"
 	"io"
+	"math"
+	"slices"
+	"sync"
 
 	"github.com/h2non/bimg"
 
@@ -11,36 +20,30 @@ import (
 	"image-processing-service/internal/ports"
 )
 
+// BimgAvailable reports whether this build links libvips.
+const BimgAvailable = true
+
 type BimgProcessor struct{}
 
 func NewBimgProcessor() *BimgProcessor {
 	return &BimgProcessor{}
 }
 
-func (p *BimgProcessor) Transform(ctx context.Context, srcReader io.Reader, spec *image.TransformationSpec) (*ports.ProcessedImage, error) {
+func (p *BimgProcessor) Transform(ctx context.Context, srcReader io.Reader, spec *image.TransformationSpec, assets *ports.TransformAssets) (*ports.ProcessedImage, error) {
 	buffer, err := io.ReadAll(srcReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read source image: %w", err)
 	}
 
-	img := bimg.NewImage(buffer)
-	options := bimg.Options{}
-
-	// Resize
-	if spec.Resize != nil {
-		options.Width = spec.Resize.Width
-		options.Height = spec.Resize.Height
-		options.Embed = true // Keep aspect ratio by default or as needed
+	srcType := bimg.DetermineImageType(buffer)
+	if p.animates(buffer, srcType, spec) {
+		return p.transformAnimation(ctx, buffer, srcType, spec, assets)
 	}
-
-	// Crop
-	if spec.Crop != nil {
-		options.Top = spec.Crop.Y
-		options.Left = spec.Crop.X
-		options.AreaWidth = spec.Crop.Width
-		options.AreaHeight = spec.Crop.Height
-		options.Crop = true
+	if err := checkStillFrame(spec); err != nil {
+		return nil, err
 	}
+	kept := readMetadata(buffer).keep(spec.Metadata)
+	options := bimg.Options{}
 
 	// Rotate
 	if spec.Rotate != nil {
@@ -48,42 +51,169 @@ func (p *BimgProcessor) Transform(ctx context.Context, srcReader io.Reader, spec
 	}
 
 	// Flip/Mirror
-	if spec.Flip {
+	if spec.Flipped() {
 		options.Flip = true
 	}
-	if spec.Mirror {
+	if spec.Mirrored() {
 		options.Flop = true
 	}
 
+	inW, inH, err := orientedSize(buffer, spec)
+	if err != nil {
+		return nil, err
+	}
+	f := newFocus(spec.FocalPoint, spec, inW, inH)
+
+	// Crop: libvips extracts areas only after resizing, so the area is cut
+	// out of the rotated original in a lossless pass of its own.
+	if c := spec.Crop; c != nil {
+		if err := c.CheckBounds(inW, inH); err != nil {
+			return nil, err
+		}
+		var x, y int
+		if c.IsSmart() {
+			x, y, err = p.smartCrop(buffer, options, inW, inH, c)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			x, y = cropOrigin(c, f, inW, inH)
+		}
+
+		options.Top = y
+		options.Left = x
+		options.AreaWidth = c.Width
+		options.AreaHeight = c.Height
+		options.Type = bimg.PNG
+
+		buffer, err = bimg.NewImage(buffer).Process(options)
+		if err != nil {
+			return nil, fmt.Errorf("bimg crop failed: %w", err)
+		}
+		options = bimg.Options{NoAutoRotate: true}
+		inW, inH = c.Width, c.Height
+		f = f.shift(x, y)
+	}
+
+	img := bimg.NewImage(buffer)
+
+	// Resize: plan the output like StdLibImageProcessor. Cover crops scale
+	// to the planned size and extract the area around the focal point in
+	// the same pass; letterboxes are left to libvips.
+	if spec.Resize != nil {
+		plan := planResize(inW, inH, spec.Resize)
+		options.Width, options.Height = plan.outW, plan.outH
+		switch {
+		case plan.crops():
+			options.Width, options.Height = plan.scaleW, plan.scaleH
+			options.Force = true
+			options.Left, options.Top = centreOn(f.scale(inW, inH, plan.scaleW, plan.scaleH), plan.scaleW, plan.scaleH, plan.outW, plan.outH)
+			options.AreaWidth, options.AreaHeight = plan.outW, plan.outH
+		case plan.pads():
+			options.Embed = true
+			options.Enlarge = true
+		default:
+			options.Force = true
+		}
+
+		if spec.Resize.Background != "" {
+			bg, err := parseHexColor(spec.Resize.Background)
+			if err != nil {
+				return nil, err
+			}
+			options.Background = bimg.Color{R: bg.R, G: bg.G, B: bg.B}
+			options.Extend = bimg.ExtendBackground
+		}
+	}
+
 	// Quality
-	if spec.Quality != nil {
-		options.Quality = *spec.Quality
+	if spec.Quality != nil && !spec.Quality.IsAuto() {
+		options.Quality = int(*spec.Quality)
 	}
 
 	// Format
+	options.Type = srcType
 	if spec.Format != nil {
-		options.Type = p.toBimgType(*spec.Format)
+		if !p.Capabilities().CanOutput(*spec.Format) {
+			return nil, fmt.Errorf("%w: %s output", ports.ErrUnsupportedOperation, *spec.Format)
+		}
+		options.Type = p.toBimgType(image.CanonicalFormat(*spec.Format))
 	}
 
-	// Filters
+	// Filters: blur and sharpen run in libvips, the colour filters in Go
 	if spec.Filters != nil {
-		if spec.Filters.Grayscale {
-			options.Interpretation = bimg.InterpretationBW
-		}
 		if spec.Filters.Blur > 0 {
 			options.GaussianBlur = bimg.GaussianBlur{
 				Sigma: float64(spec.Filters.Blur),
 			}
 		}
+		if spec.Filters.Sharpen > 0 {
+			options.Sharpen = bimg.Sharpen{
+				Radius: 1,
+				X1:     2,
+				Y2:     10,
+				Y3:     20,
+				M1:     spec.Filters.Sharpen / 3,
+				M2:     spec.Filters.Sharpen,
+			}
+		}
 	}
 
-	// Watermark - Not implemented in this version
+	// Colour filters and the watermark work on the rendered pixels and the
+	// quality search encodes them repeatedly, so the other operations are
+	// rendered losslessly first and encoded at the end.
+	colorFilters := needsColorFilters(spec.Filters)
+	lossy := image.IsLossyFormat(bimg.ImageTypeName(options.Type))
+	search := lossy && searchesQuality(spec)
+	postProcess := colorFilters || spec.Watermark != nil || search
+	outType, outQuality := options.Type, options.Quality
+	if postProcess {
+		options.Type = bimg.PNG
+		options.Quality = 0
+	}
 
+	options.StripMetadata = true
 	newBuffer, err := img.Process(options)
 	if err != nil {
 		return nil, fmt.Errorf("bimg processing failed: %w", err)
 	}
 
+	if colorFilters {
+		newBuffer, err = filterPNG(newBuffer, spec.Filters)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	quality := 0
+	if lossy {
+		quality = cmp.Or(outQuality, image.DefaultQuality)
+	}
+	switch {
+	case search:
+		if spec.Watermark != nil {
+			newBuffer, err = p.finish(newBuffer, spec.Watermark, assets, bimg.PNG, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		newBuffer, quality, err = p.searchQuality(newBuffer, outType, spec, kept)
+		if err != nil {
+			return nil, err
+		}
+	case postProcess:
+		newBuffer, err = p.finish(newBuffer, spec.Watermark, assets, outType, outQuality)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !search {
+		newBuffer, err = withMetadata(newBuffer, kept)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write metadata: %w", err)
+		}
+	}
+
 	metadata, err := bimg.Metadata(newBuffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get processed image metadata: %w", err)
@@ -95,28 +225,158 @@ func (p *BimgProcessor) Transform(ctx context.Context, srcReader io.Reader, spec
 		Width:    metadata.Size.Width,
 		Height:   metadata.Size.Height,
 		Size:     int64(len(newBuffer)),
+		Quality:  quality,
 	}, nil
 }
 
+// searchQuality encodes the lossless render buf as outType at the quality
+// found for spec's quality auto or MaxBytes. The kept metadata counts
+// towards MaxBytes.
+func (p *BimgProcessor) searchQuality(buf []byte, outType bimg.ImageType, spec *image.TransformationSpec, kept metadataBlocks) ([]byte, int, error) {
+	var reference *stdimage.NRGBA
+	search := &qualitySearch{
+		encode: func(quality int) ([]byte, error) {
+			out, err := bimg.NewImage(buf).Process(bimg.Options{Type: outType, Quality: quality, NoAutoRotate: true, StripMetadata: true})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode image: %w", err)
+			}
+			return withMetadata(out, kept)
+		},
+		similarity: func(out []byte) (float64, error) {
+			if reference == nil {
+				var err error
+				if reference, err = comparable(buf); err != nil {
+					return 0, err
+				}
+			}
+			candidate, err := comparable(out)
+			if err != nil {
+				return 0, err
+			}
+			return ssim(reference, candidate), nil
+		},
+	}
+	return search.run(spec)
+}
+
+// comparable decodes buf at the size ssim compares images at.
+func comparable(buf []byte) (*stdimage.NRGBA, error) {
+	size, err := bimg.Size(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image size: %w", err)
+	}
+	w, h := ssimDims(size.Width, size.Height)
+	out, err := bimg.NewImage(buf).Process(bimg.Options{Width: w, Height: h, Force: true, Type: bimg.PNG, NoAutoRotate: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image for comparison: %w", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image for comparison: %w", err)
+	}
+	return toNRGBA(decoded), nil
+}
+
+// smartCrop places a smart crop with the pure-Go analysis, run on a
+// thumbnail of the w×h oriented image that libvips renders with options.
+func (p *BimgProcessor) smartCrop(buf []byte, options bimg.Options, w, h int, c *image.CropSpec) (int, int, error) {
+	scale := math.Min(1, float64(smartCropSize)/float64(max(w, h)))
+	options.Width = max(1, int(math.Round(float64(w)*scale)))
+	options.Height = max(1, int(math.Round(float64(h)*scale)))
+	options.Force = true
+	options.Type = bimg.PNG
+
+	thumb, err := bimg.NewImage(buf).Process(options)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bimg smart crop failed: %w", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to decode smart crop thumbnail: %w", err)
+	}
+
+	x, y := smartCrop(toNRGBA(decoded),
+		max(1, int(math.Round(float64(c.Width)*scale))),
+		max(1, int(math.Round(float64(c.Height)*scale))),
+		c.Gravity)
+	left := int(math.Round(float64(x) / scale))
+	top := int(math.Round(float64(y) / scale))
+	return max(0, min(left, w-c.Width)), max(0, min(top, h-c.Height)), nil
+}
+
+// orientedSize returns the size of buf once libvips has applied the explicit
+// or EXIF rotation for spec.
+func orientedSize(buf []byte, spec *image.TransformationSpec) (int, int, error) {
+	meta, err := bimg.Metadata(buf)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get image metadata: %w", err)
+	}
+
+	angle := 0
+	if spec.Rotate != nil && *spec.Rotate > 0 {
+		angle = *spec.Rotate
+	} else {
+		angle, _ = orientationTransform(meta.Orientation)
+	}
+
+	if angle == 90 || angle == 270 {
+		return meta.Size.Height, meta.Size.Width, nil
+	}
+	return meta.Size.Width, meta.Size.Height, nil
+}
+
 func (p *BimgProcessor) ExtractMetadata(ctx context.Context, reader io.Reader) (*ports.ImageMetadata, error) {
 	buffer, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image for metadata: %w", err)
 	}
 
-	size, err := bimg.Size(buffer)
+	meta, err := bimg.Metadata(buffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get image size: %w", err)
 	}
 
+	orientation := cmp.Or(meta.Orientation, 1)
+	width, height := displaySize(meta.Size.Width, meta.Size.Height, orientation)
 	return &ports.ImageMetadata{
-		Width:    size.Width,
-		Height:   size.Height,
-		MimeType: p.getMimeType(bimg.DetermineImageType(buffer)),
-		Size:     int64(len(buffer)),
+		Width:       width,
+		Height:      height,
+		MimeType:    p.getMimeType(bimg.DetermineImageType(buffer)),
+		Size:        int64(len(buffer)),
+		HasAlpha:    meta.Alpha,
+		Orientation: orientation,
+		Animation:   probeAnimation(buffer),
+		Photo:       photoMetadata(buffer, orientation),
 	}, nil
 }
 
+// bimgInputFormats are formats libvips may load besides the output formats.
+var bimgInputFormats = []string{"svg", "pdf"}
+
+// bimgCapabilities asks libvips once which formats this build loads and
+// saves.
+var bimgCapabilities = sync.OnceValue(func() ports.ProcessorCapabilities {
+	p := &BimgProcessor{}
+	caps := ports.ProcessorCapabilities{Input: []string{}, Output: []string{}}
+	for _, format := range append(slices.Clone(image.Formats), bimgInputFormats...) {
+		t := p.toBimgType(format)
+		if t == bimg.UNKNOWN {
+			continue
+		}
+		if bimg.IsTypeSupported(t) {
+			caps.Input = append(caps.Input, format)
+		}
+		if bimg.IsTypeSupportedSave(t) && slices.Contains(image.Formats, format) {
+			caps.Output = append(caps.Output, format)
+		}
+	}
+	return caps
+})
+
+func (p *BimgProcessor) Capabilities() ports.ProcessorCapabilities {
+	return bimgCapabilities()
+}
+
 func (p *BimgProcessor) getMimeType(t bimg.ImageType) string {
 	switch t {
 	case bimg.JPEG:
@@ -0,0 +1,221 @@
+package processor
+
+import (
+	"image"
+	"math"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+// smartCropSize is the longest side of the thumbnail smart crops analyse.
+const smartCropSize = 256
+
+// focus is a focal point in pixels of the image being worked on.
+type focus struct {
+	x, y float64
+}
+
+// newFocus places fp on the w×h image produced by the spec's explicit
+// rotation, flip and mirror. The EXIF orientation is not applied as focal
+// points are set on the image as displayed.
+func newFocus(fp *domainImage.FocalPoint, spec *domainImage.TransformationSpec, w, h int) *focus {
+	if fp == nil {
+		return nil
+	}
+
+	x, y := fp.X, fp.Y
+	if spec.Rotate != nil {
+		switch *spec.Rotate {
+		case 90:
+			x, y = 1-y, x
+		case 180:
+			x, y = 1-x, 1-y
+		case 270:
+			x, y = y, 1-x
+		}
+	}
+	if spec.Flipped() {
+		x = 1 - x
+	}
+	if spec.Mirrored() {
+		y = 1 - y
+	}
+	return &focus{x: x * float64(w), y: y * float64(h)}
+}
+
+// shift moves the focus into an area cut out at left,top.
+func (f *focus) shift(left, top int) *focus {
+	if f == nil {
+		return nil
+	}
+	return &focus{x: f.x - float64(left), y: f.y - float64(top)}
+}
+
+// scale follows the focus through a resize from inW×inH to outW×outH.
+func (f *focus) scale(inW, inH, outW, outH int) *focus {
+	if f == nil {
+		return nil
+	}
+	return &focus{
+		x: f.x * float64(outW) / float64(inW),
+		y: f.y * float64(outH) / float64(inH),
+	}
+}
+
+// centreOn returns the top-left corner of a cw×ch area of a w×h image
+// centred on f as far as the edges allow, or on the image centre, rounded
+// like the libvips crop, when there is no focus.
+func centreOn(f *focus, w, h, cw, ch int) (int, int) {
+	if f == nil {
+		return (w - cw + 1) / 2, (h - ch + 1) / 2
+	}
+	left := int(math.Round(f.x - float64(cw)/2))
+	top := int(math.Round(f.y - float64(ch)/2))
+	return max(0, min(left, w-cw)), max(0, min(top, h-ch))
+}
+
+// cropOrigin returns the top-left corner of a positioned crop within a w×h
+// image. Smart crops are placed by smartCrop instead.
+func cropOrigin(c *domainImage.CropSpec, f *focus, w, h int) (int, int) {
+	switch g := c.EffectiveGravity(); g {
+	case "":
+		return c.X, c.Y
+	case domainImage.CropFocal:
+		return centreOn(f, w, h, c.Width, c.Height)
+	default:
+		return gravityOffset(g, w, h, c.Width, c.Height, 0)
+	}
+}
+
+// smartCrop returns the top-left corner of the most interesting w×h area of
+// img according to strategy. Large images are analysed at smartCropSize.
+func smartCrop(img *image.NRGBA, w, h int, strategy string) (int, int) {
+	b := img.Bounds()
+	scale := math.Min(1, float64(smartCropSize)/float64(max(b.Dx(), b.Dy())))
+	if scale < 1 {
+		img = resample(img,
+			max(1, int(math.Round(float64(b.Dx())*scale))),
+			max(1, int(math.Round(float64(b.Dy())*scale))))
+	}
+	sb := img.Bounds()
+	sw := max(1, min(sb.Dx(), int(math.Round(float64(w)*scale))))
+	sh := max(1, min(sb.Dy(), int(math.Round(float64(h)*scale))))
+
+	var x, y int
+	if strategy == domainImage.CropEntropy {
+		x, y = entropyCrop(img, sw, sh)
+	} else {
+		x, y = attentionCrop(img, sw, sh)
+	}
+
+	left := int(math.Round(float64(x) / scale))
+	top := int(math.Round(float64(y) / scale))
+	return max(0, min(left, b.Dx()-w)), max(0, min(top, b.Dy()-h))
+}
+
+// entropyCrop trims img down to w×h, repeatedly dropping whichever edge
+// slice carries less information, as the libvips entropy strategy does.
+func entropyCrop(img *image.NRGBA, w, h int) (int, int) {
+	area := img.Bounds()
+	for area.Dx() > w {
+		slice := min(area.Dx()-w, max(1, area.Dx()/16))
+		left := image.Rect(area.Min.X, area.Min.Y, area.Min.X+slice, area.Max.Y)
+		right := image.Rect(area.Max.X-slice, area.Min.Y, area.Max.X, area.Max.Y)
+		if entropy(img, left) < entropy(img, right) {
+			area.Min.X += slice
+		} else {
+			area.Max.X -= slice
+		}
+	}
+	for area.Dy() > h {
+		slice := min(area.Dy()-h, max(1, area.Dy()/16))
+		top := image.Rect(area.Min.X, area.Min.Y, area.Max.X, area.Min.Y+slice)
+		bottom := image.Rect(area.Min.X, area.Max.Y-slice, area.Max.X, area.Max.Y)
+		if entropy(img, top) < entropy(img, bottom) {
+			area.Min.Y += slice
+		} else {
+			area.Max.Y -= slice
+		}
+	}
+	return area.Min.X, area.Min.Y
+}
+
+// entropy is the Shannon entropy of the luminance histogram of r.
+func entropy(img *image.NRGBA, r image.Rectangle) float64 {
+	var hist [256]int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			p := img.Pix[img.PixOffset(x, y):]
+			hist[uint8(clamp8(luma(float64(p[0]), float64(p[1]), float64(p[2]))))]++
+		}
+	}
+
+	n := float64(r.Dx() * r.Dy())
+	e := 0.0
+	for _, c := range hist {
+		if c > 0 {
+			p := float64(c) / n
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
+
+// attentionCrop scores every pixel for edges, saturation and skin tones and
+// returns the w×h window with the highest total. Ties keep the centre.
+func attentionCrop(img *image.NRGBA, w, h int) (int, int) {
+	b := img.Bounds()
+	iw, ih := b.Dx(), b.Dy()
+
+	lum := make([]float64, iw*ih)
+	for y := 0; y < ih; y++ {
+		for x := 0; x < iw; x++ {
+			p := img.Pix[img.PixOffset(x, y):]
+			lum[y*iw+x] = luma(float64(p[0]), float64(p[1]), float64(p[2]))
+		}
+	}
+
+	// Summed-area table of the scores, one row and column of padding.
+	sum := make([]float64, (iw+1)*(ih+1))
+	for y := 0; y < ih; y++ {
+		row := 0.0
+		for x := 0; x < iw; x++ {
+			row += attention(img, lum, iw, ih, x, y)
+			sum[(y+1)*(iw+1)+x+1] = sum[y*(iw+1)+x+1] + row
+		}
+	}
+	window := func(x, y int) float64 {
+		return sum[(y+h)*(iw+1)+x+w] - sum[y*(iw+1)+x+w] - sum[(y+h)*(iw+1)+x] + sum[y*(iw+1)+x]
+	}
+
+	bestX, bestY := centreOn(nil, iw, ih, w, h)
+	best := window(bestX, bestY)
+	for y := 0; y+h <= ih; y++ {
+		for x := 0; x+w <= iw; x++ {
+			if s := window(x, y); s > best {
+				best, bestX, bestY = s, x, y
+			}
+		}
+	}
+	return bestX, bestY
+}
+
+// attention scores one pixel: its edge strength, its saturation and a bonus
+// for skin tones, weighted by its opacity.
+func attention(img *image.NRGBA, lum []float64, w, h, x, y int) float64 {
+	at := func(x, y int) float64 {
+		return lum[max(0, min(y, h-1))*w+max(0, min(x, w-1))]
+	}
+	edge := math.Abs(4*at(x, y) - at(x-1, y) - at(x+1, y) - at(x, y-1) - at(x, y+1))
+
+	p := img.Pix[img.PixOffset(x, y):]
+	r, g, bl := float64(p[0]), float64(p[1]), float64(p[2])
+	saturation := math.Max(r, math.Max(g, bl)) - math.Min(r, math.Min(g, bl))
+
+	skin := 0.0
+	if r > 95 && g > 40 && bl > 20 && r > g && r > bl && r-math.Min(g, bl) > 15 && math.Abs(r-g) > 15 {
+		skin = 128
+	}
+
+	return (edge + saturation/2 + skin) * float64(p[3]) / 255
+}
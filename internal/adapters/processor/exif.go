@@ -0,0 +1,388 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+// EXIF tags read here, by IFD.
+const (
+	tagMake        = 0x010f
+	tagModel       = 0x0110
+	tagOrientation = 0x0112
+	tagEXIFIFD     = 0x8769
+	tagGPSIFD      = 0x8825
+
+	tagExposureTime       = 0x829a
+	tagFNumber            = 0x829d
+	tagISO                = 0x8827
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+	tagFocalLength        = 0x920a
+	tagColorSpace         = 0xa001
+	tagLensModel          = 0xa434
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+)
+
+// tiffTypeSizes are the sizes of the TIFF field types, by type number.
+var tiffTypeSizes = [...]int{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// tiffFile reads the IFDs of a TIFF structure, the layout of EXIF.
+type tiffFile struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+// tiffEntry is a field of an IFD. at is the offset of the entry; it is 0
+// for the zero entry that missing tags read as.
+type tiffEntry struct {
+	at    int
+	typ   int
+	count int
+}
+
+func newTIFF(b []byte) *tiffFile {
+	if len(b) < 8 {
+		return nil
+	}
+	switch string(b[:4]) {
+	case "II*\x00":
+		return &tiffFile{b: b, order: binary.LittleEndian}
+	case "MM\x00*":
+		return &tiffFile{b: b, order: binary.BigEndian}
+	}
+	return nil
+}
+
+func isTIFF(buf []byte) bool {
+	return newTIFF(buf) != nil
+}
+
+// ifd0 returns the offset of the first IFD.
+func (t *tiffFile) ifd0() int {
+	return int(t.order.Uint32(t.b[4:]))
+}
+
+// entries returns the fields of the IFD at offset, by tag.
+func (t *tiffFile) entries(offset int) map[uint16]tiffEntry {
+	if offset <= 0 || offset+2 > len(t.b) {
+		return nil
+	}
+	out := make(map[uint16]tiffEntry)
+	n := int(t.order.Uint16(t.b[offset:]))
+	for i := range n {
+		at := offset + 2 + i*12
+		if at+12 > len(t.b) {
+			break
+		}
+		out[t.order.Uint16(t.b[at:])] = tiffEntry{
+			at:    at,
+			typ:   int(t.order.Uint16(t.b[at+2:])),
+			count: int(t.order.Uint32(t.b[at+4:])),
+		}
+	}
+	return out
+}
+
+// value returns the bytes of an entry's value, or nil when it is missing
+// or out of bounds.
+func (t *tiffFile) value(e tiffEntry) []byte {
+	if e.at == 0 || e.typ <= 0 || e.typ >= len(tiffTypeSizes) || e.count < 0 || e.count > len(t.b) {
+		return nil
+	}
+	size := tiffTypeSizes[e.typ] * e.count
+	at := e.at + 8
+	if size > 4 {
+		at = int(t.order.Uint32(t.b[e.at+8:]))
+	}
+	if at < 0 || at+size > len(t.b) {
+		return nil
+	}
+	return t.b[at : at+size]
+}
+
+func (t *tiffFile) string(e tiffEntry) string {
+	v := t.value(e)
+	if i := bytes.IndexByte(v, 0); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(string(v))
+}
+
+// uint reads the first value of a BYTE, SHORT or LONG entry.
+func (t *tiffFile) uint(e tiffEntry) int {
+	v := t.value(e)
+	if len(v) == 0 {
+		return 0
+	}
+	switch e.typ {
+	case 1:
+		return int(v[0])
+	case 3:
+		return int(t.order.Uint16(v))
+	case 4:
+		return int(t.order.Uint32(v))
+	}
+	return 0
+}
+
+// rationals reads a RATIONAL or SRATIONAL entry.
+func (t *tiffFile) rationals(e tiffEntry) []float64 {
+	v := t.value(e)
+	if e.typ != 5 && e.typ != 10 {
+		return nil
+	}
+	out := make([]float64, 0, len(v)/8)
+	for i := 0; i+8 <= len(v); i += 8 {
+		num, den := t.order.Uint32(v[i:]), t.order.Uint32(v[i+4:])
+		if den == 0 {
+			return nil
+		}
+		if e.typ == 10 {
+			out = append(out, float64(int32(num))/float64(int32(den)))
+		} else {
+			out = append(out, float64(num)/float64(den))
+		}
+	}
+	return out
+}
+
+func (t *tiffFile) rational(e tiffEntry) float64 {
+	if r := t.rationals(e); len(r) > 0 {
+		return r[0]
+	}
+	return 0
+}
+
+// exifOrientation returns the EXIF orientation (1-8) of a JPEG, PNG or
+// WebP, or 1 when it carries none.
+func exifOrientation(buf []byte) int {
+	return tiffOrientation(readMetadata(buf).exif)
+}
+
+func tiffOrientation(exif []byte) int {
+	t := newTIFF(exif)
+	if t == nil {
+		return 1
+	}
+	if v := t.uint(t.entries(t.ifd0())[tagOrientation]); v >= 1 && v <= 8 {
+		return v
+	}
+	return 1
+}
+
+// uprightEXIF returns a copy of exif with its orientation set to 1.
+func uprightEXIF(exif []byte) []byte {
+	exif = slices.Clone(exif)
+	t := newTIFF(exif)
+	if t == nil {
+		return exif
+	}
+	if e, ok := t.entries(t.ifd0())[tagOrientation]; ok && e.typ == 3 {
+		t.order.PutUint16(exif[e.at+8:], 1)
+	}
+	return exif
+}
+
+// stripGPS empties the GPS IFD of exif in place: its entries and their
+// values are zeroed, so every offset in exif stays valid.
+func stripGPS(exif []byte) {
+	t := newTIFF(exif)
+	if t == nil {
+		return
+	}
+	pointer, ok := t.entries(t.ifd0())[tagGPSIFD]
+	if !ok {
+		return
+	}
+	offset := t.uint(pointer)
+	if offset <= 0 || offset+2 > len(exif) {
+		return
+	}
+	entries := t.entries(offset)
+	for _, e := range entries {
+		if v := t.value(e); len(v) > 4 {
+			clear(v)
+		}
+		clear(exif[e.at : e.at+12])
+	}
+	t.order.PutUint16(exif[offset:], 0)
+	if next := offset + 2 + len(entries)*12; next+4 <= len(exif) {
+		clear(exif[next : next+4])
+	}
+}
+
+// photoMetadata reads the camera, capture time, GPS position and colour
+// profile of an image with the given EXIF orientation. It returns nil when
+// the image records none.
+func photoMetadata(buf []byte, orientation int) *domainImage.PhotoMetadata {
+	m := readMetadata(buf)
+	if isTIFF(buf) {
+		m.exif = buf
+	}
+
+	photo := &domainImage.PhotoMetadata{}
+	if orientation > 1 {
+		photo.Orientation = orientation
+	}
+	if t := newTIFF(m.exif); t != nil {
+		ifd0 := t.entries(t.ifd0())
+		exif := t.entries(t.uint(ifd0[tagEXIFIFD]))
+		camera := domainImage.Camera{
+			Make:         t.string(ifd0[tagMake]),
+			Model:        t.string(ifd0[tagModel]),
+			Lens:         t.string(exif[tagLensModel]),
+			FocalLength:  roundTo(t.rational(exif[tagFocalLength]), 2),
+			FNumber:      roundTo(t.rational(exif[tagFNumber]), 2),
+			ExposureTime: exposureTime(t.rational(exif[tagExposureTime])),
+			ISO:          t.uint(exif[tagISO]),
+		}
+		if camera != (domainImage.Camera{}) {
+			photo.Camera = &camera
+		}
+		photo.CapturedAt = captureTime(t.string(exif[tagDateTimeOriginal]), t.string(exif[tagOffsetTimeOriginal]))
+		photo.GPS = gpsPosition(t, t.entries(t.uint(ifd0[tagGPSIFD])))
+		if t.uint(exif[tagColorSpace]) == 1 {
+			photo.ColorProfile = &domainImage.ColorProfile{Name: "sRGB", ColorSpace: "RGB"}
+		}
+	}
+	if profile := iccProfile(m.icc); profile != nil {
+		photo.ColorProfile = profile
+	}
+
+	if *photo == (domainImage.PhotoMetadata{}) {
+		return nil
+	}
+	return photo
+}
+
+func gpsPosition(t *tiffFile, gps map[uint16]tiffEntry) *domainImage.GPS {
+	lat := degrees(t.rationals(gps[tagGPSLatitude]))
+	lon := degrees(t.rationals(gps[tagGPSLongitude]))
+	if math.IsNaN(lat) || math.IsNaN(lon) {
+		return nil
+	}
+	if t.string(gps[tagGPSLatitudeRef]) == "S" {
+		lat = -lat
+	}
+	if t.string(gps[tagGPSLongitudeRef]) == "W" {
+		lon = -lon
+	}
+	position := &domainImage.GPS{Latitude: roundTo(lat, 6), Longitude: roundTo(lon, 6)}
+	if alt := t.rationals(gps[tagGPSAltitude]); len(alt) > 0 {
+		a := roundTo(alt[0], 1)
+		if t.uint(gps[tagGPSAltitudeRef]) == 1 {
+			a = -a
+		}
+		position.Altitude = &a
+	}
+	return position
+}
+
+// degrees converts degrees, minutes and seconds to decimal degrees, NaN
+// when they are missing.
+func degrees(dms []float64) float64 {
+	if len(dms) != 3 {
+		return math.NaN()
+	}
+	return dms[0] + dms[1]/60 + dms[2]/3600
+}
+
+// exposureTime writes exposures shorter than a second as a fraction.
+func exposureTime(seconds float64) string {
+	switch {
+	case seconds <= 0:
+		return ""
+	case seconds < 1:
+		return "1/" + strconv.Itoa(int(math.Round(1/seconds)))
+	default:
+		return strconv.FormatFloat(roundTo(seconds, 1), 'f', -1, 64)
+	}
+}
+
+// captureTime converts the EXIF date format to ISO 8601.
+func captureTime(datetime, offset string) string {
+	t, err := time.Parse("2006:01:02 15:04:05", datetime)
+	if err != nil {
+		return ""
+	}
+	out := t.Format("2006-01-02T15:04:05")
+	if _, err := time.Parse("-07:00", offset); err == nil {
+		out += offset
+	}
+	return out
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+// iccProfile reads the data colour space and description of an ICC
+// profile.
+func iccProfile(icc []byte) *domainImage.ColorProfile {
+	if len(icc) < 132 || string(icc[36:40]) != "acsp" {
+		return nil
+	}
+	profile := &domainImage.ColorProfile{ColorSpace: strings.TrimSpace(string(icc[16:20]))}
+
+	tags := int(binary.BigEndian.Uint32(icc[128:]))
+	for i := range tags {
+		at := 132 + i*12
+		if at+12 > len(icc) {
+			break
+		}
+		if string(icc[at:at+4]) != "desc" {
+			continue
+		}
+		offset, size := int(binary.BigEndian.Uint32(icc[at+4:])), int(binary.BigEndian.Uint32(icc[at+8:]))
+		if offset+size <= len(icc) {
+			profile.Name = iccText(icc[offset : offset+size])
+		}
+	}
+	return profile
+}
+
+// iccText reads a textDescriptionType (ICC v2) or the first record of a
+// multiLocalizedUnicodeType (ICC v4).
+func iccText(tag []byte) string {
+	if len(tag) < 12 {
+		return ""
+	}
+	switch string(tag[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		if 12+n > len(tag) {
+			return ""
+		}
+		return strings.TrimSpace(string(bytes.TrimRight(tag[12:12+n], "\x00")))
+	case "mluc":
+		if len(tag) < 28 || binary.BigEndian.Uint32(tag[8:]) == 0 {
+			return ""
+		}
+		n, offset := int(binary.BigEndian.Uint32(tag[20:])), int(binary.BigEndian.Uint32(tag[24:]))
+		if offset+n > len(tag) {
+			return ""
+		}
+		units := make([]uint16, n/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(tag[offset+2*i:])
+		}
+		return strings.TrimSpace(string(utf16.Decode(units)))
+	}
+	return ""
+}
@@ -0,0 +1,231 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domainImage "image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// byteOrder writes the byte order of a TIFF.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// testEntry is an IFD field for buildTIFF, its value encoded in the byte
+// order of the file.
+type testEntry struct {
+	tag, typ uint16
+	count    uint32
+	value    []byte
+}
+
+// buildTIFF lays out IFD0 and, when gps is not nil, a GPS IFD that IFD0
+// points to. Values longer than four bytes follow the IFDs.
+func buildTIFF(order byteOrder, ifd0, gps []testEntry) []byte {
+	if gps != nil {
+		ifd0 = append(slices.Clone(ifd0), testEntry{tag: tagGPSIFD, typ: 4, count: 1})
+	}
+	gpsAt := 8 + 2 + 12*len(ifd0) + 4
+	end := gpsAt
+	if gps != nil {
+		end += 2 + 12*len(gps) + 4
+	}
+
+	buf := make([]byte, end)
+	copy(buf, "II*\x00")
+	if order == binary.BigEndian {
+		copy(buf, "MM\x00*")
+	}
+	order.PutUint32(buf[4:], 8)
+	writeIFD := func(at int, entries []testEntry) {
+		order.PutUint16(buf[at:], uint16(len(entries)))
+		for i, e := range entries {
+			p := at + 2 + 12*i
+			order.PutUint16(buf[p:], e.tag)
+			order.PutUint16(buf[p+2:], e.typ)
+			order.PutUint32(buf[p+4:], e.count)
+			value := e.value
+			if e.tag == tagGPSIFD {
+				value = order.AppendUint32(nil, uint32(gpsAt))
+			}
+			if len(value) <= 4 {
+				copy(buf[p+8:], value)
+			} else {
+				order.PutUint32(buf[p+8:], uint32(len(buf)))
+				buf = append(buf, value...)
+			}
+		}
+	}
+	writeIFD(8, ifd0)
+	if gps != nil {
+		writeIFD(gpsAt, gps)
+	}
+	return buf
+}
+
+func shortEntry(order byteOrder, tag uint16, v int) testEntry {
+	return testEntry{tag: tag, typ: 3, count: 1, value: order.AppendUint16(nil, uint16(v))}
+}
+
+func asciiEntry(tag uint16, s string) testEntry {
+	return testEntry{tag: tag, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+// rationalEntry holds num/den pairs.
+func rationalEntry(order byteOrder, tag uint16, pairs ...uint32) testEntry {
+	var value []byte
+	for _, v := range pairs {
+		value = order.AppendUint32(value, v)
+	}
+	return testEntry{tag: tag, typ: 5, count: uint32(len(pairs) / 2), value: value}
+}
+
+// photoEXIF is the EXIF of a Canon photo with the given orientation taken
+// at 51°30'N 0°7'30"W, 35 m up.
+func photoEXIF(order byteOrder, orientation int) []byte {
+	return buildTIFF(order,
+		[]testEntry{
+			asciiEntry(tagMake, "Canon"),
+			shortEntry(order, tagOrientation, orientation),
+		},
+		[]testEntry{
+			asciiEntry(tagGPSLatitudeRef, "N"),
+			rationalEntry(order, tagGPSLatitude, 51, 1, 30, 1, 0, 1),
+			asciiEntry(tagGPSLongitudeRef, "W"),
+			rationalEntry(order, tagGPSLongitude, 0, 1, 7, 1, 30, 1),
+			rationalEntry(order, tagGPSAltitude, 35, 1),
+		})
+}
+
+var byteOrders = []byteOrder{binary.LittleEndian, binary.BigEndian}
+
+func TestTIFFOrientation(t *testing.T) {
+	for _, order := range byteOrders {
+		for o := 1; o <= 8; o++ {
+			assert.Equal(t, o, tiffOrientation(photoEXIF(order, o)), "%v %d", order, o)
+		}
+		assert.Equal(t, 1, tiffOrientation(photoEXIF(order, 0)), "out of range")
+		assert.Equal(t, 1, tiffOrientation(photoEXIF(order, 9)), "out of range")
+	}
+	assert.Equal(t, 1, tiffOrientation(nil))
+	assert.Equal(t, 1, tiffOrientation(buildTIFF(binary.LittleEndian, []testEntry{asciiEntry(tagMake, "Canon")}, nil)))
+}
+
+func TestUprightEXIF(t *testing.T) {
+	exif := photoEXIF(binary.BigEndian, 6)
+	upright := uprightEXIF(exif)
+	assert.Equal(t, 1, tiffOrientation(upright))
+	assert.Equal(t, 6, tiffOrientation(exif), "the source is left alone")
+	assert.Len(t, upright, len(exif))
+}
+
+func TestStripGPS(t *testing.T) {
+	for _, order := range byteOrders {
+		exif := photoEXIF(order, 1)
+		photo := photoMetadata(exif, 1)
+		require.NotNil(t, photo.GPS)
+		alt := 35.0
+		assert.Equal(t, &domainImage.GPS{Latitude: 51.5, Longitude: -0.125, Altitude: &alt}, photo.GPS)
+
+		stripped := slices.Clone(exif)
+		stripGPS(stripped)
+		assert.Len(t, stripped, len(exif), "offsets stay valid")
+		photo = photoMetadata(stripped, 1)
+		assert.Nil(t, photo.GPS)
+		assert.Equal(t, "Canon", photo.Camera.Make, "the rest is kept")
+		assert.False(t, bytes.Contains(stripped, rationalEntry(order, 0, 51, 1, 30, 1, 0, 1).value), "the values are zeroed")
+	}
+
+	noGPS := buildTIFF(binary.LittleEndian, []testEntry{asciiEntry(tagMake, "Canon")}, nil)
+	stripped := slices.Clone(noGPS)
+	stripGPS(stripped)
+	assert.Equal(t, noGPS, stripped)
+}
+
+// tiffVariants returns corruptions of a valid EXIF that must not be
+// trusted: offsets and counts pointing outside the data or back into it.
+func tiffVariants() map[string][]byte {
+	le := binary.LittleEndian
+	out := map[string][]byte{}
+	corrupt := func(name string, f func(b []byte)) {
+		b := photoEXIF(le, 6)
+		f(b)
+		out[name] = b
+	}
+	corrupt("ifd0 past the end", func(b []byte) { le.PutUint32(b[4:], 0xffffff00) })
+	corrupt("ifd0 at the last byte", func(b []byte) { le.PutUint32(b[4:], uint32(len(b)-1)) })
+	corrupt("too many entries", func(b []byte) { le.PutUint16(b[8:], 0xffff) })
+	corrupt("huge count", func(b []byte) { le.PutUint32(b[10+4:], 0xffffffff) })
+	corrupt("value past the end", func(b []byte) { le.PutUint32(b[10+8:], 0xfffffff0) })
+	corrupt("unknown type", func(b []byte) { le.PutUint16(b[10+2:], 13) })
+	corrupt("gps ifd past the end", func(b []byte) { le.PutUint32(b[10+2*12+8:], 0xfffffff0) })
+	corrupt("gps ifd is ifd0", func(b []byte) { le.PutUint32(b[10+2*12+8:], 8) })
+	out["truncated header"] = []byte("II*\x00\x08\x00")
+	full := photoEXIF(le, 6)
+	for _, n := range []int{8, 10, 20, 40, len(full) - 1} {
+		out[fmt.Sprintf("truncated to %d bytes", n)] = full[:n]
+	}
+	return out
+}
+
+func TestMalformedEXIF(t *testing.T) {
+	for name, exif := range tiffVariants() {
+		t.Run(name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				o := tiffOrientation(exif)
+				assert.True(t, o >= 1 && o <= 8)
+				_ = uprightEXIF(exif)
+				stripGPS(slices.Clone(exif))
+				_ = photoMetadata(exif, 1)
+			})
+		})
+	}
+}
+
+// corners is a 3×2 image whose corners have distinct colours.
+func corners() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.SetNRGBA(0, 0, red)
+	img.SetNRGBA(2, 0, green)
+	img.SetNRGBA(0, 1, blue)
+	img.SetNRGBA(2, 1, white)
+	return img
+}
+
+func TestRenderOrientation(t *testing.T) {
+	// The stored corner each orientation displays top left.
+	topLeft := map[int]color.NRGBA{1: red, 2: green, 3: white, 4: blue, 5: red, 6: blue, 7: white, 8: green}
+	p := NewStdLibImageProcessor()
+	for o := 1; o <= 8; o++ {
+		var src bytes.Buffer
+		require.NoError(t, png.Encode(&src, corners()))
+		buf, err := withMetadata(src.Bytes(), metadataBlocks{exif: photoEXIF(binary.LittleEndian, o)})
+		require.NoError(t, err)
+
+		meta, err := p.ExtractMetadata(context.Background(), bytes.NewReader(buf))
+		require.NoError(t, err)
+		assert.Equal(t, o, meta.Orientation)
+
+		out, err := p.Transform(context.Background(), bytes.NewReader(buf), &domainImage.TransformationSpec{}, &ports.TransformAssets{})
+		require.NoError(t, err)
+		assert.Equal(t, meta.Width, out.Width, "orientation %d", o)
+		assert.Equal(t, meta.Height, out.Height, "orientation %d", o)
+		rendered, err := png.Decode(bytes.NewReader(out.Data))
+		require.NoError(t, err)
+		assert.Equal(t, topLeft[o], color.NRGBAModel.Convert(rendered.At(0, 0)), "orientation %d", o)
+		assert.Equal(t, 1, exifOrientation(out.Data), "kept EXIF is reset")
+	}
+}
@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+// needsColorFilters reports whether any per-pixel colour filter is set.
+func needsColorFilters(f *domainImage.FilterSpec) bool {
+	if f == nil {
+		return false
+	}
+	return f.Gamma != 0 || f.Brightness != 0 || f.Contrast != 0 || f.Saturation != 0 ||
+		f.Grayscale || f.Sepia || f.Tint != "" || f.Invert
+}
+
+// applyColorFilters runs the colour filters of f on src in the order
+// documented on FilterSpec: gamma, brightness, contrast, saturation,
+// grayscale, sepia, tint, invert. Alpha is left untouched.
+func applyColorFilters(src image.Image, f *domainImage.FilterSpec) (*image.NRGBA, error) {
+	var tint color.NRGBA
+	if f.Tint != "" {
+		var err error
+		if tint, err = parseHexColor(f.Tint); err != nil {
+			return nil, err
+		}
+	}
+
+	dst := toNRGBA(src)
+	lut := toneCurve(f)
+
+	for i := 0; i < len(dst.Pix); i += 4 {
+		px := dst.Pix[i : i+3 : i+3]
+		r, g, b := float64(lut[px[0]]), float64(lut[px[1]]), float64(lut[px[2]])
+
+		if f.Saturation != 0 {
+			l := luma(r, g, b)
+			k := 1 + f.Saturation
+			r, g, b = l+(r-l)*k, l+(g-l)*k, l+(b-l)*k
+		}
+		if f.Grayscale {
+			l := luma(r, g, b)
+			r, g, b = l, l, l
+		}
+		if f.Sepia {
+			r, g, b = 0.393*r+0.769*g+0.189*b,
+				0.349*r+0.686*g+0.168*b,
+				0.272*r+0.534*g+0.131*b
+		}
+		if f.Tint != "" {
+			// Keep the luminance and take the hue from the tint colour.
+			l := luma(r, g, b) / 255
+			r, g, b = l*float64(tint.R), l*float64(tint.G), l*float64(tint.B)
+		}
+		if f.Invert {
+			r, g, b = 255-clamp8(r), 255-clamp8(g), 255-clamp8(b)
+		}
+
+		px[0], px[1], px[2] = uint8(clamp8(r)), uint8(clamp8(g)), uint8(clamp8(b))
+	}
+
+	return dst, nil
+}
+
+// toneCurve folds gamma, brightness and contrast into a lookup table.
+func toneCurve(f *domainImage.FilterSpec) [256]uint8 {
+	var lut [256]uint8
+	for i := range lut {
+		v := float64(i) / 255
+		if f.Gamma != 0 {
+			v = math.Pow(v, 1/f.Gamma)
+		}
+		v += f.Brightness
+		v = (v-0.5)*(1+f.Contrast) + 0.5
+		lut[i] = uint8(clamp8(v * 255))
+	}
+	return lut
+}
+
+// luma returns the Rec. 601 luminance of an RGB triple.
+func luma(r, g, b float64) float64 {
+	return 0.299*r + 0.587*g + 0.114*b
+}
+
+func clamp8(v float64) float64 {
+	return math.Round(math.Max(0, math.Min(255, v)))
+}
+
+// toNRGBA returns a copy of src as non-premultiplied RGBA anchored at 0,0.
+func toNRGBA(src image.Image) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// parseHexColor parses #rgb or #rrggbb, the colours specs validate as.
+// Colours are always opaque.
+func parseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
@@ -0,0 +1,35 @@
+package processor
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{in: "#fff", want: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{in: "#3366ff", want: color.NRGBA{R: 0x33, G: 0x66, B: 0xff, A: 0xff}},
+		{in: "3366FF", want: color.NRGBA{R: 0x33, G: 0x66, B: 0xff, A: 0xff}},
+		{in: "#ffff", wantErr: true},
+		{in: "#3366ff80", wantErr: true},
+		{in: "#zzz", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseHexColor(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package processor
+
+import "image"
+
+// rotate turns src clockwise by 90, 180 or 270 degrees.
+func rotate(src *image.NRGBA, angle int) *image.NRGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	var dst *image.NRGBA
+	var at func(x, y int) (int, int)
+	switch angle {
+	case 90:
+		dst = image.NewNRGBA(image.Rect(0, 0, h, w))
+		at = func(x, y int) (int, int) { return h - 1 - y, x }
+	case 180:
+		dst = image.NewNRGBA(image.Rect(0, 0, w, h))
+		at = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 270:
+		dst = image.NewNRGBA(image.Rect(0, 0, h, w))
+		at = func(x, y int) (int, int) { return y, w - 1 - x }
+	default:
+		return src
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := at(x, y)
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
+
+// flipHorizontal mirrors src left to right.
+func flipHorizontal(src *image.NRGBA) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(b)
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			copy(dst.Pix[dst.PixOffset(b.Dx()-1-x, y):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
+
+// flipVertical mirrors src top to bottom.
+func flipVertical(src *image.NRGBA) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(b)
+	for y := 0; y < b.Dy(); y++ {
+		copy(dst.Pix[dst.PixOffset(0, b.Dy()-1-y):][:b.Dx()*4], src.Pix[src.PixOffset(0, y):][:b.Dx()*4])
+	}
+	return dst
+}
+
+// orientationTransform maps an EXIF orientation to the clockwise rotation
+// and left-right flip libvips applies to display the image upright.
+func orientationTransform(orientation int) (angle int, flip bool) {
+	switch orientation {
+	case 2:
+		return 0, true
+	case 3:
+		return 180, false
+	case 4:
+		return 180, true
+	case 5:
+		return 90, true
+	case 6:
+		return 90, false
+	case 7:
+		return 270, true
+	case 8:
+		return 270, false
+	default:
+		return 0, false
+	}
+}
+
+// displaySize returns the dimensions of a w×h image displayed with the
+// EXIF orientation: orientations 5 to 8 turn it by 90 degrees.
+func displaySize(w, h, orientation int) (int, int) {
+	if orientation >= 5 && orientation <= 8 {
+		return h, w
+	}
+	return w, h
+}
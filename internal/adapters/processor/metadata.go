@@ -0,0 +1,547 @@
+package processor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"slices"
+	"strings"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+// Variants are encoded without metadata and get the metadata their spec
+// keeps copied from the original, so both processors write the same and
+// libvips' all-or-nothing strip is not needed. JPEG, PNG and WebP carry
+// it; see domainImage.CarriesMetadata.
+
+var errMalformedImage = errors.New("malformed image")
+
+// metadataBlocks holds the metadata of an image: exif is a TIFF structure,
+// icc an ICC profile and xmp an XMP packet. Each is nil when absent.
+type metadataBlocks struct {
+	exif, icc, xmp []byte
+}
+
+func (m metadataBlocks) empty() bool {
+	return m.exif == nil && m.icc == nil && m.xmp == nil
+}
+
+// keep returns the blocks spec keeps. Kept EXIF is copied with its
+// orientation reset, as variants are rendered upright. XMP is dropped when
+// it records a GPS position that spec does not keep, as its properties can
+// be written in too many ways to strip reliably.
+func (m metadataBlocks) keep(spec *domainImage.MetadataSpec) metadataBlocks {
+	var kept metadataBlocks
+	if spec.KeepsEXIF() && m.exif != nil {
+		kept.exif = uprightEXIF(m.exif)
+		if !spec.KeepsGPS() {
+			stripGPS(kept.exif)
+		}
+	}
+	if spec.KeepsICC() {
+		kept.icc = m.icc
+	}
+	if spec.KeepsXMP() && (spec.KeepsGPS() || !xmpHasGPS(m.xmp)) {
+		kept.xmp = m.xmp
+	}
+	return kept
+}
+
+// xmpHasGPS reports whether an XMP packet has a GPS property, such as
+// exif:GPSLatitude, under any namespace prefix.
+func xmpHasGPS(xmp []byte) bool {
+	return bytes.Contains(xmp, []byte(":GPS"))
+}
+
+// readMetadata returns the metadata of a JPEG, PNG or WebP; other formats
+// have none.
+func readMetadata(buf []byte) metadataBlocks {
+	switch {
+	case isJPEG(buf):
+		return readJPEGMetadata(buf)
+	case isPNG(buf):
+		return readPNGMetadata(buf)
+	case isWebP(buf):
+		return readWebPMetadata(buf)
+	}
+	return metadataBlocks{}
+}
+
+// withMetadata replaces the metadata of an encoded JPEG, PNG or WebP with
+// m. Other formats are returned unchanged. An ICC profile is only written
+// when its colour space is that of buf, as libvips converts CMYK and
+// grayscale sources to RGB.
+func withMetadata(buf []byte, m metadataBlocks) ([]byte, error) {
+	if m.icc != nil && iccColorSpace(m.icc) != encodedColorSpace(buf) {
+		m.icc = nil
+	}
+	switch {
+	case isJPEG(buf):
+		return jpegWithMetadata(buf, m)
+	case isPNG(buf):
+		return pngWithMetadata(buf, m)
+	case isWebP(buf):
+		return webpWithMetadata(buf, m)
+	}
+	return buf, nil
+}
+
+// iccColorSpace returns the data colour space of an ICC profile, such as
+// "RGB", "GRAY" or "CMYK".
+func iccColorSpace(icc []byte) string {
+	if len(icc) < 20 {
+		return ""
+	}
+	return strings.TrimSpace(string(icc[16:20]))
+}
+
+// encodedColorSpace returns the colour space of an encoded JPEG, PNG or
+// WebP in the terms of iccColorSpace, or "" when it cannot tell.
+func encodedColorSpace(buf []byte) string {
+	switch {
+	case isJPEG(buf):
+		segments, _, err := jpegSegments(buf)
+		if err != nil {
+			return ""
+		}
+		for _, s := range segments {
+			// Start of frame, less DHT, JPG and DAC: precision, height,
+			// width, components.
+			if s.marker < 0xc0 || s.marker > 0xcf || s.marker == 0xc4 || s.marker == 0xc8 || s.marker == 0xcc || len(s.data) < 6 {
+				continue
+			}
+			switch s.data[5] {
+			case 1:
+				return "GRAY"
+			case 3:
+				return "RGB"
+			case 4:
+				return "CMYK"
+			}
+			return ""
+		}
+	case isPNG(buf):
+		chunks, err := pngChunks(buf)
+		if err != nil || len(chunks[0].data) < 10 {
+			return ""
+		}
+		switch chunks[0].data[9] {
+		case 0, 4:
+			return "GRAY"
+		case 2, 3, 6:
+			return "RGB"
+		}
+	case isWebP(buf):
+		return "RGB"
+	}
+	return ""
+}
+
+func isJPEG(buf []byte) bool {
+	return len(buf) >= 3 && buf[0] == 0xff && buf[1] == 0xd8 && buf[2] == 0xff
+}
+
+func isPNG(buf []byte) bool {
+	return bytes.HasPrefix(buf, []byte(pngSignature))
+}
+
+func isWebP(buf []byte) bool {
+	return len(buf) >= 12 && string(buf[0:4]) == "RIFF" && string(buf[8:12]) == "WEBP"
+}
+
+// JPEG keeps EXIF and XMP in APP1 segments and the ICC profile in APP2
+// segments of up to jpegICCChunk bytes each.
+const (
+	jpegEXIFPrefix = "Exif\x00\x00"
+	jpegXMPPrefix  = "http://ns.adobe.com/xap/1.0/\x00"
+	jpegICCPrefix  = "ICC_PROFILE\x00"
+	jpegMaxSegment = 0xffff - 2
+	jpegICCChunk   = jpegMaxSegment - len(jpegICCPrefix) - 2
+)
+
+type jpegSegment struct {
+	marker byte
+	data   []byte
+}
+
+// jpegSegments splits a JPEG into the segments before the scan and the rest
+// of the file, from the start of scan on.
+func jpegSegments(buf []byte) ([]jpegSegment, []byte, error) {
+	var segments []jpegSegment
+	for i := 2; i+4 <= len(buf); {
+		if buf[i] != 0xff {
+			return nil, nil, errMalformedImage
+		}
+		marker := buf[i+1]
+		if marker == 0xff {
+			i++ // fill byte
+			continue
+		}
+		if marker == 0xda {
+			return segments, buf[i:], nil
+		}
+		length := int(binary.BigEndian.Uint16(buf[i+2:]))
+		if length < 2 || i+2+length > len(buf) {
+			return nil, nil, errMalformedImage
+		}
+		segments = append(segments, jpegSegment{marker: marker, data: buf[i+4 : i+2+length]})
+		i += 2 + length
+	}
+	return nil, nil, errMalformedImage
+}
+
+// jpegMetadataKind reports which metadata a segment holds, if any.
+func jpegMetadataKind(s jpegSegment) string {
+	switch {
+	case s.marker == 0xe1 && bytes.HasPrefix(s.data, []byte(jpegEXIFPrefix)):
+		return "exif"
+	case s.marker == 0xe1 && bytes.HasPrefix(s.data, []byte(jpegXMPPrefix)):
+		return "xmp"
+	case s.marker == 0xe2 && bytes.HasPrefix(s.data, []byte(jpegICCPrefix)) && len(s.data) > len(jpegICCPrefix)+2:
+		return "icc"
+	}
+	return ""
+}
+
+func readJPEGMetadata(buf []byte) metadataBlocks {
+	segments, _, err := jpegSegments(buf)
+	if err != nil {
+		return metadataBlocks{}
+	}
+
+	var m metadataBlocks
+	icc := map[byte][]byte{}
+	for _, s := range segments {
+		switch jpegMetadataKind(s) {
+		case "exif":
+			if m.exif == nil {
+				m.exif = s.data[len(jpegEXIFPrefix):]
+			}
+		case "xmp":
+			if m.xmp == nil {
+				m.xmp = s.data[len(jpegXMPPrefix):]
+			}
+		case "icc":
+			icc[s.data[len(jpegICCPrefix)]] = s.data[len(jpegICCPrefix)+2:]
+		}
+	}
+	// ICC chunks are numbered from 1 and must all be present.
+	for n := byte(1); n <= byte(len(icc)); n++ {
+		chunk, ok := icc[n]
+		if !ok {
+			m.icc = nil
+			break
+		}
+		m.icc = append(m.icc, chunk...)
+	}
+	return m
+}
+
+// jpegWithMetadata writes m after the JFIF header. Blocks too large for
+// JPEG segments are left out.
+func jpegWithMetadata(buf []byte, m metadataBlocks) ([]byte, error) {
+	segments, scan, err := jpegSegments(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var inserted []jpegSegment
+	if m.exif != nil && len(jpegEXIFPrefix)+len(m.exif) <= jpegMaxSegment {
+		inserted = append(inserted, jpegSegment{0xe1, append([]byte(jpegEXIFPrefix), m.exif...)})
+	}
+	if m.xmp != nil && len(jpegXMPPrefix)+len(m.xmp) <= jpegMaxSegment {
+		inserted = append(inserted, jpegSegment{0xe1, append([]byte(jpegXMPPrefix), m.xmp...)})
+	}
+	if chunks := (len(m.icc) + jpegICCChunk - 1) / jpegICCChunk; chunks > 0 && chunks <= 0xff {
+		for n := 0; n < chunks; n++ {
+			data := append([]byte(jpegICCPrefix), byte(n+1), byte(chunks))
+			data = append(data, m.icc[n*jpegICCChunk:min((n+1)*jpegICCChunk, len(m.icc))]...)
+			inserted = append(inserted, jpegSegment{0xe2, data})
+		}
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(buf)+len(m.exif)+len(m.xmp)+len(m.icc)))
+	out.Write([]byte{0xff, 0xd8})
+	write := func(s jpegSegment) {
+		out.Write([]byte{0xff, s.marker})
+		_ = binary.Write(out, binary.BigEndian, uint16(len(s.data)+2))
+		out.Write(s.data)
+	}
+	rest := segments
+	for len(rest) > 0 && rest[0].marker == 0xe0 {
+		write(rest[0])
+		rest = rest[1:]
+	}
+	for _, s := range inserted {
+		write(s)
+	}
+	for _, s := range rest {
+		if jpegMetadataKind(s) == "" {
+			write(s)
+		}
+	}
+	out.Write(scan)
+	return out.Bytes(), nil
+}
+
+// PNG keeps EXIF in eXIf, the zlib-compressed ICC profile in iCCP and XMP
+// in an iTXt chunk with the pngXMPKeyword.
+const (
+	pngSignature  = "\x89PNG\r\n\x1a\n"
+	pngXMPKeyword = "XML:com.adobe.xmp"
+)
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+func pngChunks(buf []byte) ([]pngChunk, error) {
+	var chunks []pngChunk
+	for b := buf[len(pngSignature):]; len(b) >= 12; {
+		length := int(binary.BigEndian.Uint32(b))
+		if length > len(b)-12 {
+			return nil, errMalformedImage
+		}
+		chunks = append(chunks, pngChunk{typ: string(b[4:8]), data: b[8 : 8+length]})
+		b = b[12+length:]
+	}
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" {
+		return nil, errMalformedImage
+	}
+	return chunks, nil
+}
+
+func isPNGXMP(c pngChunk) bool {
+	return c.typ == "iTXt" && bytes.HasPrefix(c.data, []byte(pngXMPKeyword+"\x00"))
+}
+
+func readPNGMetadata(buf []byte) metadataBlocks {
+	chunks, err := pngChunks(buf)
+	if err != nil {
+		return metadataBlocks{}
+	}
+
+	var m metadataBlocks
+	for _, c := range chunks {
+		switch {
+		case c.typ == "eXIf":
+			m.exif = c.data
+		case c.typ == "iCCP":
+			// profile name, NUL, compression method, zlib stream
+			if i := bytes.IndexByte(c.data, 0); i >= 0 && i+2 <= len(c.data) {
+				m.icc = inflate(c.data[i+2:])
+			}
+		case isPNGXMP(c):
+			m.xmp = pngXMPText(c.data[len(pngXMPKeyword)+1:])
+		}
+	}
+	return m
+}
+
+// pngXMPText reads the text of an iTXt chunk after its keyword:
+// compression flag and method, language tag, translated keyword, text.
+func pngXMPText(b []byte) []byte {
+	if len(b) < 2 {
+		return nil
+	}
+	compressed := b[0] == 1
+	rest := b[2:]
+	for range 2 {
+		i := bytes.IndexByte(rest, 0)
+		if i < 0 {
+			return nil
+		}
+		rest = rest[i+1:]
+	}
+	if compressed {
+		return inflate(rest)
+	}
+	return rest
+}
+
+// maxInflated bounds the size of a compressed metadata block once inflated,
+// so a small chunk cannot expand without end. JPEG cannot hold larger ICC
+// profiles either.
+const maxInflated = 16 << 20
+
+func inflate(b []byte) []byte {
+	r, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxInflated+1))
+	if err != nil || len(out) > maxInflated {
+		return nil
+	}
+	return out
+}
+
+// pngWithMetadata writes m right after the IHDR chunk. An sRGB chunk is
+// dropped along with a written ICC profile, as PNG allows only one.
+func pngWithMetadata(buf []byte, m metadataBlocks) ([]byte, error) {
+	chunks, err := pngChunks(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var inserted []pngChunk
+	if m.icc != nil {
+		var profile bytes.Buffer
+		profile.WriteString("ICC Profile\x00\x00")
+		w := zlib.NewWriter(&profile)
+		_, _ = w.Write(m.icc)
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		inserted = append(inserted, pngChunk{"iCCP", profile.Bytes()})
+	}
+	if m.exif != nil {
+		inserted = append(inserted, pngChunk{"eXIf", m.exif})
+	}
+	if m.xmp != nil {
+		text := append([]byte(pngXMPKeyword+"\x00\x00\x00\x00\x00"), m.xmp...)
+		inserted = append(inserted, pngChunk{"iTXt", text})
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(buf)+len(m.exif)+len(m.xmp)+len(m.icc)))
+	out.WriteString(pngSignature)
+	write := func(c pngChunk) {
+		_ = binary.Write(out, binary.BigEndian, uint32(len(c.data)))
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(c.typ))
+		crc.Write(c.data)
+		out.WriteString(c.typ)
+		out.Write(c.data)
+		_ = binary.Write(out, binary.BigEndian, crc.Sum32())
+	}
+	write(chunks[0])
+	for _, c := range inserted {
+		write(c)
+	}
+	for _, c := range chunks[1:] {
+		switch {
+		case c.typ == "eXIf", c.typ == "iCCP", isPNGXMP(c):
+		case c.typ == "sRGB" && m.icc != nil:
+		default:
+			write(c)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// WebP keeps the metadata in ICCP, EXIF and "XMP " chunks, announced by
+// flags of the VP8X header.
+const (
+	webpFlagICC  = 0x20
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+func readWebPMetadata(buf []byte) metadataBlocks {
+	chunks, err := readWebPChunks(buf[12:])
+	if err != nil {
+		return metadataBlocks{}
+	}
+
+	var m metadataBlocks
+	for _, c := range chunks {
+		switch c.id {
+		case "ICCP":
+			m.icc = c.data
+		case "EXIF":
+			// Some writers keep the JPEG prefix.
+			m.exif = bytes.TrimPrefix(c.data, []byte(jpegEXIFPrefix))
+		case "XMP ":
+			m.xmp = c.data
+		}
+	}
+	return m
+}
+
+// webpWithMetadata writes m into the extended format, adding a VP8X header
+// to simple WebPs that get metadata.
+func webpWithMetadata(buf []byte, m metadataBlocks) ([]byte, error) {
+	chunks, err := readWebPChunks(buf[12:])
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, errMalformedWebP
+	}
+	chunks = slices.DeleteFunc(chunks, func(c webpChunk) bool {
+		return c.id == "ICCP" || c.id == "EXIF" || c.id == "XMP "
+	})
+
+	var vp8x []byte
+	if chunks[0].id == "VP8X" {
+		vp8x = slices.Clone(chunks[0].data)
+		chunks = chunks[1:]
+	} else if !m.empty() {
+		if vp8x, err = simpleWebPHeader(chunks[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	var body bytes.Buffer
+	if vp8x != nil {
+		if len(vp8x) < 10 {
+			return nil, errMalformedWebP
+		}
+		vp8x[0] &^= webpFlagICC | webpFlagEXIF | webpFlagXMP
+		if m.icc != nil {
+			vp8x[0] |= webpFlagICC
+		}
+		if m.exif != nil {
+			vp8x[0] |= webpFlagEXIF
+		}
+		if m.xmp != nil {
+			vp8x[0] |= webpFlagXMP
+		}
+		writeWebPChunk(&body, "VP8X", vp8x)
+	}
+	if m.icc != nil {
+		writeWebPChunk(&body, "ICCP", m.icc)
+	}
+	for _, c := range chunks {
+		writeWebPChunk(&body, c.id, c.data)
+	}
+	if m.exif != nil {
+		writeWebPChunk(&body, "EXIF", m.exif)
+	}
+	if m.xmp != nil {
+		writeWebPChunk(&body, "XMP ", m.xmp)
+	}
+	return riffWebP(body.Bytes()), nil
+}
+
+// simpleWebPHeader builds the VP8X header of a simple WebP from the size
+// in its bitstream.
+func simpleWebPHeader(c webpChunk) ([]byte, error) {
+	var w, h int
+	alpha := false
+	switch {
+	case c.id == "VP8 " && len(c.data) >= 10 && bytes.Equal(c.data[3:6], []byte{0x9d, 0x01, 0x2a}):
+		w = int(binary.LittleEndian.Uint16(c.data[6:]) & 0x3fff)
+		h = int(binary.LittleEndian.Uint16(c.data[8:]) & 0x3fff)
+	case c.id == "VP8L" && len(c.data) >= 5 && c.data[0] == 0x2f:
+		bits := binary.LittleEndian.Uint32(c.data[1:])
+		w, h = int(bits&0x3fff)+1, int(bits>>14&0x3fff)+1
+		alpha = bits>>28&1 == 1
+	default:
+		return nil, errMalformedWebP
+	}
+
+	vp8x := make([]byte, 10)
+	if alpha {
+		vp8x[0] = webpFlagAlpha
+	}
+	putUint24(vp8x[4:], w-1)
+	putUint24(vp8x[7:], h-1)
+	return vp8x, nil
+}
@@ -0,0 +1,222 @@
+package processor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domainImage "image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// fakeICC is an n-byte ICC profile header in the given colour space.
+func fakeICC(space string, n int) []byte {
+	icc := make([]byte, n)
+	copy(icc[16:20], space+"    ")
+	copy(icc[36:40], "acsp")
+	for i := 132; i < n; i++ {
+		icc[i] = byte(i)
+	}
+	return icc
+}
+
+const gpsXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+	`<rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/" exif:GPSLatitude="51,30.0N" exif:GPSLongitude="0,7.5W"/>` +
+	`</rdf:RDF></x:xmpmeta>`
+
+const plainXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+	`<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/" dc:format="image/jpeg"/>` +
+	`</rdf:RDF></x:xmpmeta>`
+
+func testJPEG(t testing.TB, img image.Image) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, jpeg.Encode(&out, img, nil))
+	return out.Bytes()
+}
+
+func testPNG(t testing.TB, img image.Image) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, png.Encode(&out, img))
+	return out.Bytes()
+}
+
+// testSimpleWebP is a simple 3×2 lossless WebP; only its header is valid.
+func testSimpleWebP() []byte {
+	bits := make([]byte, 5)
+	bits[0] = 0x2f
+	binary.LittleEndian.PutUint32(bits[1:], 2|1<<14)
+	var body bytes.Buffer
+	writeWebPChunk(&body, "VP8L", append(bits, 0, 0, 0))
+	return riffWebP(body.Bytes())
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	m := metadataBlocks{
+		exif: photoEXIF(binary.LittleEndian, 1),
+		// Larger than one JPEG segment.
+		icc: fakeICC("RGB", 3*jpegICCChunk/2),
+		xmp: []byte(plainXMP),
+	}
+	for name, buf := range map[string][]byte{
+		"jpeg": testJPEG(t, corners()),
+		"png":  testPNG(t, corners()),
+		"webp": testSimpleWebP(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.True(t, readMetadata(buf).empty())
+			out, err := withMetadata(buf, m)
+			require.NoError(t, err)
+			assert.Equal(t, m, readMetadata(out))
+
+			out, err = withMetadata(out, metadataBlocks{})
+			require.NoError(t, err)
+			assert.True(t, readMetadata(out).empty(), "replaced, not added to")
+		})
+	}
+}
+
+func TestKeep(t *testing.T) {
+	yes := true
+	m := metadataBlocks{exif: photoEXIF(binary.LittleEndian, 6), icc: fakeICC("RGB", 200), xmp: []byte(gpsXMP)}
+
+	kept := m.keep(nil)
+	assert.Equal(t, 1, tiffOrientation(kept.exif))
+	assert.Nil(t, photoMetadata(kept.exif, 1).GPS)
+	assert.NotNil(t, photoMetadata(m.exif, 1).GPS, "the original is left alone")
+	assert.Equal(t, m.icc, kept.icc)
+	assert.Nil(t, kept.xmp, "XMP with a GPS position is dropped")
+
+	kept = m.keep(&domainImage.MetadataSpec{GPS: &yes})
+	assert.NotNil(t, photoMetadata(kept.exif, 1).GPS)
+	assert.Equal(t, m.xmp, kept.xmp)
+
+	m.xmp = []byte(plainXMP)
+	assert.Equal(t, m.xmp, m.keep(nil).xmp)
+
+	no := false
+	assert.True(t, m.keep(&domainImage.MetadataSpec{EXIF: &no, ICC: &no, XMP: &no}).empty())
+}
+
+func TestTransformStripsXMPGPS(t *testing.T) {
+	src, err := withMetadata(testJPEG(t, corners()), metadataBlocks{
+		exif: photoEXIF(binary.BigEndian, 1),
+		xmp:  []byte(gpsXMP),
+	})
+	require.NoError(t, err)
+	p := NewStdLibImageProcessor()
+
+	out, err := p.Transform(context.Background(), bytes.NewReader(src), &domainImage.TransformationSpec{}, &ports.TransformAssets{})
+	require.NoError(t, err)
+	m := readMetadata(out.Data)
+	assert.Nil(t, m.xmp)
+	require.NotNil(t, m.exif)
+	assert.Nil(t, photoMetadata(m.exif, 1).GPS)
+	assert.NotContains(t, string(out.Data), "GPSLatitude")
+
+	yes := true
+	out, err = p.Transform(context.Background(), bytes.NewReader(src), &domainImage.TransformationSpec{Metadata: &domainImage.MetadataSpec{GPS: &yes}}, &ports.TransformAssets{})
+	require.NoError(t, err)
+	m = readMetadata(out.Data)
+	assert.Equal(t, []byte(gpsXMP), m.xmp)
+	assert.NotNil(t, photoMetadata(m.exif, 1).GPS)
+}
+
+func TestWithMetadataMatchesICCColorSpace(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	tests := []struct {
+		name  string
+		buf   []byte
+		space string
+		kept  bool
+	}{
+		{"rgb jpeg, rgb profile", testJPEG(t, corners()), "RGB", true},
+		{"rgb jpeg, cmyk profile", testJPEG(t, corners()), "CMYK", false},
+		{"rgb jpeg, gray profile", testJPEG(t, corners()), "GRAY", false},
+		{"gray jpeg, gray profile", testJPEG(t, gray), "GRAY", true},
+		{"rgb png, rgb profile", testPNG(t, corners()), "RGB", true},
+		{"gray png, gray profile", testPNG(t, gray), "GRAY", true},
+		{"gray png, rgb profile", testPNG(t, gray), "RGB", false},
+		{"webp, cmyk profile", testSimpleWebP(), "CMYK", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icc := fakeICC(tt.space, 200)
+			out, err := withMetadata(tt.buf, metadataBlocks{icc: icc})
+			require.NoError(t, err)
+			if tt.kept {
+				assert.Equal(t, icc, readMetadata(out).icc)
+			} else {
+				assert.Nil(t, readMetadata(out).icc)
+			}
+		})
+	}
+}
+
+func TestInflateIsBounded(t *testing.T) {
+	deflate := func(n int) []byte {
+		var out bytes.Buffer
+		w := zlib.NewWriter(&out)
+		_, _ = w.Write(make([]byte, n))
+		require.NoError(t, w.Close())
+		return out.Bytes()
+	}
+	assert.Len(t, inflate(deflate(1000)), 1000)
+	assert.Len(t, inflate(deflate(maxInflated)), maxInflated)
+	assert.Nil(t, inflate(deflate(maxInflated+1)))
+	assert.Nil(t, inflate([]byte("not zlib")))
+}
+
+func TestReadMetadataMalformed(t *testing.T) {
+	full, err := withMetadata(testJPEG(t, corners()), metadataBlocks{exif: photoEXIF(binary.LittleEndian, 6), xmp: []byte(plainXMP)})
+	require.NoError(t, err)
+	for n := range 64 {
+		assert.NotPanics(t, func() { readMetadata(full[:n]) })
+	}
+
+	// An APP1 segment claiming more bytes than the file has.
+	bad := bytes.Clone(full)
+	binary.BigEndian.PutUint16(bad[4:], 0xfff0)
+	assert.True(t, readMetadata(bad).empty())
+	_, err = withMetadata(bad, metadataBlocks{})
+	assert.ErrorIs(t, err, errMalformedImage)
+
+	// A PNG chunk longer than the file.
+	buf := testPNG(t, corners())
+	binary.BigEndian.PutUint32(buf[8:], 0xfffffff0)
+	assert.True(t, readMetadata(buf).empty())
+}
+
+func FuzzReadMetadata(f *testing.F) {
+	m := metadataBlocks{exif: photoEXIF(binary.LittleEndian, 6), icc: fakeICC("RGB", 200), xmp: []byte(gpsXMP)}
+	for _, buf := range [][]byte{testJPEG(f, corners()), testPNG(f, corners()), testSimpleWebP()} {
+		withM, err := withMetadata(buf, m)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(withM)
+	}
+	f.Add(photoEXIF(binary.BigEndian, 8))
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		m := readMetadata(buf)
+		_ = tiffOrientation(m.exif)
+		kept := m.keep(nil)
+		_ = photoMetadata(buf, exifOrientation(buf))
+		_ = encodedColorSpace(buf)
+		if out, err := withMetadata(buf, kept); err == nil {
+			again := readMetadata(out)
+			if again.exif != nil && photoMetadata(again.exif, 1) != nil && photoMetadata(again.exif, 1).GPS != nil {
+				t.Fatal("kept EXIF has a GPS position")
+			}
+		}
+	})
+}
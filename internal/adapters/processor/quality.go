@@ -0,0 +1,201 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+const (
+	// autoQualityTarget is the SSIM a quality auto variant keeps against the
+	// lossless render, about where compression artefacts become visible.
+	autoQualityTarget = 0.985
+	// autoQualityMin and autoQualityMax bound the quality auto search.
+	autoQualityMin = 30
+	autoQualityMax = 95
+	// ssimSize bounds the longest side images are compared at.
+	ssimSize = 1024
+)
+
+// searchesQuality reports whether the encoder quality of spec is found by
+// encoding repeatedly rather than given.
+func searchesQuality(spec *domainImage.TransformationSpec) bool {
+	return (spec.Quality != nil && spec.Quality.IsAuto()) || spec.MaxBytes > 0
+}
+
+// qualitySearch encodes the rendered image at candidate qualities. Encodes
+// are kept, so each quality is encoded once.
+type qualitySearch struct {
+	encode func(quality int) ([]byte, error)
+	// similarity scores an encode against the lossless render, 1 being
+	// identical.
+	similarity func(buf []byte) (float64, error)
+	encoded    map[int][]byte
+}
+
+// run returns the encode the spec asks for and its quality. Quality auto
+// picks the lowest quality scoring autoQualityTarget; MaxBytes then lowers
+// the quality until the output fits, failing with ErrMaxBytesUnreachable
+// when not even quality 1 does.
+func (s *qualitySearch) run(spec *domainImage.TransformationSpec) ([]byte, int, error) {
+	s.encoded = make(map[int][]byte)
+
+	quality := domainImage.DefaultQuality
+	switch {
+	case spec.Quality != nil && spec.Quality.IsAuto():
+		q, err := lowest(autoQualityMin, autoQualityMax, func(q int) (bool, error) {
+			buf, err := s.at(q)
+			if err != nil {
+				return false, err
+			}
+			score, err := s.similarity(buf)
+			return score >= autoQualityTarget, err
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		quality = q
+	case spec.Quality != nil:
+		quality = int(*spec.Quality)
+	case spec.MaxBytes > 0:
+		quality = 100
+	}
+
+	if spec.MaxBytes > 0 {
+		q, err := highest(1, quality, func(q int) (bool, error) {
+			buf, err := s.at(q)
+			return len(buf) <= spec.MaxBytes, err
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		quality = q
+	}
+
+	buf, err := s.at(quality)
+	if err != nil {
+		return nil, 0, err
+	}
+	if spec.MaxBytes > 0 && len(buf) > spec.MaxBytes {
+		return nil, 0, fmt.Errorf("%w: %d bytes at quality %d, %d allowed", domainImage.ErrMaxBytesUnreachable, len(buf), quality, spec.MaxBytes)
+	}
+	return buf, quality, nil
+}
+
+func (s *qualitySearch) at(quality int) ([]byte, error) {
+	if buf, ok := s.encoded[quality]; ok {
+		return buf, nil
+	}
+	buf, err := s.encode(quality)
+	if err != nil {
+		return nil, err
+	}
+	s.encoded[quality] = buf
+	return buf, nil
+}
+
+// lowest binary searches [lo, hi] for the lowest value ok accepts, assuming
+// ok accepts every value above one it accepts. It returns hi when ok accepts
+// none.
+func lowest(lo, hi int, ok func(int) (bool, error)) (int, error) {
+	for lo < hi {
+		mid := (lo + hi) / 2
+		accepted, err := ok(mid)
+		if err != nil {
+			return 0, err
+		}
+		if accepted {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return hi, nil
+}
+
+// highest binary searches [lo, hi] for the highest value ok accepts,
+// assuming ok accepts every value below one it accepts. It returns lo when
+// ok accepts none.
+func highest(lo, hi int, ok func(int) (bool, error)) (int, error) {
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		accepted, err := ok(mid)
+		if err != nil {
+			return 0, err
+		}
+		if accepted {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo, nil
+}
+
+// ssimDims returns the size a w×h image is compared at.
+func ssimDims(w, h int) (int, int) {
+	scale := math.Min(1, float64(ssimSize)/float64(max(w, h)))
+	return max(1, int(math.Round(float64(w)*scale))), max(1, int(math.Round(float64(h)*scale)))
+}
+
+// ssim is the mean structural similarity of the luma of two images of the
+// same size, over 8×8 windows placed every 4 pixels.
+func ssim(a, b *image.NRGBA) float64 {
+	const (
+		window = 8
+		step   = 4
+		c1     = (0.01 * 255) * (0.01 * 255)
+		c2     = (0.03 * 255) * (0.03 * 255)
+	)
+	la, lb := lumaPlane(a), lumaPlane(b)
+	w, h := a.Bounds().Dx(), a.Bounds().Dy()
+	if w < window || h < window {
+		return ssimWindow(la, lb, w, 0, 0, w, h, c1, c2)
+	}
+
+	var sum float64
+	var n int
+	for y := 0; y+window <= h; y += step {
+		for x := 0; x+window <= w; x += step {
+			sum += ssimWindow(la, lb, w, x, y, window, window, c1, c2)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+func ssimWindow(a, b []float64, stride, x0, y0, w, h int, c1, c2 float64) float64 {
+	var sa, sb, saa, sbb, sab float64
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			va, vb := a[y*stride+x], b[y*stride+x]
+			sa += va
+			sb += vb
+			saa += va * va
+			sbb += vb * vb
+			sab += va * vb
+		}
+	}
+	n := float64(w * h)
+	ma, mb := sa/n, sb/n
+	va, vb := saa/n-ma*ma, sbb/n-mb*mb
+	cov := sab/n - ma*mb
+	return ((2*ma*mb + c1) * (2*cov + c2)) / ((ma*ma + mb*mb + c1) * (va + vb + c2))
+}
+
+// lumaPlane returns the luma of img composited on black, row by row.
+func lumaPlane(img *image.NRGBA) []float64 {
+	b := img.Bounds()
+	out := make([]float64, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := row[x*4 : x*4+4]
+			alpha := float64(p[3]) / 255
+			out = append(out, luma(float64(p[0]), float64(p[1]), float64(p[2]))*alpha)
+		}
+	}
+	return out
+}
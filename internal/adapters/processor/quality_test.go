@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+func TestLowest(t *testing.T) {
+	for _, threshold := range []int{1, 7, 30, 99, 100} {
+		got, err := lowest(1, 100, func(n int) (bool, error) { return n >= threshold, nil })
+		require.NoError(t, err)
+		assert.Equal(t, threshold, got)
+	}
+	got, err := lowest(1, 100, func(int) (bool, error) { return false, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 100, got, "hi when nothing is accepted")
+}
+
+func TestHighest(t *testing.T) {
+	for _, threshold := range []int{1, 7, 30, 99, 100} {
+		got, err := highest(1, 100, func(n int) (bool, error) { return n <= threshold, nil })
+		require.NoError(t, err)
+		assert.Equal(t, threshold, got)
+	}
+	got, err := highest(1, 100, func(int) (bool, error) { return false, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, got, "lo when nothing is accepted")
+}
+
+// fakeSearch encodes quality q as 10·q bytes that score 0.9 + q/1000.
+func fakeSearch() *qualitySearch {
+	return &qualitySearch{
+		encode: func(q int) ([]byte, error) {
+			return bytes.Repeat([]byte{byte(q)}, 10*q), nil
+		},
+		similarity: func(buf []byte) (float64, error) {
+			return 0.9 + float64(buf[0])/1000, nil
+		},
+	}
+}
+
+func TestQualitySearch(t *testing.T) {
+	auto, fixed := domainImage.QualityAuto, domainImage.Quality(60)
+	tests := []struct {
+		name string
+		spec domainImage.TransformationSpec
+		want int
+	}{
+		{"auto", domainImage.TransformationSpec{Quality: &auto}, 85},
+		{"max bytes", domainImage.TransformationSpec{MaxBytes: 505}, 50},
+		{"max bytes above the fixed quality", domainImage.TransformationSpec{Quality: &fixed, MaxBytes: 5000}, 60},
+		{"max bytes below auto", domainImage.TransformationSpec{Quality: &auto, MaxBytes: 400}, 40},
+		{"max bytes of quality 1", domainImage.TransformationSpec{MaxBytes: 10}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, quality, err := fakeSearch().run(&tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, quality)
+			assert.Len(t, buf, 10*tt.want)
+		})
+	}
+}
+
+func TestQualitySearchFailsWhenNothingFits(t *testing.T) {
+	_, _, err := fakeSearch().run(&domainImage.TransformationSpec{MaxBytes: 9})
+	assert.ErrorIs(t, err, domainImage.ErrMaxBytesUnreachable)
+}
+
+func TestSSIM(t *testing.T) {
+	gradient := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	noisy := image.NewNRGBA(gradient.Rect)
+	flat := image.NewNRGBA(gradient.Rect)
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			v := uint8(x * 8)
+			gradient.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+			noisy.SetNRGBA(x, y, color.NRGBA{v ^ uint8(y%2*16), v, v, 255})
+			flat.SetNRGBA(x, y, color.NRGBA{128, 128, 128, 255})
+		}
+	}
+
+	assert.InDelta(t, 1, ssim(gradient, gradient), 1e-9)
+	slight, far := ssim(gradient, noisy), ssim(gradient, flat)
+	assert.Less(t, slight, 1.0)
+	assert.Less(t, far, slight)
+}
+
+func TestSSIMDims(t *testing.T) {
+	w, h := ssimDims(4000, 2000)
+	assert.Equal(t, []int{1024, 512}, []int{w, h})
+	w, h = ssimDims(300, 200)
+	assert.Equal(t, []int{300, 200}, []int{w, h})
+}
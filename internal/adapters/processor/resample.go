@@ -0,0 +1,196 @@
+package processor
+
+import (
+	"image"
+	"math"
+)
+
+// contribution is one source sample feeding an output sample.
+type contribution struct {
+	index  int
+	weight float32
+}
+
+// planes holds an image as premultiplied float RGBA so that filters do not
+// bleed the colour of transparent pixels into their neighbours.
+type planes struct {
+	w, h int
+	pix  []float32
+}
+
+func toPlanes(src *image.NRGBA) *planes {
+	b := src.Bounds()
+	p := &planes{w: b.Dx(), h: b.Dy(), pix: make([]float32, b.Dx()*b.Dy()*4)}
+	for y := 0; y < p.h; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x := 0; x < p.w; x++ {
+			s, d := row[x*4:x*4+4], p.pix[(y*p.w+x)*4:]
+			a := float32(s[3]) / 255
+			d[0], d[1], d[2], d[3] = float32(s[0])*a, float32(s[1])*a, float32(s[2])*a, float32(s[3])
+		}
+	}
+	return p
+}
+
+func (p *planes) toNRGBA() *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, p.w, p.h))
+	for i := 0; i < len(p.pix); i += 4 {
+		a := clampf(p.pix[i+3])
+		d := dst.Pix[i : i+4 : i+4]
+		d[3] = uint8(a + 0.5)
+		if a == 0 {
+			d[0], d[1], d[2] = 0, 0, 0
+			continue
+		}
+		k := 255 / a
+		d[0], d[1], d[2] = uint8(clampf(p.pix[i]*k)+0.5), uint8(clampf(p.pix[i+1]*k)+0.5), uint8(clampf(p.pix[i+2]*k)+0.5)
+	}
+	return dst
+}
+
+func clampf(v float32) float32 {
+	return float32(math.Max(0, math.Min(255, float64(v))))
+}
+
+// convolve applies per-column contributions horizontally and per-row
+// contributions vertically, producing an image of len(cols)×len(rows).
+func (p *planes) convolve(cols, rows [][]contribution) *planes {
+	tmp := &planes{w: len(cols), h: p.h, pix: make([]float32, len(cols)*p.h*4)}
+	for y := 0; y < p.h; y++ {
+		for x, cs := range cols {
+			var r, g, b, a float32
+			for _, c := range cs {
+				s := p.pix[(y*p.w+c.index)*4:]
+				r += s[0] * c.weight
+				g += s[1] * c.weight
+				b += s[2] * c.weight
+				a += s[3] * c.weight
+			}
+			d := tmp.pix[(y*tmp.w+x)*4:]
+			d[0], d[1], d[2], d[3] = r, g, b, a
+		}
+	}
+
+	out := &planes{w: tmp.w, h: len(rows), pix: make([]float32, tmp.w*len(rows)*4)}
+	for y, cs := range rows {
+		for x := 0; x < tmp.w; x++ {
+			var r, g, b, a float32
+			for _, c := range cs {
+				s := tmp.pix[(c.index*tmp.w+x)*4:]
+				r += s[0] * c.weight
+				g += s[1] * c.weight
+				b += s[2] * c.weight
+				a += s[3] * c.weight
+			}
+			d := out.pix[(y*out.w+x)*4:]
+			d[0], d[1], d[2], d[3] = r, g, b, a
+		}
+	}
+	return out
+}
+
+// resample scales src to w×h with a Lanczos-3 filter, which is also what
+// libvips uses when reducing.
+func resample(src *image.NRGBA, w, h int) *image.NRGBA {
+	b := src.Bounds()
+	if b.Dx() == w && b.Dy() == h {
+		return src
+	}
+	return toPlanes(src).convolve(scaleContributions(b.Dx(), w), scaleContributions(b.Dy(), h)).toNRGBA()
+}
+
+func scaleContributions(srcLen, dstLen int) [][]contribution {
+	scale := float64(srcLen) / float64(dstLen)
+	// Widen the filter when shrinking so every source pixel contributes.
+	filterScale := math.Max(scale, 1)
+	support := 3 * filterScale
+
+	out := make([][]contribution, dstLen)
+	for i := range out {
+		center := (float64(i) + 0.5) * scale
+		left := int(math.Floor(center - support))
+		right := int(math.Ceil(center + support))
+
+		cs := make([]contribution, 0, right-left+1)
+		for j := left; j <= right; j++ {
+			w := lanczos3((float64(j) + 0.5 - center) / filterScale)
+			if w == 0 {
+				continue
+			}
+			cs = append(cs, contribution{index: clampIndex(j, srcLen), weight: float32(w)})
+		}
+		out[i] = normalize(cs)
+	}
+	return out
+}
+
+func lanczos3(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x == 0:
+		return 1
+	case x >= 3:
+		return 0
+	default:
+		px := math.Pi * x
+		return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+	}
+}
+
+// gaussianBlur blurs src with the given sigma.
+func gaussianBlur(src *image.NRGBA, sigma float64) *image.NRGBA {
+	b := src.Bounds()
+	return toPlanes(src).convolve(gaussianContributions(b.Dx(), sigma), gaussianContributions(b.Dy(), sigma)).toNRGBA()
+}
+
+func gaussianContributions(n int, sigma float64) [][]contribution {
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+	}
+
+	out := make([][]contribution, n)
+	for i := range out {
+		cs := make([]contribution, 0, len(kernel))
+		for k, w := range kernel {
+			cs = append(cs, contribution{index: clampIndex(i+k-radius, n), weight: float32(w)})
+		}
+		out[i] = normalize(cs)
+	}
+	return out
+}
+
+// sharpen applies an unsharp mask; strength follows FilterSpec.Sharpen.
+func sharpen(src *image.NRGBA, strength float64) *image.NRGBA {
+	blurred := gaussianBlur(src, 1)
+	amount := strength / 3
+
+	dst := image.NewNRGBA(src.Bounds())
+	for i := 0; i < len(src.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			v := float64(src.Pix[i+c])
+			dst.Pix[i+c] = uint8(clamp8(v + (v-float64(blurred.Pix[i+c]))*amount))
+		}
+		dst.Pix[i+3] = src.Pix[i+3]
+	}
+	return dst
+}
+
+func normalize(cs []contribution) []contribution {
+	var sum float32
+	for _, c := range cs {
+		sum += c.weight
+	}
+	if sum != 0 {
+		for i := range cs {
+			cs[i].weight /= sum
+		}
+	}
+	return cs
+}
+
+func clampIndex(i, n int) int {
+	return min(max(i, 0), n-1)
+}
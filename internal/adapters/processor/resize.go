@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+// resizePlan is what a ResizeSpec does to an image of a given size: it is
+// scaled to scaleW×scaleH and then cropped around the focal point or centre
+// (cover) or letterboxed (contain) to outW×outH. Both processors follow the
+// same plan so their variants have identical dimensions.
+type resizePlan struct {
+	scaleW, scaleH int
+	outW, outH     int
+}
+
+func (p resizePlan) crops() bool {
+	return p.scaleW > p.outW || p.scaleH > p.outH
+}
+
+func (p resizePlan) pads() bool {
+	return p.scaleW < p.outW || p.scaleH < p.outH
+}
+
+func planResize(inW, inH int, r *domainImage.ResizeSpec) resizePlan {
+	sx := float64(r.Width) / float64(inW)
+	sy := float64(r.Height) / float64(inH)
+
+	limit := func(s float64) float64 {
+		if r.WithoutEnlargement {
+			return math.Min(s, 1)
+		}
+		return s
+	}
+	scaled := func(s float64) resizePlan {
+		w := max(1, int(math.Round(float64(inW)*s)))
+		h := max(1, int(math.Round(float64(inH)*s)))
+		return resizePlan{scaleW: w, scaleH: h, outW: w, outH: h}
+	}
+
+	// A single dimension keeps the aspect ratio whatever the fit.
+	switch {
+	case r.Height == 0:
+		return scaled(limit(sx))
+	case r.Width == 0:
+		return scaled(limit(sy))
+	}
+
+	switch r.EffectiveFit() {
+	case domainImage.FitFill:
+		w, h := r.Width, r.Height
+		if r.WithoutEnlargement {
+			w, h = min(w, inW), min(h, inH)
+		}
+		return resizePlan{scaleW: w, scaleH: h, outW: w, outH: h}
+	case domainImage.FitInside:
+		return scaled(limit(math.Min(sx, sy)))
+	case domainImage.FitOutside:
+		return scaled(limit(math.Max(sx, sy)))
+	case domainImage.FitCover:
+		p := scaled(limit(math.Max(sx, sy)))
+		p.outW, p.outH = min(r.Width, p.scaleW), min(r.Height, p.scaleH)
+		return p
+	default: // FitContain
+		s := math.Min(sx, sy)
+		if s > 1 {
+			// Contain never enlarges, as resizes did before Fit existed:
+			// smaller images are returned as they are, not letterboxed.
+			return scaled(1)
+		}
+		p := scaled(s)
+		p.scaleW, p.scaleH = min(p.scaleW, r.Width), min(p.scaleH, r.Height)
+		p.outW, p.outH = r.Width, r.Height
+		return p
+	}
+}
+
+// cropAt cuts the w×h area at left,top out of src, which must contain it.
+func cropAt(src *image.NRGBA, left, top, w, h int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), src, image.Pt(left, top), draw.Src)
+	return dst
+}
+
+// embed centres src on a w×h canvas. As with libvips, the padding of an
+// opaque image is the background colour (black by default) and the padding
+// of an image with transparency is transparent.
+func embed(src *image.NRGBA, w, h int, background *color.NRGBA) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if src.Opaque() {
+		fill := color.NRGBA{A: 0xff}
+		if background != nil {
+			fill = *background
+		}
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(fill), image.Point{}, draw.Src)
+	}
+	b := src.Bounds()
+	offset := image.Pt((w-b.Dx())/2, (h-b.Dy())/2)
+	draw.Draw(dst, b.Add(offset), src, b.Min, draw.Src)
+	return dst
+}
+
+// flatten composites src onto an opaque background. Like libvips, black
+// leaves the image untouched.
+func flatten(src *image.NRGBA, background color.NRGBA) *image.NRGBA {
+	if background.R == 0 && background.G == 0 && background.B == 0 {
+		return src
+	}
+	dst := image.NewNRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+func TestPlanResize(t *testing.T) {
+	tests := []struct {
+		name     string
+		inW, inH int
+		spec     domainImage.ResizeSpec
+		want     resizePlan
+	}{
+		{"contain letterboxes larger images", 400, 200, domainImage.ResizeSpec{Width: 100, Height: 100},
+			resizePlan{scaleW: 100, scaleH: 50, outW: 100, outH: 100}},
+		{"contain never enlarges", 50, 40, domainImage.ResizeSpec{Width: 100, Height: 100},
+			resizePlan{scaleW: 50, scaleH: 40, outW: 50, outH: 40}},
+		{"contain shrinks images larger in one dimension", 100, 500, domainImage.ResizeSpec{Width: 200, Height: 200},
+			resizePlan{scaleW: 40, scaleH: 200, outW: 200, outH: 200}},
+		{"cover crops the overflow", 400, 200, domainImage.ResizeSpec{Width: 100, Height: 100, Fit: domainImage.FitCover},
+			resizePlan{scaleW: 200, scaleH: 100, outW: 100, outH: 100}},
+		{"cover enlarges", 50, 50, domainImage.ResizeSpec{Width: 100, Height: 200, Fit: domainImage.FitCover},
+			resizePlan{scaleW: 200, scaleH: 200, outW: 100, outH: 200}},
+		{"fill stretches", 400, 200, domainImage.ResizeSpec{Width: 100, Height: 100, Fit: domainImage.FitFill},
+			resizePlan{scaleW: 100, scaleH: 100, outW: 100, outH: 100}},
+		{"inside without enlargement", 50, 50, domainImage.ResizeSpec{Width: 100, Height: 200, Fit: domainImage.FitInside, WithoutEnlargement: true},
+			resizePlan{scaleW: 50, scaleH: 50, outW: 50, outH: 50}},
+		{"outside covers the box", 400, 200, domainImage.ResizeSpec{Width: 100, Height: 100, Fit: domainImage.FitOutside},
+			resizePlan{scaleW: 200, scaleH: 100, outW: 200, outH: 100}},
+		{"width only keeps the aspect ratio", 400, 200, domainImage.ResizeSpec{Width: 100},
+			resizePlan{scaleW: 100, scaleH: 50, outW: 100, outH: 50}},
+		{"height only enlarges", 40, 20, domainImage.ResizeSpec{Height: 40},
+			resizePlan{scaleW: 80, scaleH: 40, outW: 80, outH: 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, planResize(tt.inW, tt.inH, &tt.spec))
+		})
+	}
+}
@@ -1,27 +1,50 @@
 package processor
 
 import (
+	"bytes"
 	"context"
-	"errors"
+	"fmt"
 	"image"
-	_ "image/gif"
-	_ "image/jpeg"
-	_ "image/png"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
 	"io"
+	"math"
 
 	domainImage "image-processing-service/internal/domain/image"
 	"image-processing-service/internal/ports"
 )
 
+// defaultQuality matches the libvips encoder default used by BimgProcessor.
+const defaultQuality = domainImage.DefaultQuality
+
+// StdLibImageProcessor transforms images in pure Go. It follows the operation
+// order and output dimensions of BimgProcessor so either can serve the same
+// variants, but it only decodes and encodes JPEG, PNG and GIF and cannot
+// render text watermarks.
 type StdLibImageProcessor struct{}
 
 func NewStdLibImageProcessor() *StdLibImageProcessor {
 	return &StdLibImageProcessor{}
 }
 
+// stdlibFormats are the formats the Go standard library decodes and encodes.
+var stdlibFormats = []string{domainImage.FormatJPEG, domainImage.FormatPNG, domainImage.FormatGIF}
+
+func (p *StdLibImageProcessor) Capabilities() ports.ProcessorCapabilities {
+	return ports.ProcessorCapabilities{Input: stdlibFormats, Output: stdlibFormats}
+}
+
 func (p *StdLibImageProcessor) ExtractMetadata(ctx context.Context, reader io.Reader) (*ports.ImageMetadata, error) {
+	buffer, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
 	// We need to decode config, not the whole image, to be fast.
-	config, format, err := image.DecodeConfig(reader)
+	config, format, err := image.DecodeConfig(bytes.NewReader(buffer))
 	if err != nil {
 		return nil, err
 	}
@@ -39,14 +62,330 @@ func (p *StdLibImageProcessor) ExtractMetadata(ctx context.Context, reader io.Re
 		// _ "golang.org/x/image/webp" // We might need this if we want webp support
 	}
 
+	orientation := exifOrientation(buffer)
+	width, height := displaySize(config.Width, config.Height, orientation)
 	return &ports.ImageMetadata{
-		Width:    config.Width,
-		Height:   config.Height,
+		Width:       width,
+		Height:      height,
+		MimeType:    mimeType,
+		Size:        int64(len(buffer)),
+		HasAlpha:    hasAlpha(config.ColorModel),
+		Orientation: orientation,
+		Animation:   probeAnimation(buffer),
+		Photo:       photoMetadata(buffer, orientation),
+	}, nil
+}
+
+// hasAlpha reports whether images of the color model can be transparent:
+// models with an alpha channel and palettes with a translucent entry.
+func hasAlpha(model color.Model) bool {
+	switch model {
+	case color.RGBAModel, color.RGBA64Model, color.NRGBAModel, color.NRGBA64Model, color.AlphaModel, color.Alpha16Model:
+		return true
+	}
+	if palette, ok := model.(color.Palette); ok {
+		for _, c := range palette {
+			if _, _, _, a := c.RGBA(); a < 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *StdLibImageProcessor) Transform(ctx context.Context, srcReader io.Reader, spec *domainImage.TransformationSpec, assets *ports.TransformAssets) (*ports.ProcessedImage, error) {
+	buffer, err := io.ReadAll(srcReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source image: %w", err)
+	}
+	if isAnimatedGIF(buffer) {
+		return p.transformAnimation(buffer, spec, assets)
+	}
+	if err := checkStillFrame(spec); err != nil {
+		return nil, err
+	}
+
+	decoded, format, err := image.Decode(bytes.NewReader(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode source image: %w", err)
+	}
+
+	exifAngle, exifFlip := orientationTransform(exifOrientation(buffer))
+	img, err := p.render(toNRGBA(decoded), spec, assets, exifAngle, exifFlip)
+	if err != nil {
+		return nil, err
+	}
+	return encodeStill(img, outputFormat(format, spec), spec, readMetadata(buffer).keep(spec.Metadata))
+}
+
+// transformAnimation renders an animated GIF. Every frame is kept when the
+// output can animate; otherwise the frame the spec picks, or the first, is
+// rendered as a still image.
+func (p *StdLibImageProcessor) transformAnimation(buffer []byte, spec *domainImage.TransformationSpec, assets *ports.TransformAssets) (*ports.ProcessedImage, error) {
+	format := outputFormat(domainImage.FormatGIF, spec)
+	pick := -1
+	if spec.Frame != nil {
+		pick = *spec.Frame
+	} else if !keepsAnimation(spec, format) {
+		pick = 0
+	}
+	a, err := decodeGIFAnimation(buffer, pick, spec.MaxFrames)
+	if err != nil {
+		return nil, decodeAnimationError(err)
+	}
+
+	if pick >= 0 {
+		img, err := p.render(a.frames[0], spec, assets, 0, false)
+		if err != nil {
+			return nil, err
+		}
+		return encodeStill(img, format, spec, metadataBlocks{})
+	}
+	if format != domainImage.FormatGIF {
+		return nil, fmt.Errorf("%w: animated %s output", ports.ErrUnsupportedOperation, format)
+	}
+
+	if err := renderAnimation(a, spec, assets); err != nil {
+		return nil, err
+	}
+	data, err := encodeGIFAnimation(a)
+	if err != nil {
+		return nil, err
+	}
+	return &ports.ProcessedImage{
+		Data:     data,
+		MimeType: "image/gif",
+		Width:    a.frames[0].Bounds().Dx(),
+		Height:   a.frames[0].Bounds().Dy(),
+		Size:     int64(len(data)),
+	}, nil
+}
+
+// outputFormat is the format spec asks for, or the source format.
+func outputFormat(source string, spec *domainImage.TransformationSpec) string {
+	if spec.Format != nil {
+		return domainImage.CanonicalFormat(*spec.Format)
+	}
+	return source
+}
+
+// render applies spec to a decoded image, up to encoding. exifAngle and
+// exifFlip give the orientation stored with the source.
+func (p *StdLibImageProcessor) render(img *image.NRGBA, spec *domainImage.TransformationSpec, assets *ports.TransformAssets, exifAngle int, exifFlip bool) (*image.NRGBA, error) {
+	var err error
+	img = orient(img, spec, exifAngle, exifFlip)
+
+	f := newFocus(spec.FocalPoint, spec, img.Bounds().Dx(), img.Bounds().Dy())
+
+	// Crop
+	if c := spec.Crop; c != nil {
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		if err := c.CheckBounds(w, h); err != nil {
+			return nil, err
+		}
+		var x, y int
+		if c.IsSmart() {
+			x, y = smartCrop(img, c.Width, c.Height, c.Gravity)
+		} else {
+			x, y = cropOrigin(c, f, w, h)
+		}
+		img = cropAt(img, x, y, c.Width, c.Height)
+		f = f.shift(x, y)
+	}
+
+	// Resize
+	var background *color.NRGBA
+	if spec.Resize != nil {
+		if spec.Resize.Background != "" {
+			bg, err := parseHexColor(spec.Resize.Background)
+			if err != nil {
+				return nil, err
+			}
+			background = &bg
+		}
+		img = resize(img, spec.Resize, background, f)
+	}
+
+	// Filters, in the order documented on FilterSpec
+	if f := spec.Filters; f != nil {
+		if f.Blur > 0 {
+			img = gaussianBlur(img, float64(f.Blur))
+		}
+		if f.Sharpen > 0 {
+			img = sharpen(img, f.Sharpen)
+		}
+	}
+
+	// Flatten onto the background, where libvips does it
+	if background != nil {
+		img = flatten(img, *background)
+	}
+
+	if needsColorFilters(spec.Filters) {
+		img, err = applyColorFilters(img, spec.Filters)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Watermark
+	if spec.Watermark != nil {
+		img, err = p.watermark(img, spec.Watermark, assets)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// orient rotates and flips img. Like libvips, the EXIF orientation is only
+// honoured when no explicit rotation is requested.
+func orient(img *image.NRGBA, spec *domainImage.TransformationSpec, exifAngle int, exifFlip bool) *image.NRGBA {
+	angle, flip := exifAngle, spec.Flipped() || exifFlip
+	if spec.Rotate != nil && *spec.Rotate > 0 {
+		angle, flip = *spec.Rotate, spec.Flipped()
+	}
+	img = rotate(img, angle)
+	if flip {
+		img = flipHorizontal(img)
+	}
+	if spec.Mirrored() {
+		img = flipVertical(img)
+	}
+	return img
+}
+
+// encodeStill encodes img in format with the metadata blocks kept,
+// searching the quality when spec asks for it.
+func encodeStill(img *image.NRGBA, format string, spec *domainImage.TransformationSpec, kept metadataBlocks) (*ports.ProcessedImage, error) {
+	quality := defaultQuality
+	if spec.Quality != nil && !spec.Quality.IsAuto() {
+		quality = int(*spec.Quality)
+	}
+
+	var data []byte
+	var mimeType string
+	var err error
+	lossy := domainImage.IsLossyFormat(format)
+	if lossy && searchesQuality(spec) {
+		data, quality, err = searchQuality(img, format, spec, kept)
+		mimeType = domainImage.MimeTypeFor(format)
+	} else {
+		var out bytes.Buffer
+		mimeType, err = encode(&out, img, format, quality)
+		if err == nil {
+			data, err = withMetadata(out.Bytes(), kept)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !lossy {
+		quality = 0
+	}
+
+	return &ports.ProcessedImage{
+		Data:     data,
 		MimeType: mimeType,
-		Size:     0, // Size is not available from reader decode
+		Width:    img.Bounds().Dx(),
+		Height:   img.Bounds().Dy(),
+		Size:     int64(len(data)),
+		Quality:  quality,
 	}, nil
 }
 
-func (p *StdLibImageProcessor) Transform(ctx context.Context, srcReader io.Reader, spec *domainImage.TransformationSpec) (*ports.ProcessedImage, error) {
-	return nil, errors.New("transform not implemented in stdlib processor (use bimg)")
+// searchQuality encodes img in format at the quality found for spec's
+// quality auto or MaxBytes. The kept metadata counts towards MaxBytes.
+func searchQuality(img *image.NRGBA, format string, spec *domainImage.TransformationSpec, kept metadataBlocks) ([]byte, int, error) {
+	w, h := ssimDims(img.Bounds().Dx(), img.Bounds().Dy())
+	var reference *image.NRGBA
+	search := &qualitySearch{
+		encode: func(quality int) ([]byte, error) {
+			var out bytes.Buffer
+			if _, err := encode(&out, img, format, quality); err != nil {
+				return nil, err
+			}
+			return withMetadata(out.Bytes(), kept)
+		},
+		similarity: func(buf []byte) (float64, error) {
+			decoded, _, err := image.Decode(bytes.NewReader(buf))
+			if err != nil {
+				return 0, fmt.Errorf("failed to decode image for comparison: %w", err)
+			}
+			if reference == nil {
+				reference = resample(img, w, h)
+			}
+			return ssim(reference, resample(toNRGBA(decoded), w, h)), nil
+		},
+	}
+	return search.run(spec)
+}
+
+// resize applies r following planResize. Cover crops centre on f when set.
+func resize(img *image.NRGBA, r *domainImage.ResizeSpec, background *color.NRGBA, f *focus) *image.NRGBA {
+	inW, inH := img.Bounds().Dx(), img.Bounds().Dy()
+	plan := planResize(inW, inH, r)
+	img = resample(img, plan.scaleW, plan.scaleH)
+	switch {
+	case plan.crops():
+		left, top := centreOn(f.scale(inW, inH, plan.scaleW, plan.scaleH), plan.scaleW, plan.scaleH, plan.outW, plan.outH)
+		return cropAt(img, left, top, plan.outW, plan.outH)
+	case plan.pads():
+		return embed(img, plan.outW, plan.outH, background)
+	default:
+		return img
+	}
+}
+
+// watermark composites an image watermark. Text needs a font renderer and
+// is left to BimgProcessor.
+func (p *StdLibImageProcessor) watermark(img *image.NRGBA, wm *domainImage.WatermarkSpec, assets *ports.TransformAssets) (*image.NRGBA, error) {
+	if wm.ImageID == "" {
+		return nil, fmt.Errorf("%w: text watermarks", ports.ErrUnsupportedOperation)
+	}
+	if assets == nil || len(assets.WatermarkImage) == 0 {
+		return nil, errWatermarkImageMissing
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(assets.WatermarkImage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode watermark image: %w", err)
+	}
+	overlay := toNRGBA(decoded)
+
+	// Scale down to fit half the image, never up.
+	b, ob := img.Bounds(), overlay.Bounds()
+	scale := math.Min(1, math.Min(float64(b.Dx()/2)/float64(ob.Dx()), float64(b.Dy()/2)/float64(ob.Dy())))
+	overlay = resample(overlay,
+		max(1, int(math.Round(float64(ob.Dx())*scale))),
+		max(1, int(math.Round(float64(ob.Dy())*scale))))
+	ob = overlay.Bounds()
+
+	x, y := gravityOffset(wm.EffectiveGravity(), b.Dx(), b.Dy(), ob.Dx(), ob.Dy(), watermarkMargin(b.Dx(), b.Dy()))
+	mask := image.NewUniform(color.Alpha{A: uint8(math.Round(wm.EffectiveOpacity() * 255))})
+	draw.DrawMask(img, ob.Add(image.Pt(x, y)), overlay, image.Point{}, mask, image.Point{}, draw.Over)
+	return img, nil
+}
+
+// encode writes img in the given format and returns its MIME type.
+func encode(w io.Writer, img *image.NRGBA, format string, quality int) (string, error) {
+	switch format {
+	case "jpeg", "jpg":
+		if err := jpeg.Encode(w, img, &jpeg.Options{Quality: quality}); err != nil {
+			return "", fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+		return "image/jpeg", nil
+	case "png":
+		if err := png.Encode(w, img); err != nil {
+			return "", fmt.Errorf("failed to encode png: %w", err)
+		}
+		return "image/png", nil
+	case "gif":
+		if err := gif.Encode(w, img, &gif.Options{NumColors: 256}); err != nil {
+			return "", fmt.Errorf("failed to encode gif: %w", err)
+		}
+		return "image/gif", nil
+	default:
+		return "", fmt.Errorf("%w: %s output", ports.ErrUnsupportedOperation, format)
+	}
 }
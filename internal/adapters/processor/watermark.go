@@ -0,0 +1,46 @@
+package processor
+
+import (
+	"errors"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+var errWatermarkImageMissing = errors.New("watermark image was not loaded")
+
+// gravityOffset returns the top-left corner of a w×h box placed on an
+// outerW×outerH canvas according to gravity, keeping margin pixels away from
+// the edges the box is pinned to.
+func gravityOffset(gravity string, outerW, outerH, w, h, margin int) (x, y int) {
+	left, top := margin, margin
+	right, bottom := outerW-w-margin, outerH-h-margin
+	centerX, centerY := (outerW-w)/2, (outerH-h)/2
+
+	switch gravity {
+	case domainImage.GravityNorth:
+		x, y = centerX, top
+	case domainImage.GravitySouth:
+		x, y = centerX, bottom
+	case domainImage.GravityEast:
+		x, y = right, centerY
+	case domainImage.GravityWest:
+		x, y = left, centerY
+	case domainImage.GravityNorthEast:
+		x, y = right, top
+	case domainImage.GravityNorthWest:
+		x, y = left, top
+	case domainImage.GravitySouthWest:
+		x, y = left, bottom
+	case domainImage.GravitySouthEast:
+		x, y = right, bottom
+	default:
+		x, y = centerX, centerY
+	}
+
+	return max(x, 0), max(y, 0)
+}
+
+// watermarkMargin is the gap kept between a watermark and the image edges.
+func watermarkMargin(width, height int) int {
+	return min(width, height) / 40
+}
@@ -0,0 +1,291 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+// Animated WebP is read and written at the RIFF container level, see
+// https://developers.google.com/speed/webp/docs/riff_container. Frames are
+// encoded and decoded as still WebP images by the caller.
+
+var errMalformedWebP = errors.New("malformed webp")
+
+const (
+	webpFlagAnimation = 0x02
+	webpFlagAlpha     = 0x10
+	// anmfNoBlend and anmfDispose are the ANMF frame flags.
+	anmfNoBlend = 0x02
+	anmfDispose = 0x01
+	// webpMaxDuration is the longest frame duration ANMF can hold.
+	webpMaxDuration = 1<<24 - 1
+)
+
+type webpChunk struct {
+	id   string
+	data []byte
+}
+
+// webpFrame is an ANMF frame: a still WebP placed on the canvas.
+type webpFrame struct {
+	rect     image.Rectangle
+	duration int
+	blend    bool
+	dispose  bool
+	still    []byte
+}
+
+type webpAnimation struct {
+	width, height int
+	// loops is the ANIM loop count, how often the animation plays with
+	// zero playing it forever.
+	loops  int
+	frames []webpFrame
+}
+
+// isAnimatedWebP reports whether buf is a WebP whose VP8X header has the
+// animation flag.
+func isAnimatedWebP(buf []byte) bool {
+	return len(buf) >= 30 &&
+		string(buf[0:4]) == "RIFF" && string(buf[8:12]) == "WEBP" &&
+		string(buf[12:16]) == "VP8X" && buf[20]&webpFlagAnimation != 0
+}
+
+// probeWebPAnimation describes an animated WebP without decoding frames. It
+// returns nil for a single frame, like probeAnimation does for GIF.
+func probeWebPAnimation(buf []byte) (*domainImage.Animation, error) {
+	wa, err := parseWebPAnimation(buf)
+	if err != nil || len(wa.frames) < 2 {
+		return nil, err
+	}
+	m := &domainImage.Animation{Frames: len(wa.frames), LoopCount: wa.loops}
+	for _, f := range wa.frames {
+		m.DurationMS += f.duration
+	}
+	return m, nil
+}
+
+func parseWebPAnimation(buf []byte) (*webpAnimation, error) {
+	if len(buf) < 12 || string(buf[0:4]) != "RIFF" || string(buf[8:12]) != "WEBP" {
+		return nil, errMalformedWebP
+	}
+	chunks, err := readWebPChunks(buf[12:])
+	if err != nil {
+		return nil, err
+	}
+
+	wa := &webpAnimation{}
+	for _, c := range chunks {
+		switch c.id {
+		case "VP8X":
+			if len(c.data) < 10 {
+				return nil, errMalformedWebP
+			}
+			wa.width, wa.height = 1+uint24(c.data[4:]), 1+uint24(c.data[7:])
+		case "ANIM":
+			if len(c.data) < 6 {
+				return nil, errMalformedWebP
+			}
+			wa.loops = int(binary.LittleEndian.Uint16(c.data[4:]))
+		case "ANMF":
+			if len(c.data) < 16 {
+				return nil, errMalformedWebP
+			}
+			d := c.data
+			x, y := 2*uint24(d[0:]), 2*uint24(d[3:])
+			w, h := 1+uint24(d[6:]), 1+uint24(d[9:])
+			frameChunks, err := readWebPChunks(d[16:])
+			if err != nil {
+				return nil, err
+			}
+			wa.frames = append(wa.frames, webpFrame{
+				rect:     image.Rect(x, y, x+w, y+h),
+				duration: uint24(d[12:]),
+				blend:    d[15]&anmfNoBlend == 0,
+				dispose:  d[15]&anmfDispose != 0,
+				still:    stillWebP(w, h, frameChunks),
+			})
+		}
+	}
+	if wa.width == 0 || len(wa.frames) == 0 {
+		return nil, errMalformedWebP
+	}
+	return wa, nil
+}
+
+// decodeWebPAnimation composites the frames of an animated WebP that
+// selectFrames keeps onto its canvas. decodeStill decodes the still WebP of
+// a single frame; frames after the last kept one are not decoded.
+func decodeWebPAnimation(buf []byte, pick, maxFrames int, decodeStill func([]byte) (*image.NRGBA, error)) (*animation, error) {
+	wa, err := parseWebPAnimation(buf)
+	if err != nil {
+		return nil, err
+	}
+	spans, err := selectFrames(len(wa.frames), pick, maxFrames)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAnimationSize(len(spans), wa.width, wa.height, 0); err != nil {
+		return nil, err
+	}
+
+	c := newCompositor(image.Rect(0, 0, wa.width, wa.height), spans, wa.loops)
+	var disposed image.Rectangle
+	for i, f := range wa.frames {
+		if c.done(i) {
+			break
+		}
+		draw.Draw(c.canvas, disposed, image.Transparent, image.Point{}, draw.Src)
+
+		frame, err := decodeStill(f.still)
+		if err != nil {
+			return nil, err
+		}
+		op := draw.Over
+		if !f.blend {
+			op = draw.Src
+		}
+		draw.Draw(c.canvas, f.rect, frame, frame.Bounds().Min, op)
+		c.drawn(i, f.duration)
+
+		disposed = image.Rectangle{}
+		if f.dispose {
+			disposed = f.rect
+		}
+	}
+	return c.a, nil
+}
+
+// encodeWebPAnimation muxes the frames, frame i encoded as a still WebP by
+// encodeStill(i), into an animated WebP. Every frame covers the canvas and
+// replaces the one before.
+func encodeWebPAnimation(a *animation, encodeStill func(i int) ([]byte, error)) ([]byte, error) {
+	w, h := a.frames[0].Bounds().Dx(), a.frames[0].Bounds().Dy()
+	var frames bytes.Buffer
+	alpha := false
+	for i := range a.frames {
+		still, err := encodeStill(i)
+		if err != nil {
+			return nil, err
+		}
+		if len(still) < 12 {
+			return nil, errMalformedWebP
+		}
+		chunks, err := readWebPChunks(still[12:])
+		if err != nil {
+			return nil, err
+		}
+
+		header := make([]byte, 16)
+		putUint24(header[6:], w-1)
+		putUint24(header[9:], h-1)
+		putUint24(header[12:], min(a.delays[i], webpMaxDuration))
+		header[15] = anmfNoBlend
+		payload := bytes.NewBuffer(header)
+		for _, c := range chunks {
+			switch c.id {
+			case "ALPH", "VP8L":
+				alpha = true
+				writeWebPChunk(payload, c.id, c.data)
+			case "VP8 ":
+				writeWebPChunk(payload, c.id, c.data)
+			}
+		}
+		writeWebPChunk(&frames, "ANMF", payload.Bytes())
+	}
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpFlagAnimation
+	if alpha {
+		vp8x[0] |= webpFlagAlpha
+	}
+	putUint24(vp8x[4:], w-1)
+	putUint24(vp8x[7:], h-1)
+	anim := make([]byte, 6)
+	binary.LittleEndian.PutUint16(anim[4:], uint16(min(a.loops, 0xffff)))
+
+	var body bytes.Buffer
+	writeWebPChunk(&body, "VP8X", vp8x)
+	writeWebPChunk(&body, "ANIM", anim)
+	body.Write(frames.Bytes())
+	return riffWebP(body.Bytes()), nil
+}
+
+// firstWebPFrame returns the first frame of an animated WebP as a still.
+func firstWebPFrame(buf []byte) ([]byte, error) {
+	wa, err := parseWebPAnimation(buf)
+	if err != nil {
+		return nil, err
+	}
+	return wa.frames[0].still, nil
+}
+
+// stillWebP wraps the bitstream chunks of a w×h frame as a WebP file. An
+// alpha channel needs the extended format.
+func stillWebP(w, h int, chunks []webpChunk) []byte {
+	var body bytes.Buffer
+	var alph *webpChunk
+	for i, c := range chunks {
+		if c.id == "ALPH" {
+			alph = &chunks[i]
+		}
+	}
+	if alph != nil {
+		vp8x := make([]byte, 10)
+		vp8x[0] = webpFlagAlpha
+		putUint24(vp8x[4:], w-1)
+		putUint24(vp8x[7:], h-1)
+		writeWebPChunk(&body, "VP8X", vp8x)
+		writeWebPChunk(&body, alph.id, alph.data)
+	}
+	for _, c := range chunks {
+		if c.id == "VP8 " || c.id == "VP8L" {
+			writeWebPChunk(&body, c.id, c.data)
+		}
+	}
+	return riffWebP(body.Bytes())
+}
+
+func readWebPChunks(b []byte) ([]webpChunk, error) {
+	var chunks []webpChunk
+	for len(b) >= 8 {
+		size := int(binary.LittleEndian.Uint32(b[4:8]))
+		if size > len(b)-8 {
+			return nil, errMalformedWebP
+		}
+		chunks = append(chunks, webpChunk{id: string(b[0:4]), data: b[8 : 8+size]})
+		b = b[min(8+size+size%2, len(b)):]
+	}
+	return chunks, nil
+}
+
+// writeWebPChunk writes a chunk, padded to an even length.
+func writeWebPChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	_ = binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+func riffWebP(body []byte) []byte {
+	out := make([]byte, 12, 12+len(body))
+	copy(out, "RIFF")
+	binary.LittleEndian.PutUint32(out[4:], uint32(4+len(body)))
+	copy(out[8:], "WEBP")
+	return append(out, body...)
+}
+
+func uint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+func putUint24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domainImage "image-processing-service/internal/domain/image"
+)
+
+var testColors = []color.NRGBA{red, green, blue, white}
+
+// fakeStill is a 1×1 still WebP whose VP8L "bitstream" is the index of its
+// colour in testColors.
+func fakeStill(i int) []byte {
+	return stillWebP(1, 1, []webpChunk{{id: "VP8L", data: []byte{byte(i)}}})
+}
+
+// fakeDecodeStill decodes a fakeStill.
+func fakeDecodeStill(buf []byte) (*image.NRGBA, error) {
+	chunks, err := readWebPChunks(buf[12:])
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, testColors[chunks[0].data[0]])
+	return img, nil
+}
+
+// testWebP muxes a 1×1 animated WebP with a frame of each of testColors,
+// frame i showing for 100·(i+1) milliseconds.
+func testWebP(t testing.TB, loops int) []byte {
+	t.Helper()
+	a := &animation{loops: loops}
+	for i := range testColors {
+		a.frames = append(a.frames, image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+		a.delays = append(a.delays, 100*(i+1))
+	}
+	buf, err := encodeWebPAnimation(a, func(i int) ([]byte, error) { return fakeStill(i), nil })
+	require.NoError(t, err)
+	return buf
+}
+
+func TestEncodeWebPAnimation(t *testing.T) {
+	buf := testWebP(t, 3)
+	assert.True(t, isAnimatedWebP(buf))
+
+	wa, err := parseWebPAnimation(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, wa.width)
+	assert.Equal(t, 1, wa.height)
+	assert.Equal(t, 3, wa.loops)
+	require.Len(t, wa.frames, len(testColors))
+	for i, f := range wa.frames {
+		assert.Equal(t, image.Rect(0, 0, 1, 1), f.rect)
+		assert.Equal(t, 100*(i+1), f.duration)
+		assert.False(t, f.blend, "every frame replaces the one before")
+		assert.Equal(t, fakeStill(i), f.still)
+	}
+
+	first, err := firstWebPFrame(buf)
+	require.NoError(t, err)
+	assert.Equal(t, fakeStill(0), first)
+}
+
+func TestEncodeWebPAnimationCapsDuration(t *testing.T) {
+	a := &animation{frames: []*image.NRGBA{image.NewNRGBA(image.Rect(0, 0, 1, 1))}, delays: []int{webpMaxDuration + 1}}
+	buf, err := encodeWebPAnimation(a, func(i int) ([]byte, error) { return fakeStill(i), nil })
+	require.NoError(t, err)
+	wa, err := parseWebPAnimation(buf)
+	require.NoError(t, err)
+	assert.Equal(t, webpMaxDuration, wa.frames[0].duration)
+}
+
+func TestDecodeWebPAnimation(t *testing.T) {
+	buf := testWebP(t, 0)
+
+	a, err := decodeWebPAnimation(buf, -1, 0, fakeDecodeStill)
+	require.NoError(t, err)
+	assert.Equal(t, testColors, firstPixels(a))
+	assert.Equal(t, []int{100, 200, 300, 400}, a.delays)
+
+	a, err = decodeWebPAnimation(buf, -1, 2, fakeDecodeStill)
+	require.NoError(t, err)
+	assert.Equal(t, []color.NRGBA{red, blue}, firstPixels(a))
+	assert.Equal(t, []int{300, 700}, a.delays)
+
+	decoded := 0
+	a, err = decodeWebPAnimation(buf, 1, 0, func(b []byte) (*image.NRGBA, error) {
+		decoded++
+		return fakeDecodeStill(b)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []color.NRGBA{green}, firstPixels(a))
+	assert.Equal(t, 2, decoded, "frames after the picked one are not decoded")
+
+	_, err = decodeWebPAnimation(buf, 4, 0, fakeDecodeStill)
+	assert.ErrorIs(t, err, domainImage.ErrFrameOutOfRange)
+}
+
+func TestProbeWebPAnimation(t *testing.T) {
+	assert.Equal(t, &domainImage.Animation{Frames: 4, LoopCount: 2, DurationMS: 1000}, probeAnimation(testWebP(t, 2)))
+}
+
+func TestReadWebPChunks(t *testing.T) {
+	chunks, err := readWebPChunks([]byte("ABCD\x03\x00\x00\x00xyz\x00EFGH\x00\x00\x00\x00"))
+	require.NoError(t, err)
+	assert.Equal(t, []webpChunk{{"ABCD", []byte("xyz")}, {"EFGH", []byte{}}}, chunks, "odd chunks are padded")
+
+	_, err = readWebPChunks([]byte("ABCD\xff\x00\x00\x00xyz"))
+	assert.ErrorIs(t, err, errMalformedWebP, "chunk larger than the file")
+}
+
+func TestParseWebPAnimationMalformed(t *testing.T) {
+	buf := testWebP(t, 0)
+	for _, bad := range [][]byte{
+		nil,
+		[]byte("RIFF\x00\x00\x00\x00WEBX"),
+		riffWebP([]byte("VP8X\x02\x00\x00\x00\x02\x00")),
+		riffWebP([]byte("ANMF\x02\x00\x00\x00\x00\x00")),
+		buf[:len(buf)-3],
+	} {
+		_, err := parseWebPAnimation(bad)
+		assert.ErrorIs(t, err, errMalformedWebP, "%q", bad)
+	}
+}
+
+func FuzzParseWebPAnimation(f *testing.F) {
+	f.Add(testWebP(f, 0))
+	f.Add(fakeStill(0))
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		wa, err := parseWebPAnimation(buf)
+		if err != nil {
+			return
+		}
+		if wa.width <= 0 || len(wa.frames) == 0 {
+			t.Fatalf("parsed %dx%d with %d frames", wa.width, wa.height, len(wa.frames))
+		}
+	})
+}
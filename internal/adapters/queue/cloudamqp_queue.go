@@ -3,15 +3,37 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 
+	"image-processing-service/internal/adapters/monitoring"
 	"image-processing-service/internal/config"
 	"image-processing-service/internal/ports"
 )
 
+const (
+	// headerAttempts counts the attempts already made before a delivery.
+	headerAttempts = "x-attempts"
+	// headerLastError carries the error of the most recent failed attempt.
+	headerLastError = "x-last-error"
+	// headerDeath is maintained by RabbitMQ every time a message expires
+	// from a retry queue and is dead-lettered back to the work queue.
+	headerDeath = "x-death"
+)
+
+// CloudAMQPQueue publishes and consumes TransformJobs over AMQP.
+//
+// Failed jobs are not requeued in place. Attempt n is published to the
+// retry queue "<queue>.retry.<n>.<delay>ms", whose message TTL grows
+// exponentially and whose dead-letter route points back at the work queue.
+// The delay is part of the name, so changing the retry delays declares new
+// queues instead of failing to redeclare the old ones. Once MaxAttempts is
+// reached, or the handler reports ports.ErrPermanentFailure, the job is
+// published to the dead-letter exchange and parked in the dead-letter queue.
 type CloudAMQPQueue struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
@@ -19,6 +41,13 @@ type CloudAMQPQueue struct {
 }
 
 func NewCloudAMQPQueue(cfg config.CloudAMQPConfig) (*CloudAMQPQueue, error) {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.DeadLetterQueue == "" {
+		cfg.DeadLetterQueue = cfg.QueueName + ".dead"
+	}
+
 	conn, err := amqp.Dial(cfg.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to rabbitmq: %w", err)
@@ -30,19 +59,16 @@ func NewCloudAMQPQueue(cfg config.CloudAMQPConfig) (*CloudAMQPQueue, error) {
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
 
-	// Declare queue to ensure it exists
-	_, err = ch.QueueDeclare(
-		cfg.QueueName,    // name
-		cfg.QueueDurable, // durable
-		false,            // delete when unused
-		false,            // exclusive
-		false,            // no-wait
-		nil,              // arguments
-	)
-	if err != nil {
+	q := &CloudAMQPQueue{
+		conn:    conn,
+		channel: ch,
+		cfg:     cfg,
+	}
+
+	if err := q.declareTopology(); err != nil {
 		_ = ch.Close()
 		_ = conn.Close()
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+		return nil, err
 	}
 
 	if err := ch.Qos(
@@ -55,11 +81,55 @@ func NewCloudAMQPQueue(cfg config.CloudAMQPConfig) (*CloudAMQPQueue, error) {
 		return nil, fmt.Errorf("failed to set qos: %w", err)
 	}
 
-	return &CloudAMQPQueue{
-		conn:    conn,
-		channel: ch,
-		cfg:     cfg,
-	}, nil
+	return q, nil
+}
+
+// declareTopology ensures the work queue, one retry queue per retryable
+// attempt and the dead-letter exchange and queue exist.
+func (q *CloudAMQPQueue) declareTopology() error {
+	// Work queue; declared without arguments so existing deployments keep
+	// a compatible declaration.
+	if _, err := q.channel.QueueDeclare(
+		q.cfg.QueueName,    // name
+		q.cfg.QueueDurable, // durable
+		false,              // delete when unused
+		false,              // exclusive
+		false,              // no-wait
+		nil,                // arguments
+	); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	for attempt := 1; attempt < q.cfg.MaxAttempts; attempt++ {
+		args := amqp.Table{
+			"x-message-ttl":             q.retryDelay(attempt).Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": q.cfg.QueueName,
+		}
+		if _, err := q.channel.QueueDeclare(q.retryQueueName(attempt), q.cfg.QueueDurable, false, false, false, args); err != nil {
+			return fmt.Errorf("failed to declare retry queue: %w", err)
+		}
+	}
+
+	if err := q.channel.ExchangeDeclare(
+		q.deadLetterExchange(), // name
+		amqp.ExchangeFanout,    // kind
+		true,                   // durable
+		false,                  // auto-deleted
+		false,                  // internal
+		false,                  // no-wait
+		nil,                    // arguments
+	); err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange: %w", err)
+	}
+	if _, err := q.channel.QueueDeclare(q.cfg.DeadLetterQueue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+	if err := q.channel.QueueBind(q.cfg.DeadLetterQueue, "", q.deadLetterExchange(), false, nil); err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue: %w", err)
+	}
+
+	return nil
 }
 
 func (q *CloudAMQPQueue) Publish(ctx context.Context, job *ports.TransformJob) error {
@@ -100,30 +170,150 @@ func (q *CloudAMQPQueue) Consume(ctx context.Context, handler func(*ports.Transf
 
 	go func() {
 		for d := range msgs {
-			var job ports.TransformJob
-			if err := json.Unmarshal(d.Body, &job); err != nil {
-				log.Printf("Error unmarshalling job: %v", err)
-				_ = d.Nack(false, false) // Dead letter or discard
-				continue
-			}
-
-			// Execute handler
-			if err := handler(&job); err != nil {
-				log.Printf("Error processing job %s: %v", job.JobID, err)
-				if nerr := d.Nack(false, true); nerr != nil {
-					log.Printf("Error nacking message: %v", nerr)
-				}
-			} else {
-				if aerr := d.Ack(false); aerr != nil {
-					log.Printf("Error acking message: %v", aerr)
-				}
-			}
+			q.handleDelivery(ctx, d, handler)
 		}
 	}()
 
 	return nil
 }
 
+func (q *CloudAMQPQueue) handleDelivery(ctx context.Context, d amqp.Delivery, handler func(*ports.TransformJob) error) {
+	attempt := attemptOf(d)
+
+	var job ports.TransformJob
+	if err := json.Unmarshal(d.Body, &job); err != nil {
+		log.Printf("Error unmarshalling job: %v", err)
+		q.deadLetter(ctx, d, attempt, "malformed", err)
+		return
+	}
+	job.Attempt = attempt
+	job.MaxAttempts = q.cfg.MaxAttempts
+
+	// Execute handler
+	herr := handler(&job)
+	if herr == nil {
+		monitoring.RecordJobAttempts(attempt)
+		if aerr := d.Ack(false); aerr != nil {
+			log.Printf("Error acking message: %v", aerr)
+		}
+		return
+	}
+
+	log.Printf("Error processing job %s (attempt %d/%d): %v", job.JobID, attempt, q.cfg.MaxAttempts, herr)
+	switch {
+	case errors.Is(herr, ports.ErrPermanentFailure):
+		q.deadLetter(ctx, d, attempt, "permanent", herr)
+	case attempt >= q.cfg.MaxAttempts:
+		q.deadLetter(ctx, d, attempt, "exhausted", herr)
+	default:
+		q.retry(ctx, d, attempt, herr)
+	}
+}
+
+// retry republishes the delivery to the retry queue for the given attempt
+// and acks the original.
+func (q *CloudAMQPQueue) retry(ctx context.Context, d amqp.Delivery, attempt int, cause error) {
+	if err := q.republish(ctx, d, "", q.retryQueueName(attempt), attempt, cause); err != nil {
+		log.Printf("Error scheduling retry: %v", err)
+		if nerr := d.Nack(false, true); nerr != nil {
+			log.Printf("Error nacking message: %v", nerr)
+		}
+		return
+	}
+
+	monitoring.RecordQueueRetry(attempt)
+	if aerr := d.Ack(false); aerr != nil {
+		log.Printf("Error acking message: %v", aerr)
+	}
+}
+
+// deadLetter parks the delivery in the dead-letter queue and acks the original.
+func (q *CloudAMQPQueue) deadLetter(ctx context.Context, d amqp.Delivery, attempt int, reason string, cause error) {
+	if err := q.republish(ctx, d, q.deadLetterExchange(), "", attempt, cause); err != nil {
+		log.Printf("Error dead-lettering message: %v", err)
+		if nerr := d.Nack(false, true); nerr != nil {
+			log.Printf("Error nacking message: %v", nerr)
+		}
+		return
+	}
+
+	monitoring.RecordDeadLetter(reason)
+	monitoring.RecordJobAttempts(attempt)
+	if aerr := d.Ack(false); aerr != nil {
+		log.Printf("Error acking message: %v", aerr)
+	}
+}
+
+func (q *CloudAMQPQueue) republish(ctx context.Context, d amqp.Delivery, exchange, key string, attempt int, cause error) error {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[headerAttempts] = int32(attempt) // #nosec G115
+	headers[headerLastError] = cause.Error()
+
+	return q.channel.PublishWithContext(ctx,
+		exchange,
+		key,
+		false, // mandatory
+		false, // immediate
+		amqp.Publishing{
+			ContentType:  d.ContentType,
+			Headers:      headers,
+			Body:         d.Body,
+			DeliveryMode: amqp.Persistent,
+		})
+}
+
+func (q *CloudAMQPQueue) retryQueueName(attempt int) string {
+	return fmt.Sprintf("%s.retry.%d.%dms", q.cfg.QueueName, attempt, q.retryDelay(attempt).Milliseconds())
+}
+
+func (q *CloudAMQPQueue) retryDelay(attempt int) time.Duration {
+	return retryDelay(q.cfg.RetryBaseDelay, q.cfg.RetryMaxDelay, attempt)
+}
+
+func (q *CloudAMQPQueue) deadLetterExchange() string {
+	return q.cfg.QueueName + ".dlx"
+}
+
+// attemptOf returns the 1-based attempt number of a delivery. It prefers the
+// counter written on republish and falls back to the x-death entries added
+// by RabbitMQ when a retry queue dead-letters the message back.
+func attemptOf(d amqp.Delivery) int {
+	if n, ok := toInt(d.Headers[headerAttempts]); ok && n > 0 {
+		return n + 1
+	}
+
+	deaths, _ := d.Headers[headerDeath].([]interface{})
+	expired := 0
+	for _, entry := range deaths {
+		table, ok := entry.(amqp.Table)
+		if !ok || table["reason"] != "expired" {
+			continue
+		}
+		if n, ok := toInt(table["count"]); ok {
+			expired += n
+		}
+	}
+	return expired + 1
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
+
 func (q *CloudAMQPQueue) Close() error {
 	if q.channel != nil {
 		_ = q.channel.Close()
@@ -0,0 +1,281 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"image-processing-service/internal/adapters/monitoring"
+	"image-processing-service/internal/ports"
+)
+
+var ErrQueueClosed = errors.New("queue is closed")
+
+// MemoryQueueOptions configures a MemoryQueue.
+type MemoryQueueOptions struct {
+	BufferSize     int
+	Workers        int
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	DrainTimeout   time.Duration
+}
+
+// DeadLetter is a job the MemoryQueue gave up on.
+type DeadLetter struct {
+	Body     []byte
+	Attempts int
+	Reason   string
+	Error    string
+	At       time.Time
+}
+
+type memoryDelivery struct {
+	body     []byte
+	attempts int // attempts already made
+}
+
+// MemoryQueue is an in-process ports.Queue for development and tests.
+//
+// It mirrors CloudAMQPQueue: messages are serialized on publish, failed jobs
+// are retried after an exponential backoff until MaxAttempts is reached, and
+// jobs that fail permanently or run out of attempts are dead-lettered. Jobs
+// are lost when the process exits; Close drains what is already buffered.
+type MemoryQueue struct {
+	opts MemoryQueueOptions
+	jobs chan memoryDelivery
+	stop chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	pending map[*time.Timer]memoryDelivery
+	dead    []DeadLetter
+
+	// outstanding counts accepted messages that have not been acked or
+	// dead-lettered yet, including those waiting for a retry.
+	outstanding sync.WaitGroup
+	workers     sync.WaitGroup
+}
+
+func NewMemoryQueue(opts MemoryQueueOptions) *MemoryQueue {
+	if opts.BufferSize < 1 {
+		opts.BufferSize = 100
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = 30 * time.Second
+	}
+
+	return &MemoryQueue{
+		opts:    opts,
+		jobs:    make(chan memoryDelivery, opts.BufferSize),
+		stop:    make(chan struct{}),
+		pending: make(map[*time.Timer]memoryDelivery),
+	}
+}
+
+// Publish enqueues the job, blocking while the buffer is full until ctx is done.
+func (q *MemoryQueue) Publish(ctx context.Context, job *ports.TransformJob) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	q.outstanding.Add(1)
+	q.mu.Unlock()
+
+	select {
+	case q.jobs <- memoryDelivery{body: body}:
+		monitoring.UpdateQueueDepth(float64(len(q.jobs)))
+		return nil
+	case <-ctx.Done():
+		q.outstanding.Done()
+		return fmt.Errorf("failed to publish message: %w", ctx.Err())
+	}
+}
+
+// Consume starts the configured number of consumer goroutines.
+func (q *MemoryQueue) Consume(ctx context.Context, handler func(*ports.TransformJob) error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	for i := 0; i < q.opts.Workers; i++ {
+		q.workers.Add(1)
+		go q.consume(ctx, handler)
+	}
+	return nil
+}
+
+func (q *MemoryQueue) consume(ctx context.Context, handler func(*ports.TransformJob) error) {
+	defer q.workers.Done()
+	for {
+		select {
+		case d := <-q.jobs:
+			monitoring.UpdateQueueDepth(float64(len(q.jobs)))
+			q.handleDelivery(d, handler)
+		case <-q.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (q *MemoryQueue) handleDelivery(d memoryDelivery, handler func(*ports.TransformJob) error) {
+	attempt := d.attempts + 1
+
+	var job ports.TransformJob
+	if err := json.Unmarshal(d.body, &job); err != nil {
+		log.Printf("Error unmarshalling job: %v", err)
+		q.deadLetter(d, attempt, "malformed", err)
+		return
+	}
+	job.Attempt = attempt
+	job.MaxAttempts = q.opts.MaxAttempts
+
+	// Execute handler
+	herr := handler(&job)
+	if herr == nil {
+		monitoring.RecordJobAttempts(attempt)
+		q.outstanding.Done()
+		return
+	}
+
+	log.Printf("Error processing job %s (attempt %d/%d): %v", job.JobID, attempt, q.opts.MaxAttempts, herr)
+	switch {
+	case errors.Is(herr, ports.ErrPermanentFailure):
+		q.deadLetter(d, attempt, "permanent", herr)
+	case attempt >= q.opts.MaxAttempts:
+		q.deadLetter(d, attempt, "exhausted", herr)
+	default:
+		monitoring.RecordQueueRetry(attempt)
+		d.attempts = attempt
+		q.scheduleRetry(d, retryDelay(q.opts.RetryBaseDelay, q.opts.RetryMaxDelay, attempt))
+	}
+}
+
+// scheduleRetry re-enqueues the delivery after the delay. While the queue is
+// draining, retries are not waited for and the job is dead-lettered instead.
+func (q *MemoryQueue) scheduleRetry(d memoryDelivery, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.deadLetterLocked(d, d.attempts, "shutdown", ErrQueueClosed)
+		q.outstanding.Done()
+		return
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		_, ok := q.pending[t]
+		delete(q.pending, t)
+		q.mu.Unlock()
+
+		if !ok {
+			return
+		}
+		// The buffer may stay full once Shutdown gives up draining it.
+		select {
+		case q.jobs <- d:
+		case <-q.stop:
+			q.deadLetter(d, d.attempts, "shutdown", ErrQueueClosed)
+		}
+	})
+	q.pending[t] = d
+}
+
+func (q *MemoryQueue) deadLetter(d memoryDelivery, attempt int, reason string, cause error) {
+	q.mu.Lock()
+	q.deadLetterLocked(d, attempt, reason, cause)
+	q.mu.Unlock()
+	q.outstanding.Done()
+}
+
+func (q *MemoryQueue) deadLetterLocked(d memoryDelivery, attempt int, reason string, cause error) {
+	monitoring.RecordDeadLetter(reason)
+	monitoring.RecordJobAttempts(attempt)
+
+	// Keep the dead-letter list bounded like the buffer itself.
+	if len(q.dead) >= q.opts.BufferSize {
+		q.dead = q.dead[1:]
+	}
+	q.dead = append(q.dead, DeadLetter{
+		Body:     d.body,
+		Attempts: attempt,
+		Reason:   reason,
+		Error:    cause.Error(),
+		At:       time.Now().UTC(),
+	})
+}
+
+// DeadLetters returns the most recent dead-lettered jobs.
+func (q *MemoryQueue) DeadLetters() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]DeadLetter, len(q.dead))
+	copy(out, q.dead)
+	return out
+}
+
+// Close stops accepting jobs and drains buffered ones within DrainTimeout.
+func (q *MemoryQueue) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), q.opts.DrainTimeout)
+	defer cancel()
+	return q.Shutdown(ctx)
+}
+
+// Shutdown stops accepting jobs and waits until buffered and in-flight jobs
+// are handled or ctx is done. Jobs waiting for a retry are dead-lettered.
+func (q *MemoryQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	for t, d := range q.pending {
+		// A timer that already fired re-enqueues its job itself.
+		if t.Stop() {
+			delete(q.pending, t)
+			q.deadLetterLocked(d, d.attempts, "shutdown", ErrQueueClosed)
+			q.outstanding.Done()
+		}
+	}
+	q.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		q.outstanding.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = fmt.Errorf("queue drain interrupted: %w", ctx.Err())
+	}
+
+	close(q.stop)
+	q.workers.Wait()
+	return err
+}
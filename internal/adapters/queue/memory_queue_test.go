@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/ports"
+)
+
+// A retry that fires while the buffer is full must not block forever once
+// Shutdown has given up draining.
+func TestMemoryQueueRetryDoesNotOutliveShutdown(t *testing.T) {
+	const retryDelay = 50 * time.Millisecond
+	q := NewMemoryQueue(MemoryQueueOptions{BufferSize: 1, MaxAttempts: 2, RetryBaseDelay: retryDelay})
+
+	failed := make(chan struct{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, q.Consume(context.Background(), func(job *ports.TransformJob) error {
+		switch job.JobID {
+		case "retried":
+			close(failed)
+			return errors.New("storage unavailable")
+		case "blocker":
+			close(started)
+			<-release
+		}
+		return nil
+	}))
+
+	// The only worker is busy and the buffer is full when the retry fires.
+	require.NoError(t, q.Publish(context.Background(), &ports.TransformJob{JobID: "retried"}))
+	<-failed
+	require.NoError(t, q.Publish(context.Background(), &ports.TransformJob{JobID: "blocker"}))
+	<-started
+	require.NoError(t, q.Publish(context.Background(), &ports.TransformJob{JobID: "buffered"}))
+	time.Sleep(2 * retryDelay)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		shutdown <- q.Shutdown(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		for _, d := range q.DeadLetters() {
+			if d.Reason == "shutdown" && d.Attempts == 1 {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond, "the retry is dead-lettered")
+
+	close(release)
+	select {
+	case err := <-shutdown:
+		assert.Error(t, err, "the drain was interrupted")
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not return")
+	}
+}
@@ -0,0 +1,19 @@
+package queue
+
+import "time"
+
+// retryDelay returns the backoff before the attempt following the given one:
+// base * 2^(attempt-1), capped at max.
+func retryDelay(base, max time.Duration, attempt int) time.Duration {
+	delay := base
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if max > 0 && delay >= max {
+			return max
+		}
+	}
+	return delay
+}
@@ -0,0 +1,40 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"image-processing-service/internal/config"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		base, max time.Duration
+		attempt   int
+		want      time.Duration
+	}{
+		{"first attempt waits the base delay", 5 * time.Second, time.Minute, 1, 5 * time.Second},
+		{"doubles per attempt", 5 * time.Second, time.Minute, 3, 20 * time.Second},
+		{"capped at max", 5 * time.Second, time.Minute, 10, time.Minute},
+		{"no cap without max", time.Second, 0, 5, 16 * time.Second},
+		{"base defaults to a second", 0, 0, 2, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryDelay(tt.base, tt.max, tt.attempt))
+		})
+	}
+}
+
+func TestRetryQueueNameCarriesDelay(t *testing.T) {
+	q := &CloudAMQPQueue{cfg: config.CloudAMQPConfig{QueueName: "jobs", RetryBaseDelay: 5 * time.Second, RetryMaxDelay: time.Minute}}
+	assert.Equal(t, "jobs.retry.1.5000ms", q.retryQueueName(1))
+	assert.Equal(t, "jobs.retry.2.10000ms", q.retryQueueName(2))
+
+	// Other delays must not redeclare the same queue with another TTL.
+	q.cfg.RetryBaseDelay = 2 * time.Second
+	assert.NotEqual(t, "jobs.retry.1.5000ms", q.retryQueueName(1))
+}
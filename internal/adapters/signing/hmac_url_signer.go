@@ -0,0 +1,64 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"image-processing-service/internal/ports"
+)
+
+// HMACURLSigner signs with HMAC-SHA256 under the active key and verifies
+// signatures made with any configured key, so keys can be rotated by adding
+// a new active key and removing the old one once its URLs have expired.
+type HMACURLSigner struct {
+	keys        map[string][]byte
+	activeKeyID string
+}
+
+func NewHMACURLSigner(keys map[string]string, activeKeyID string) (*HMACURLSigner, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active signing key %q is not configured", activeKeyID)
+	}
+
+	s := &HMACURLSigner{
+		keys:        make(map[string][]byte, len(keys)),
+		activeKeyID: activeKeyID,
+	}
+	for id, secret := range keys {
+		if secret == "" {
+			return nil, fmt.Errorf("signing key %q is empty", id)
+		}
+		s.keys[id] = []byte(secret)
+	}
+	return s, nil
+}
+
+func (s *HMACURLSigner) Sign(payload string) (string, string) {
+	return s.activeKeyID, s.sign(s.activeKeyID, payload)
+}
+
+func (s *HMACURLSigner) Verify(payload, keyID, signature string) error {
+	if _, ok := s.keys[keyID]; !ok {
+		return ports.ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(keyID, payload))) {
+		return ports.ErrInvalidSignature
+	}
+	return nil
+}
+
+// sign covers the key ID as well, so a signature cannot be replayed under
+// another key.
+func (s *HMACURLSigner) sign(keyID, payload string) string {
+	mac := hmac.New(sha256.New, s.keys[keyID])
+	mac.Write([]byte(keyID))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"path"
 	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
@@ -14,8 +16,9 @@ import (
 )
 
 type CloudinaryStorage struct {
-	client *cloudinary.Cloudinary
-	config config.CloudinaryConfig
+	client     *cloudinary.Cloudinary
+	config     config.CloudinaryConfig
+	httpClient *http.Client
 }
 
 func NewCloudinaryStorage(cfg config.CloudinaryConfig) (*CloudinaryStorage, error) {
@@ -23,9 +26,11 @@ func NewCloudinaryStorage(cfg config.CloudinaryConfig) (*CloudinaryStorage, erro
 	if err != nil {
 		return nil, fmt.Errorf("failed to init cloudinary: %w", err)
 	}
+	cld.Config.URL.Secure = cfg.Secure
 	return &CloudinaryStorage{
-		client: cld,
-		config: cfg,
+		client:     cld,
+		config:     cfg,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
 	}, nil
 }
 
@@ -46,16 +51,30 @@ func (s *CloudinaryStorage) Put(ctx context.Context, key string, reader io.Reade
 }
 
 func (s *CloudinaryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
-	_, err := s.SignedURL(ctx, key, 1*time.Hour)
+	url, err := s.SignedURL(ctx, key, 1*time.Hour)
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, fmt.Errorf("direct get not implemented for cloudinary, use SignedURL")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build cloudinary request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cloudinary download failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("cloudinary download failed: unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
 }
 
 func (s *CloudinaryStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
-	asset, err := s.client.Image(key)
+	asset, err := s.client.Image(s.publicID(key))
 	if err != nil {
 		return "", err
 	}
@@ -67,9 +86,26 @@ func (s *CloudinaryStorage) SignedURL(ctx context.Context, key string, expiry ti
 	return url, nil
 }
 
+// Delete reports API errors, which Cloudinary returns in the result body;
+// a "not found" result counts as deleted.
 func (s *CloudinaryStorage) Delete(ctx context.Context, key string) error {
-	_, err := s.client.Upload.Destroy(ctx, uploader.DestroyParams{
-		PublicID: key,
+	result, err := s.client.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID: s.publicID(key),
 	})
-	return err
+	if err != nil {
+		return fmt.Errorf("cloudinary delete failed: %w", err)
+	}
+	if result.Error.Message != "" {
+		return fmt.Errorf("cloudinary delete failed: %s", result.Error.Message)
+	}
+	return nil
+}
+
+// publicID maps a storage key to the Cloudinary public ID assigned by Put,
+// which nests uploads under the configured folder.
+func (s *CloudinaryStorage) publicID(key string) string {
+	if s.config.Folder == "" {
+		return key
+	}
+	return path.Join(s.config.Folder, key)
 }
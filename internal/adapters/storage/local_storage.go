@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -56,7 +58,11 @@ func (s *LocalStorage) SignedURL(ctx context.Context, key string, expiry time.Du
 	return filepath.Join(s.basePath, key), nil
 }
 
+// Delete treats a missing file as deleted so retried deletions succeed.
 func (s *LocalStorage) Delete(ctx context.Context, key string) error {
 	fullPath := filepath.Join(s.basePath, key)
-	return os.Remove(fullPath)
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
 }
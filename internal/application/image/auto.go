@@ -0,0 +1,71 @@
+package image
+
+import (
+	"strconv"
+	"strings"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// AutoOptions enables the auto values of specs. Disabled, format auto
+// resolves to JPEG or PNG without looking at the Accept header and quality
+// auto to the default quality.
+type AutoOptions struct {
+	Format  bool
+	Quality bool
+}
+
+// resolveAuto resolves the auto values of spec before it is hashed and
+// reports whether the format depended on accept.
+func resolveAuto(img *image.Image, spec *image.TransformationSpec, processor ports.ImageProcessor, accept string, auto AutoOptions) bool {
+	if !auto.Quality && spec.Quality != nil && spec.Quality.IsAuto() {
+		spec.Quality = nil
+	}
+	return negotiateFormat(img, spec, processor, accept, auto.Format)
+}
+
+// negotiateFormat replaces FormatAuto in spec with the best format that the
+// Accept header lists and the processor can encode, and reports whether the
+// choice depended on accept. It runs before the spec is hashed so that each
+// chosen format is its own variant. With negotiation disabled, auto always
+// resolves to JPEG, or PNG for images that may be transparent. Animations
+// resolve to WebP or GIF unless the spec picks a single frame.
+func negotiateFormat(img *image.Image, spec *image.TransformationSpec, processor ports.ImageProcessor, accept string, enabled bool) bool {
+	if spec.Format == nil || !strings.EqualFold(*spec.Format, image.FormatAuto) {
+		return false
+	}
+
+	caps := processor.Capabilities()
+	accepts := func(format string) bool {
+		return enabled && caps.CanOutput(format) && acceptsMimeType(accept, image.MimeTypeFor(format))
+	}
+	format := image.ChooseFormat(accepts, img.MayHaveAlpha())
+	if img.Animation != nil && spec.Frame == nil {
+		format = image.ChooseAnimationFormat(accepts)
+	}
+	spec.Format = &format
+	return enabled
+}
+
+// acceptsMimeType reports whether an Accept header lists mimeType with a
+// non-zero quality. Wildcard ranges do not count: browsers send */* and
+// image/* whatever formats they decode.
+func acceptsMimeType(header, mimeType string) bool {
+	for _, mediaRange := range strings.Split(header, ",") {
+		params := strings.Split(mediaRange, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), mimeType) {
+			continue
+		}
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q <= 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	processor := &fakeProcessor{caps: ports.ProcessorCapabilities{Output: []string{"jpeg", "png", "webp", "gif"}}}
+	opaque, transparent := false, true
+	tests := []struct {
+		name       string
+		img        image.Image
+		accept     string
+		enabled    bool
+		want       string
+		negotiated bool
+	}{
+		{"webp accepted", image.Image{HasAlpha: &opaque}, "image/avif,image/webp,*/*", true, "webp", true},
+		{"avif the processor lacks", image.Image{HasAlpha: &opaque}, "image/avif", true, "jpeg", true},
+		{"wildcards do not count", image.Image{HasAlpha: &opaque}, "image/*,*/*", true, "jpeg", true},
+		{"refused with q=0", image.Image{HasAlpha: &opaque}, "image/webp;q=0", true, "jpeg", true},
+		{"transparent", image.Image{HasAlpha: &transparent}, "", true, "png", true},
+		{"disabled", image.Image{HasAlpha: &opaque}, "image/webp", false, "jpeg", false},
+		{"animation", image.Image{Animation: &image.Animation{Frames: 3}}, "", true, "gif", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &image.TransformationSpec{Format: stringPtr("auto")}
+			negotiated := negotiateFormat(&tt.img, spec, processor, tt.accept, tt.enabled)
+			assert.Equal(t, tt.want, *spec.Format)
+			assert.Equal(t, tt.negotiated, negotiated)
+		})
+	}
+}
+
+func TestNegotiateFormatLeavesExplicitFormats(t *testing.T) {
+	spec := &image.TransformationSpec{Format: stringPtr("png")}
+	assert.False(t, negotiateFormat(&image.Image{}, spec, nil, "image/webp", true))
+	assert.Equal(t, "png", *spec.Format)
+}
@@ -0,0 +1,51 @@
+package image
+
+import (
+	"fmt"
+	"strings"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// UnsupportedFormatError rejects a spec whose output format the processor
+// cannot encode. It wraps ports.ErrUnsupportedOperation.
+type UnsupportedFormatError struct {
+	Format string
+	// Available lists the output formats the processor can encode.
+	Available []string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("%v: %s output; available formats: %s", ports.ErrUnsupportedOperation, e.Format, strings.Join(e.Available, ", "))
+}
+
+func (e *UnsupportedFormatError) Unwrap() error {
+	return ports.ErrUnsupportedOperation
+}
+
+// checkFormat rejects specs asking for an output format the processor
+// cannot encode.
+func checkFormat(processor ports.ImageProcessor, spec *image.TransformationSpec) error {
+	if processor == nil || spec.Format == nil {
+		return nil
+	}
+	caps := processor.Capabilities()
+	if !caps.CanOutput(*spec.Format) {
+		return &UnsupportedFormatError{Format: *spec.Format, Available: caps.Output}
+	}
+	return nil
+}
+
+type GetCapabilitiesUseCase struct {
+	processor ports.ImageProcessor
+}
+
+func NewGetCapabilitiesUseCase(processor ports.ImageProcessor) *GetCapabilitiesUseCase {
+	return &GetCapabilitiesUseCase{processor: processor}
+}
+
+// Execute returns the formats images can be uploaded in and rendered to.
+func (uc *GetCapabilitiesUseCase) Execute() ports.ProcessorCapabilities {
+	return uc.processor.Capabilities()
+}
@@ -0,0 +1,53 @@
+package image
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+func TestCheckFormat(t *testing.T) {
+	processor := &fakeProcessor{caps: ports.ProcessorCapabilities{
+		Input:  []string{"jpeg", "png"},
+		Output: []string{"jpeg", "png", "tiff"},
+	}}
+	tests := []struct {
+		format *string
+		ok     bool
+	}{
+		{nil, true},
+		{stringPtr("jpeg"), true},
+		{stringPtr("jpg"), true},
+		{stringPtr("tif"), true},
+		{stringPtr("avif"), false},
+		{stringPtr("heic"), false},
+	}
+	for _, tt := range tests {
+		name := "none"
+		if tt.format != nil {
+			name = *tt.format
+		}
+		t.Run(name, func(t *testing.T) {
+			err := checkFormat(processor, &image.TransformationSpec{Format: tt.format})
+			if tt.ok {
+				assert.NoError(t, err)
+				return
+			}
+			var formatErr *UnsupportedFormatError
+			require.True(t, errors.As(err, &formatErr))
+			assert.Equal(t, *tt.format, formatErr.Format)
+			assert.Equal(t, []string{"jpeg", "png", "tiff"}, formatErr.Available)
+			assert.ErrorIs(t, err, ports.ErrUnsupportedOperation)
+			assert.True(t, isSpecError(err), "unsupported formats are never retried")
+		})
+	}
+}
+
+func TestCheckFormatWithoutProcessor(t *testing.T) {
+	assert.NoError(t, checkFormat(nil, &image.TransformationSpec{Format: stringPtr("avif")}))
+}
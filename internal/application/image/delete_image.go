@@ -0,0 +1,145 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+// DeleteImageUseCase moves an image of the owner to the trash, or removes
+// it with its variants, jobs and stored objects.
+type DeleteImageUseCase struct {
+	repo    ports.ImageRepository
+	cache   ports.Cache
+	cleaner *StorageCleaner
+	// retention is how long trashed images stay restorable; without one
+	// deletions are permanent.
+	retention time.Duration
+}
+
+func NewDeleteImageUseCase(repo ports.ImageRepository, cache ports.Cache, cleaner *StorageCleaner, retention time.Duration) *DeleteImageUseCase {
+	return &DeleteImageUseCase{
+		repo:      repo,
+		cache:     cache,
+		cleaner:   cleaner,
+		retention: retention,
+	}
+}
+
+type DeleteImageInput struct {
+	ImageID image.ImageID
+	OwnerID user.UserID
+	// Permanent skips the trash; it also removes images already in it.
+	Permanent bool
+}
+
+// Execute reports images of other owners as ErrImageNotFound. A permanent
+// deletion is done once the rows are; stored objects that cannot be removed
+// right away are left to the StorageCleaner's background retries.
+func (uc *DeleteImageUseCase) Execute(ctx context.Context, input DeleteImageInput) error {
+	if input.Permanent || uc.retention <= 0 {
+		return uc.remove(ctx, input)
+	}
+
+	img, err := ownedImage(ctx, uc.repo, input.ImageID, input.OwnerID)
+	if err != nil {
+		return err
+	}
+	trashed, err := uc.repo.Trash(ctx, img.ID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !trashed {
+		return fmt.Errorf("%w: %s", ErrImageNotFound, img.ID)
+	}
+	_ = uc.cache.Delete(ctx, fmt.Sprintf("image:%s", img.ID))
+	return nil
+}
+
+func (uc *DeleteImageUseCase) remove(ctx context.Context, input DeleteImageInput) error {
+	// 1. Check the caller owns the image, in the trash or not
+	img, err := uc.repo.GetByID(ctx, input.ImageID)
+	if err == nil && img == nil {
+		img, err = uc.repo.GetTrashed(ctx, input.ImageID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get image: %w", err)
+	}
+	if img == nil || img.OwnerID != input.OwnerID {
+		return fmt.Errorf("%w: %s", ErrImageNotFound, input.ImageID)
+	}
+
+	// 2. Delete the rows; variants and jobs cascade
+	if err := uc.repo.Delete(ctx, img.ID); err != nil {
+		return err
+	}
+	_ = uc.cache.Delete(ctx, fmt.Sprintf("image:%s", img.ID))
+
+	// 3. Delete the objects. A failure to record retries is counted by the
+	// cleaner; it does not undo the deletion the caller asked for.
+	_ = uc.cleaner.Remove(ctx, storedObjects(img))
+	return nil
+}
+
+// storedObjects lists the storage keys of an image and its variants.
+func storedObjects(img *image.Image) []string {
+	keys := make([]string, 0, len(img.Variants)+1)
+	keys = append(keys, img.OriginalKey)
+	for _, v := range img.Variants {
+		keys = append(keys, v.VariantKey)
+	}
+	return keys
+}
+
+// DeleteVariantUseCase removes one variant of an image of the owner and its
+// stored object. Requesting the same spec again renders it anew.
+type DeleteVariantUseCase struct {
+	repo    ports.ImageRepository
+	cache   ports.Cache
+	cleaner *StorageCleaner
+}
+
+func NewDeleteVariantUseCase(repo ports.ImageRepository, cache ports.Cache, cleaner *StorageCleaner) *DeleteVariantUseCase {
+	return &DeleteVariantUseCase{
+		repo:    repo,
+		cache:   cache,
+		cleaner: cleaner,
+	}
+}
+
+type DeleteVariantInput struct {
+	ImageID   image.ImageID
+	OwnerID   user.UserID
+	VariantID uuid.UUID
+}
+
+// Execute reports images of other owners as ErrImageNotFound and unknown
+// variants as ErrVariantNotFound.
+func (uc *DeleteVariantUseCase) Execute(ctx context.Context, input DeleteVariantInput) error {
+	img, err := ownedImage(ctx, uc.repo, input.ImageID, input.OwnerID)
+	if err != nil {
+		return err
+	}
+	variant := img.FindVariant(input.VariantID)
+	if variant == nil {
+		return ErrVariantNotFound
+	}
+
+	deleted, err := uc.repo.DeleteVariant(ctx, img.ID, variant.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrVariantNotFound
+	}
+	_ = uc.cache.Delete(ctx, fmt.Sprintf("image:%s", img.ID))
+
+	_ = uc.cleaner.Remove(ctx, []string{variant.VariantKey})
+	return nil
+}
@@ -0,0 +1,35 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+// Errors callers can map to responses with errors.Is. Lookups of resources
+// that belong to someone else fail with ErrNotFound, exactly like missing
+// ones, so IDs cannot be probed; ErrForbidden is kept for callers that
+// already know the resource exists, such as holders of a signed URL.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+)
+
+var ErrImageNotFound = fmt.Errorf("image %w", ErrNotFound)
+
+// ownedImage loads an image on behalf of its owner. Missing images and
+// images of other owners both fail with ErrImageNotFound.
+func ownedImage(ctx context.Context, repo ports.ImageRepository, id image.ImageID, ownerID user.UserID) (*image.Image, error) {
+	img, err := repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	if img == nil || img.OwnerID != ownerID {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, id)
+	}
+	return img, nil
+}
@@ -0,0 +1,195 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/domain/preset"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+// fakeImageRepository keeps images in memory. Methods the tests do not
+// use panic through the embedded nil interface.
+type fakeImageRepository struct {
+	ports.ImageRepository
+	images   map[image.ImageID]*image.Image
+	trashed  map[image.ImageID]*image.Image
+	purged   []image.ImageID
+	variants []*image.Variant
+}
+
+func (r *fakeImageRepository) GetByID(ctx context.Context, id image.ImageID) (*image.Image, error) {
+	return r.images[id], nil
+}
+
+func (r *fakeImageRepository) GetTrashed(ctx context.Context, id image.ImageID) (*image.Image, error) {
+	return r.trashed[id], nil
+}
+
+func (r *fakeImageRepository) ListExpiredTrash(ctx context.Context, deletedBefore time.Time, limit int) ([]image.ImageID, error) {
+	var ids []image.ImageID
+	for id := range r.trashed {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (r *fakeImageRepository) Purge(ctx context.Context, id image.ImageID, deletedBefore time.Time) (bool, error) {
+	if r.trashed[id] == nil {
+		return false, nil
+	}
+	delete(r.trashed, id)
+	r.purged = append(r.purged, id)
+	return true, nil
+}
+
+// GetVariantBySpecHash finds variants by hash alone; tests keep the
+// variants of one image.
+func (r *fakeImageRepository) GetVariantBySpecHash(ctx context.Context, imageID image.ImageID, specHash string) (*image.Variant, error) {
+	for _, v := range r.variants {
+		if v.SpecHash == specHash {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeImageRepository) ListStaleVariants(ctx context.Context, version int, after uuid.UUID, limit int) ([]*image.Variant, error) {
+	var stale []*image.Variant
+	for _, v := range r.variants {
+		if v.SpecHashVersion < version && v.Spec != nil && v.ID.String() > after.String() && len(stale) < limit {
+			stale = append(stale, v)
+		}
+	}
+	return stale, nil
+}
+
+func (r *fakeImageRepository) RehashVariant(ctx context.Context, id uuid.UUID, specHash string, version int) (bool, error) {
+	if slices.ContainsFunc(r.variants, func(v *image.Variant) bool { return v.SpecHash == specHash && v.ID != id }) {
+		return false, nil
+	}
+	for _, v := range r.variants {
+		if v.ID == id {
+			v.SpecHash, v.SpecHashVersion = specHash, version
+		}
+	}
+	return true, nil
+}
+
+// fakeJobRepository keeps jobs in memory.
+type fakeJobRepository struct {
+	jobs map[job.JobID]*job.Job
+}
+
+func (r *fakeJobRepository) Save(ctx context.Context, j *job.Job) error {
+	r.jobs[j.ID] = j
+	return nil
+}
+
+func (r *fakeJobRepository) GetByID(ctx context.Context, id job.JobID) (*job.Job, error) {
+	return r.jobs[id], nil
+}
+
+func (r *fakeJobRepository) Update(ctx context.Context, j *job.Job) error {
+	r.jobs[j.ID] = j
+	return nil
+}
+
+// fakeStorage deletes objects, failing for the keys in failing.
+type fakeStorage struct {
+	ports.ObjectStorage
+	failing map[string]bool
+	deleted []string
+}
+
+func (s *fakeStorage) Delete(ctx context.Context, key string) error {
+	if s.failing[key] {
+		return errors.New("storage unavailable")
+	}
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+// fakeDeletions records scheduled storage deletions, or fails with err.
+type fakeDeletions struct {
+	ports.StorageDeletionRepository
+	err  error
+	keys []string
+}
+
+func (d *fakeDeletions) Add(ctx context.Context, keys []string, notBefore time.Time) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.keys = append(d.keys, keys...)
+	return nil
+}
+
+type fakeCache struct {
+	ports.Cache
+}
+
+func (fakeCache) Delete(ctx context.Context, key string) error {
+	return nil
+}
+
+// fakePresetRepository keeps the latest version of each preset by name.
+type fakePresetRepository struct {
+	ports.PresetRepository
+	presets map[string]*preset.Preset
+}
+
+func (r *fakePresetRepository) Create(ctx context.Context, p *preset.Preset) (bool, error) {
+	if r.presets == nil {
+		r.presets = map[string]*preset.Preset{}
+	}
+	if r.presets[p.Name] != nil {
+		return false, nil
+	}
+	r.presets[p.Name] = p
+	return true, nil
+}
+
+func (r *fakePresetRepository) Get(ctx context.Context, ownerID user.UserID, name string, version int) (*preset.Preset, error) {
+	p := r.presets[name]
+	if p == nil || p.OwnerID != ownerID || (version > 0 && version != p.Version) {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func (r *fakePresetRepository) List(ctx context.Context, ownerID user.UserID) ([]*preset.Preset, error) {
+	var presets []*preset.Preset
+	for _, p := range r.presets {
+		if p.OwnerID == ownerID {
+			presets = append(presets, p)
+		}
+	}
+	return presets, nil
+}
+
+func (r *fakePresetRepository) SetEager(ctx context.Context, id preset.PresetID, eager bool) error {
+	for _, p := range r.presets {
+		if p.ID == id {
+			p.Eager = eager
+		}
+	}
+	return nil
+}
+
+// fakeProcessor reports caps; other methods panic unless a test sets them.
+type fakeProcessor struct {
+	ports.ImageProcessor
+	caps ports.ProcessorCapabilities
+}
+
+func (p *fakeProcessor) Capabilities() ports.ProcessorCapabilities {
+	return p.caps
+}
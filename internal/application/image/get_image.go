@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
 	"image-processing-service/internal/ports"
 )
 
@@ -22,30 +23,46 @@ func NewGetImageUseCase(repo ports.ImageRepository, cache ports.Cache) *GetImage
 	}
 }
 
-func (uc *GetImageUseCase) Execute(ctx context.Context, id image.ImageID) (*image.Image, error) {
+type GetImageInput struct {
+	ImageID image.ImageID
+	OwnerID user.UserID
+}
+
+// Execute returns an image of the owner. Missing and trashed images and
+// images of other owners are all reported as ErrImageNotFound, cached or
+// not; trashing, restoring and purging evict the cache entry.
+func (uc *GetImageUseCase) Execute(ctx context.Context, input GetImageInput) (*image.Image, error) {
 	// 1. Try Cache
-	cacheKey := fmt.Sprintf("image:%s", id)
+	cacheKey := fmt.Sprintf("image:%s", input.ImageID)
 	cachedVal, err := uc.cache.Get(ctx, cacheKey)
 	if err == nil && cachedVal != "" {
 		var cachedImage image.Image
 		if jsonErr := json.Unmarshal([]byte(cachedVal), &cachedImage); jsonErr == nil {
+			if cachedImage.OwnerID != input.OwnerID || cachedImage.IsTrashed() {
+				return nil, fmt.Errorf("%w: %s", ErrImageNotFound, input.ImageID)
+			}
 			return &cachedImage, nil
 		}
 	}
 
 	// 2. Fetch from Repo
-	img, err := uc.repo.GetByID(ctx, id)
+	img, err := uc.repo.GetByID(ctx, input.ImageID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get image: %w", err)
 	}
+	if img == nil {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, input.ImageID)
+	}
 
 	// 3. Set Cache (Async or Sync? Sync for now, but don't block too long)
-	if img != nil {
-		if bytes, err := json.Marshal(img); err == nil {
-			// TTL: 1 hour? Configurable?
-			_ = uc.cache.Set(ctx, cacheKey, string(bytes), 1*time.Hour)
-		}
+	if bytes, err := json.Marshal(img); err == nil {
+		// TTL: 1 hour? Configurable?
+		_ = uc.cache.Set(ctx, cacheKey, string(bytes), 1*time.Hour)
 	}
 
+	// 4. Hide images of other owners; the cache entry is shared by all callers
+	if img.OwnerID != input.OwnerID {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, input.ImageID)
+	}
 	return img, nil
 }
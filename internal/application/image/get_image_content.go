@@ -0,0 +1,98 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+var ErrVariantNotFound = fmt.Errorf("variant %w", ErrNotFound)
+
+// GetImageContentUseCase opens the stored bytes of an original or one of
+// its variants for the image's owner.
+type GetImageContentUseCase struct {
+	repo    ports.ImageRepository
+	storage ports.ObjectStorage
+}
+
+func NewGetImageContentUseCase(repo ports.ImageRepository, storage ports.ObjectStorage) *GetImageContentUseCase {
+	return &GetImageContentUseCase{
+		repo:    repo,
+		storage: storage,
+	}
+}
+
+type GetImageContentInput struct {
+	ImageID image.ImageID
+	OwnerID user.UserID
+	// VariantID selects a variant; uuid.Nil selects the original.
+	VariantID uuid.UUID
+	// IfNoneMatch is the client's If-None-Match header, if any.
+	IfNoneMatch string
+}
+
+type ImageContentOutput struct {
+	// Content streams the bytes. It is nil when IfNoneMatch matched ETag;
+	// otherwise the caller closes it.
+	Content  io.ReadCloser
+	MimeType string
+	Size     int64
+	// Filename is the uploaded name, with the variant's extension for variants.
+	Filename string
+	// ETag is the quoted image ID for originals and the quoted spec hash
+	// for variants, both of which never change their bytes.
+	ETag      string
+	CreatedAt time.Time
+}
+
+// Execute reports images of other owners as ErrImageNotFound and unknown
+// variants as ErrVariantNotFound.
+func (uc *GetImageContentUseCase) Execute(ctx context.Context, input GetImageContentInput) (*ImageContentOutput, error) {
+	// 1. Check the caller owns the image
+	img, err := ownedImage(ctx, uc.repo, input.ImageID, input.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Pick the object
+	out := &ImageContentOutput{
+		MimeType:  img.MimeType,
+		Size:      img.Size,
+		Filename:  img.Filename,
+		ETag:      fmt.Sprintf("%q", img.ID),
+		CreatedAt: img.CreatedAt,
+	}
+	key := img.OriginalKey
+	if input.VariantID != uuid.Nil {
+		v := img.FindVariant(input.VariantID)
+		if v == nil {
+			return nil, ErrVariantNotFound
+		}
+		key = v.VariantKey
+		out.MimeType = v.MimeType
+		out.Size = v.Size
+		out.Filename = strings.TrimSuffix(img.Filename, filepath.Ext(img.Filename)) + extensionFor(v.MimeType)
+		out.ETag = fmt.Sprintf("%q", v.SpecHash)
+		out.CreatedAt = v.CreatedAt
+	}
+
+	if etagMatches(input.IfNoneMatch, out.ETag) {
+		return out, nil
+	}
+
+	// 3. Open the stored bytes
+	out.Content, err = uc.storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image content: %w", err)
+	}
+	return out, nil
+}
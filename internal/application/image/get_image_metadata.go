@@ -0,0 +1,38 @@
+package image
+
+import (
+	"context"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+type GetImageMetadataUseCase struct {
+	repo ports.ImageRepository
+}
+
+func NewGetImageMetadataUseCase(repo ports.ImageRepository) *GetImageMetadataUseCase {
+	return &GetImageMetadataUseCase{repo: repo}
+}
+
+type GetImageMetadataInput struct {
+	ImageID image.ImageID
+	OwnerID user.UserID
+}
+
+// Execute returns the metadata read from an image of the owner on upload,
+// GPS position included. It is empty for images that record none or were
+// uploaded before metadata was read. Images of other owners are reported
+// as ErrImageNotFound. The image cache is bypassed as it does not hold
+// the metadata.
+func (uc *GetImageMetadataUseCase) Execute(ctx context.Context, input GetImageMetadataInput) (*image.PhotoMetadata, error) {
+	img, err := ownedImage(ctx, uc.repo, input.ImageID, input.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if img.Photo == nil {
+		return &image.PhotoMetadata{}, nil
+	}
+	return img.Photo, nil
+}
@@ -0,0 +1,42 @@
+package image
+
+import (
+	"context"
+	"fmt"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+var ErrJobNotFound = fmt.Errorf("job %w", ErrNotFound)
+
+type GetJobUseCase struct {
+	jobRepo ports.JobRepository
+}
+
+func NewGetJobUseCase(jobRepo ports.JobRepository) *GetJobUseCase {
+	return &GetJobUseCase{
+		jobRepo: jobRepo,
+	}
+}
+
+type GetJobInput struct {
+	ImageID image.ImageID
+	JobID   job.JobID
+	OwnerID user.UserID
+}
+
+// Execute returns the job if it belongs to the given image and owner.
+// Mismatches are reported as ErrJobNotFound so job IDs cannot be probed.
+func (uc *GetJobUseCase) Execute(ctx context.Context, input GetJobInput) (*job.Job, error) {
+	j, err := uc.jobRepo.GetByID(ctx, input.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if j == nil || j.ImageID != input.ImageID || j.OwnerID != input.OwnerID {
+		return nil, ErrJobNotFound
+	}
+	return j, nil
+}
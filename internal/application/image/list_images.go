@@ -2,10 +2,11 @@ package image
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 
 	"image-processing-service/internal/domain/image"
-	"image-processing-service/internal/domain/user"
 	"image-processing-service/internal/ports"
 )
 
@@ -22,31 +23,76 @@ func NewListImagesUseCase(repo ports.ImageRepository, cache ports.Cache) *ListIm
 }
 
 type ListImagesInput struct {
-	OwnerID user.UserID
-	Offset  int
-	Limit   int
+	// Criteria selects the images; its After is taken from Cursor.
+	Criteria image.ListCriteria
+	// Cursor is a NextCursor of an earlier page, if any.
+	Cursor string
 }
 
 type ListImagesOutput struct {
 	Images []*image.Image
 	Total  int
+	// NextCursor continues the listing; it is empty on the last page.
+	NextCursor string
 }
 
+// Execute reports bad criteria and cursors as image.ErrInvalidListCriteria.
 func (uc *ListImagesUseCase) Execute(ctx context.Context, input ListImagesInput) (*ListImagesOutput, error) {
-	if input.Limit <= 0 {
-		input.Limit = 10
+	criteria := input.Criteria
+	if criteria.SortBy == "" {
+		criteria.SortBy = image.SortByCreatedAt
+		criteria.Descending = true
 	}
-	if input.Limit > 100 {
-		input.Limit = 100
+	if criteria.Limit <= 0 {
+		criteria.Limit = 10
+	}
+	if criteria.Limit > 100 {
+		criteria.Limit = 100
+	}
+	if input.Cursor != "" {
+		cursor, err := decodeListCursor(input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		criteria.After = cursor
+	}
+	if err := criteria.Validate(); err != nil {
+		return nil, err
 	}
 
-	images, total, err := uc.repo.List(ctx, input.OwnerID, input.Offset, input.Limit)
+	// Fetch one more image than asked for to learn whether a next page exists
+	pageSize := criteria.Limit
+	criteria.Limit++
+	images, total, err := uc.repo.List(ctx, criteria)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list images: %w", err)
 	}
 
-	return &ListImagesOutput{
+	out := &ListImagesOutput{
 		Images: images,
 		Total:  total,
-	}, nil
+	}
+	if len(images) > pageSize {
+		out.Images = images[:pageSize]
+		out.NextCursor = encodeListCursor(criteria.CursorAfter(out.Images[pageSize-1]))
+	}
+	return out, nil
+}
+
+// Cursors are opaque to clients: base64url-encoded JSON of the position.
+func encodeListCursor(c *image.ListCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(s string) (*image.ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", image.ErrInvalidListCriteria)
+	}
+	var c image.ListCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", image.ErrInvalidListCriteria)
+	}
+	return &c, nil
 }
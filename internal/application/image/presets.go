@@ -0,0 +1,250 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/preset"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+var (
+	ErrPresetNotFound = fmt.Errorf("preset %w", ErrNotFound)
+	ErrPresetExists   = errors.New("preset already exists")
+	// ErrPresetNameReserved is returned for names of built-in batch
+	// presets, which a user preset would be confused with.
+	ErrPresetNameReserved = errors.New("preset name is reserved for a built-in batch preset")
+	// ErrTooManyEagerPresets is returned when an owner would have more
+	// eager presets than a batch may hold, which would fail every upload.
+	ErrTooManyEagerPresets = errors.New("too many eager presets")
+	// ErrPresetConflict is returned when a preset is revised or deleted
+	// while another revision of it is being saved.
+	ErrPresetConflict = errors.New("preset was changed concurrently")
+)
+
+// PresetRef names a preset of the owner to transform with; Version 0 picks
+// the latest version. The zero PresetRef names none.
+type PresetRef struct {
+	Name    string
+	Version int
+}
+
+// applyPreset returns spec as is when ref names no preset. Otherwise it
+// returns the preset's spec with the fields set in spec overriding it, which
+// then hashes exactly like the equivalent inline spec.
+func applyPreset(ctx context.Context, repo ports.PresetRepository, ownerID user.UserID, ref PresetRef, spec image.TransformationSpec) (image.TransformationSpec, error) {
+	if ref.Name == "" {
+		return spec, nil
+	}
+	p, err := repo.Get(ctx, ownerID, ref.Name, ref.Version)
+	if err != nil {
+		return spec, fmt.Errorf("failed to get preset: %w", err)
+	}
+	if p == nil {
+		return spec, presetNotFound(ref.Name, ref.Version)
+	}
+	return p.Spec.Override(spec), nil
+}
+
+func presetNotFound(name string, version int) error {
+	if version > 0 {
+		return fmt.Errorf("%w: %s version %d", ErrPresetNotFound, name, version)
+	}
+	return fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+}
+
+// checkEagerLimit fails with ErrTooManyEagerPresets when the owner already
+// has maxEager eager presets other than name. A maxEager of 0 means no limit.
+func checkEagerLimit(ctx context.Context, repo ports.PresetRepository, ownerID user.UserID, name string, maxEager int) error {
+	if maxEager <= 0 {
+		return nil
+	}
+	presets, err := repo.List(ctx, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to list presets: %w", err)
+	}
+	eager := 0
+	for _, p := range presets {
+		if p.Eager && p.Name != name {
+			eager++
+		}
+	}
+	if eager >= maxEager {
+		return fmt.Errorf("%w: at most %d presets can be eager", ErrTooManyEagerPresets, maxEager)
+	}
+	return nil
+}
+
+type CreatePresetUseCase struct {
+	repo     ports.PresetRepository
+	maxEager int
+}
+
+// NewCreatePresetUseCase limits the owner's eager presets to maxEager, the
+// size of a batch, as they are all queued on upload.
+func NewCreatePresetUseCase(repo ports.PresetRepository, maxEager int) *CreatePresetUseCase {
+	return &CreatePresetUseCase{repo: repo, maxEager: maxEager}
+}
+
+type PresetInput struct {
+	OwnerID user.UserID
+	Name    string
+	Spec    image.TransformationSpec
+	Eager   bool
+}
+
+// Execute saves version 1 of a new preset. Names already used by the owner
+// fail with ErrPresetExists and those of built-in batch presets with
+// ErrPresetNameReserved. An eager preset beyond the limit fails with
+// ErrTooManyEagerPresets.
+func (uc *CreatePresetUseCase) Execute(ctx context.Context, input PresetInput) (*preset.Preset, error) {
+	if _, ok := batchPresets[input.Name]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrPresetNameReserved, input.Name)
+	}
+	if input.Eager {
+		if err := checkEagerLimit(ctx, uc.repo, input.OwnerID, input.Name, uc.maxEager); err != nil {
+			return nil, err
+		}
+	}
+	p, err := preset.New(input.OwnerID, input.Name, input.Spec)
+	if err != nil {
+		return nil, err
+	}
+	p.Eager = input.Eager
+
+	created, err := uc.repo.Create(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: %s", ErrPresetExists, input.Name)
+	}
+	return p, nil
+}
+
+type UpdatePresetUseCase struct {
+	repo     ports.PresetRepository
+	maxEager int
+}
+
+// NewUpdatePresetUseCase limits the owner's eager presets like
+// NewCreatePresetUseCase.
+func NewUpdatePresetUseCase(repo ports.PresetRepository, maxEager int) *UpdatePresetUseCase {
+	return &UpdatePresetUseCase{repo: repo, maxEager: maxEager}
+}
+
+// UpdatePresetInput changes the fields that are not nil.
+type UpdatePresetInput struct {
+	OwnerID user.UserID
+	Name    string
+	Spec    *image.TransformationSpec
+	Eager   *bool
+}
+
+// Execute sets Eager and saves the spec as the next version of an existing
+// preset. A spec equal to the latest version's is not saved again.
+func (uc *UpdatePresetUseCase) Execute(ctx context.Context, input UpdatePresetInput) (*preset.Preset, error) {
+	p, err := uc.repo.Get(ctx, input.OwnerID, input.Name, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preset: %w", err)
+	}
+	if p == nil {
+		return nil, presetNotFound(input.Name, 0)
+	}
+
+	if input.Eager != nil && *input.Eager != p.Eager {
+		if *input.Eager {
+			if err := checkEagerLimit(ctx, uc.repo, input.OwnerID, input.Name, uc.maxEager); err != nil {
+				return nil, err
+			}
+		}
+		if err := uc.repo.SetEager(ctx, p.ID, *input.Eager); err != nil {
+			return nil, err
+		}
+		p.Eager = *input.Eager
+	}
+
+	if input.Spec == nil || p.Spec.String() == input.Spec.String() {
+		return p, nil
+	}
+
+	p.Revise(*input.Spec)
+	revised, err := uc.repo.Revise(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !revised {
+		return nil, fmt.Errorf("%w: %s", ErrPresetConflict, input.Name)
+	}
+	return p, nil
+}
+
+type GetPresetUseCase struct {
+	repo ports.PresetRepository
+}
+
+func NewGetPresetUseCase(repo ports.PresetRepository) *GetPresetUseCase {
+	return &GetPresetUseCase{repo: repo}
+}
+
+// Execute returns a version of a preset of the owner, the latest for
+// version 0.
+func (uc *GetPresetUseCase) Execute(ctx context.Context, ownerID user.UserID, name string, version int) (*preset.Preset, error) {
+	p, err := uc.repo.Get(ctx, ownerID, name, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preset: %w", err)
+	}
+	if p == nil {
+		return nil, presetNotFound(name, version)
+	}
+	return p, nil
+}
+
+// Versions returns every version of a preset of the owner, newest first.
+func (uc *GetPresetUseCase) Versions(ctx context.Context, ownerID user.UserID, name string) ([]*preset.Preset, error) {
+	versions, err := uc.repo.ListVersions(ctx, ownerID, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, presetNotFound(name, 0)
+	}
+	return versions, nil
+}
+
+type ListPresetsUseCase struct {
+	repo ports.PresetRepository
+}
+
+func NewListPresetsUseCase(repo ports.PresetRepository) *ListPresetsUseCase {
+	return &ListPresetsUseCase{repo: repo}
+}
+
+// Execute returns the latest version of each preset of the owner by name.
+func (uc *ListPresetsUseCase) Execute(ctx context.Context, ownerID user.UserID) ([]*preset.Preset, error) {
+	return uc.repo.List(ctx, ownerID)
+}
+
+type DeletePresetUseCase struct {
+	repo ports.PresetRepository
+}
+
+func NewDeletePresetUseCase(repo ports.PresetRepository) *DeletePresetUseCase {
+	return &DeletePresetUseCase{repo: repo}
+}
+
+// Execute deletes a preset of the owner with all its versions. Variants
+// rendered from it are kept, as they do not depend on the preset.
+func (uc *DeletePresetUseCase) Execute(ctx context.Context, ownerID user.UserID, name string) error {
+	deleted, err := uc.repo.Delete(ctx, ownerID, name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return presetNotFound(name, 0)
+	}
+	return nil
+}
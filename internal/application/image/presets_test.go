@@ -0,0 +1,56 @@
+package image
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/domain/image"
+)
+
+func TestCreatePresetRejectsBuiltInNames(t *testing.T) {
+	uc := NewCreatePresetUseCase(&fakePresetRepository{}, 0)
+	for name := range batchPresets {
+		t.Run(name, func(t *testing.T) {
+			_, err := uc.Execute(context.Background(), PresetInput{OwnerID: "user-1", Name: name})
+			assert.ErrorIs(t, err, ErrPresetNameReserved)
+		})
+	}
+}
+
+func TestCreatePresetRejectsTakenNames(t *testing.T) {
+	uc := NewCreatePresetUseCase(&fakePresetRepository{}, 0)
+	input := PresetInput{OwnerID: "user-1", Name: "avatar", Spec: image.TransformationSpec{Format: stringPtr("webp")}}
+
+	p, err := uc.Execute(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.Version)
+
+	_, err = uc.Execute(context.Background(), input)
+	assert.ErrorIs(t, err, ErrPresetExists)
+}
+
+func TestEagerPresetsAreLimitedToABatch(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakePresetRepository{}
+	create := NewCreatePresetUseCase(repo, 2)
+	update := NewUpdatePresetUseCase(repo, 2)
+
+	for _, name := range []string{"a", "b"} {
+		_, err := create.Execute(ctx, PresetInput{OwnerID: "user-1", Name: name, Eager: true})
+		require.NoError(t, err)
+	}
+	_, err := create.Execute(ctx, PresetInput{OwnerID: "user-1", Name: "c", Eager: true})
+	assert.ErrorIs(t, err, ErrTooManyEagerPresets)
+
+	_, err = create.Execute(ctx, PresetInput{OwnerID: "user-1", Name: "c"})
+	require.NoError(t, err)
+	eager := true
+	_, err = update.Execute(ctx, UpdatePresetInput{OwnerID: "user-1", Name: "c", Eager: &eager})
+	assert.ErrorIs(t, err, ErrTooManyEagerPresets)
+
+	_, err = update.Execute(ctx, UpdatePresetInput{OwnerID: "user-1", Name: "a", Eager: &eager})
+	assert.NoError(t, err, "a preset that is already eager does not count against itself")
+}
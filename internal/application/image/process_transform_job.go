@@ -0,0 +1,179 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"image-processing-service/internal/adapters/monitoring"
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/ports"
+)
+
+var ErrInvalidJob = errors.New("invalid transform job")
+
+// ProcessTransformJobUseCase executes a TransformJob consumed from the queue
+// and keeps its persisted status in step.
+type ProcessTransformJobUseCase struct {
+	imageRepo ports.ImageRepository
+	jobRepo   ports.JobRepository
+	pipeline  *TransformPipeline
+}
+
+func NewProcessTransformJobUseCase(
+	imageRepo ports.ImageRepository,
+	jobRepo ports.JobRepository,
+	storage ports.ObjectStorage,
+	processor ports.ImageProcessor,
+) *ProcessTransformJobUseCase {
+	return &ProcessTransformJobUseCase{
+		imageRepo: imageRepo,
+		jobRepo:   jobRepo,
+		pipeline:  NewTransformPipeline(imageRepo, storage, processor),
+	}
+}
+
+// Execute renders the variant of the job and returns it. A redelivered job
+// that already succeeded returns its variant, or no output once the
+// variant has been deleted.
+func (uc *ProcessTransformJobUseCase) Execute(ctx context.Context, msg *ports.TransformJob) (*TransformOutput, error) {
+	if msg == nil || msg.ImageID == "" || msg.Spec == nil {
+		return nil, fmt.Errorf("%w: %w", ports.ErrPermanentFailure, ErrInvalidJob)
+	}
+
+	// 1. Load the status record. Messages published before job tracking
+	// existed have none and are processed untracked.
+	record, err := uc.jobRepo.GetByID(ctx, job.JobID(msg.JobID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+	if record != nil {
+		if record.Status == job.StatusSucceeded {
+			return uc.completed(ctx, record)
+		}
+		record.Start()
+		if err := uc.jobRepo.Update(ctx, record); err != nil {
+			return nil, fmt.Errorf("failed to mark job running: %w", err)
+		}
+	}
+
+	variant, err := uc.run(ctx, msg)
+	if err != nil {
+		monitoring.RecordTransformation("worker", "failure")
+		if record != nil {
+			if errors.Is(err, ports.ErrPermanentFailure) || msg.MaxAttempts == 0 || msg.IsFinalAttempt() {
+				record.Fail(err)
+			} else {
+				record.Retry(err)
+			}
+			_ = uc.jobRepo.Update(ctx, record)
+		}
+		return nil, err
+	}
+
+	monitoring.RecordTransformation("worker", "success")
+	if record != nil {
+		record.Succeed(variant.ID)
+		if err := uc.jobRepo.Update(ctx, record); err != nil {
+			return nil, fmt.Errorf("failed to mark job succeeded: %w", err)
+		}
+	}
+	return toTransformOutput(variant), nil
+}
+
+func (uc *ProcessTransformJobUseCase) run(ctx context.Context, msg *ports.TransformJob) (*image.Variant, error) {
+	imageID := image.ImageID(msg.ImageID)
+
+	// 1. Hash the spec. The hash in the message is not used, as it may
+	// follow an older SpecHashVersion or be missing.
+	specHash, err := msg.Spec.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash transformation spec: %w", err)
+	}
+
+	// 2. Skip work if the variant was produced in the meantime
+	existing, err := findVariant(ctx, uc.imageRepo, imageID, msg.Spec, specHash)
+	if err == nil && existing != nil {
+		return existing, nil
+	}
+
+	// 3. Load the original
+	img, err := uc.imageRepo.GetByID(ctx, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image metadata: %w", err)
+	}
+	if img == nil {
+		return nil, fmt.Errorf("%w: image not found: %s", ports.ErrPermanentFailure, imageID)
+	}
+
+	// 4. Render and store the variant
+	variant, err := uc.pipeline.Run(ctx, img, msg.Spec, specHash)
+	if isSpecError(err) {
+		return nil, fmt.Errorf("%w: %w", ports.ErrPermanentFailure, err)
+	}
+	return variant, err
+}
+
+// completed answers a redelivered message for a job that already finished.
+// Its variant may have been deleted since, by its owner or a purge; the job
+// stays succeeded and nothing is rendered again.
+func (uc *ProcessTransformJobUseCase) completed(ctx context.Context, record *job.Job) (*TransformOutput, error) {
+	specHash, err := record.Spec.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash transformation spec: %w", err)
+	}
+	existing, err := findVariant(ctx, uc.imageRepo, record.ImageID, record.Spec, specHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant: %w", err)
+	}
+	if existing == nil {
+		return nil, nil
+	}
+	return toTransformOutput(existing), nil
+}
+
+// ExecuteBatch processes every item of a batched message as its own job and
+// returns how many succeeded. Items that already finished are skipped, so a
+// redelivered batch only repeats the jobs that failed transiently. The error
+// is permanent only when every failed item failed permanently.
+func (uc *ProcessTransformJobUseCase) ExecuteBatch(ctx context.Context, msg *ports.TransformJob) (int, error) {
+	if msg == nil || msg.ImageID == "" || !msg.IsBatch() {
+		return 0, fmt.Errorf("%w: %w", ports.ErrPermanentFailure, ErrInvalidJob)
+	}
+
+	succeeded, failed, permanent := 0, 0, 0
+	var lastErr error
+	for _, it := range msg.Items {
+		record, err := uc.jobRepo.GetByID(ctx, job.JobID(it.JobID))
+		if err == nil && record != nil && record.Status == job.StatusFailed {
+			failed++
+			permanent++
+			continue
+		}
+
+		item := *msg
+		item.Items = nil
+		item.JobID = it.JobID
+		item.Spec = it.Spec
+		item.SpecHash = it.SpecHash
+		if _, err := uc.Execute(ctx, &item); err != nil {
+			failed++
+			if errors.Is(err, ports.ErrPermanentFailure) {
+				permanent++
+			}
+			lastErr = err
+			continue
+		}
+		succeeded++
+	}
+
+	switch {
+	case failed == 0:
+		return succeeded, nil
+	case failed == permanent:
+		return succeeded, fmt.Errorf("%w: %d of %d batch items failed", ports.ErrPermanentFailure, failed, len(msg.Items))
+	default:
+		return succeeded, fmt.Errorf("%d of %d batch items failed, last error: %v", failed, len(msg.Items), lastErr)
+	}
+}
@@ -0,0 +1,88 @@
+package image
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/ports"
+)
+
+// queuedJob records a job for spec whose message carries specHash, as a
+// publisher of another SpecHashVersion would have sent it.
+func queuedJob(t *testing.T, jobs *fakeJobRepository, spec *image.TransformationSpec, specHash string) (*job.Job, *ports.TransformJob) {
+	hash, err := spec.Hash()
+	require.NoError(t, err)
+	record, err := job.New("image-1", "owner-1", spec, hash)
+	require.NoError(t, err)
+	require.NoError(t, jobs.Save(context.Background(), record))
+	return record, &ports.TransformJob{
+		JobID:    string(record.ID),
+		ImageID:  "image-1",
+		Spec:     spec,
+		SpecHash: specHash,
+	}
+}
+
+func TestProcessTransformJobHashesTheSpec(t *testing.T) {
+	format, rotate := "png", 360
+	spec := &image.TransformationSpec{Format: &format, Rotate: &rotate}
+	hash, err := spec.Hash()
+	require.NoError(t, err)
+	legacy, err := spec.LegacyHash()
+	require.NoError(t, err)
+	require.NotEqual(t, hash, legacy)
+
+	tests := []struct {
+		name        string
+		msgHash     string
+		variantHash string
+	}{
+		{"stale hash in the message", legacy, hash},
+		{"no hash in the message", "", hash},
+		{"variant stored under the legacy hash", hash, legacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variant := &image.Variant{ID: uuid.New(), VariantKey: "variants/1", SpecHash: tt.variantHash}
+			repo := &fakeImageRepository{variants: []*image.Variant{variant}}
+			jobs := &fakeJobRepository{jobs: map[job.JobID]*job.Job{}}
+			record, msg := queuedJob(t, jobs, spec, tt.msgHash)
+
+			out, err := NewProcessTransformJobUseCase(repo, jobs, nil, nil).Execute(context.Background(), msg)
+			require.NoError(t, err)
+			assert.Equal(t, variant.ID.String(), out.ID, "the stored variant is found, not rendered again")
+			assert.Equal(t, job.StatusSucceeded, record.Status)
+		})
+	}
+}
+
+func TestProcessTransformJobCompletedWithoutVariant(t *testing.T) {
+	format := "png"
+	spec := &image.TransformationSpec{Format: &format}
+	hash, err := spec.Hash()
+	require.NoError(t, err)
+	variant := &image.Variant{ID: uuid.New(), VariantKey: "variants/1", SpecHash: hash}
+	repo := &fakeImageRepository{variants: []*image.Variant{variant}}
+	jobs := &fakeJobRepository{jobs: map[job.JobID]*job.Job{}}
+	record, msg := queuedJob(t, jobs, spec, hash)
+	record.Succeed(variant.ID)
+	uc := NewProcessTransformJobUseCase(repo, jobs, nil, nil)
+
+	out, err := uc.Execute(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, variant.ID.String(), out.ID, "a redelivery answers the variant")
+
+	// The variant is deleted, by its owner or a purge, before a redelivery.
+	repo.variants = nil
+	out, err = uc.Execute(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Nil(t, out)
+	assert.Equal(t, job.StatusSucceeded, record.Status)
+	assert.Equal(t, &variant.ID, record.VariantID)
+}
@@ -0,0 +1,65 @@
+package image
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// RehashVariantsUseCase moves the stored variants whose spec is known to the
+// current SpecHashVersion, so that they are found by the hash of their
+// normalized spec. Variants stored without a spec keep their hash and are
+// still found by the version 1 hash of the exact spec they were rendered
+// from.
+type RehashVariantsUseCase struct {
+	repo ports.ImageRepository
+}
+
+func NewRehashVariantsUseCase(repo ports.ImageRepository) *RehashVariantsUseCase {
+	return &RehashVariantsUseCase{repo: repo}
+}
+
+type RehashOutput struct {
+	Rehashed int
+	// Duplicates counts variants whose normalized spec had already been
+	// rendered under the new hash. They keep their old hash and stay
+	// reachable by it; deleting them frees their storage.
+	Duplicates int
+}
+
+// Execute rehashes every stale variant, batchSize at a time. It can be
+// stopped and run again at any point.
+func (uc *RehashVariantsUseCase) Execute(ctx context.Context, batchSize int) (*RehashOutput, error) {
+	out := &RehashOutput{}
+	after := uuid.Nil
+	for {
+		variants, err := uc.repo.ListStaleVariants(ctx, image.SpecHashVersion, after, batchSize)
+		if err != nil {
+			return out, err
+		}
+		if len(variants) == 0 {
+			return out, nil
+		}
+
+		for _, v := range variants {
+			after = v.ID
+			hash, err := v.Spec.Hash()
+			if err != nil {
+				return out, fmt.Errorf("failed to hash transformation spec: %w", err)
+			}
+			ok, err := uc.repo.RehashVariant(ctx, v.ID, hash, image.SpecHashVersion)
+			if err != nil {
+				return out, err
+			}
+			if ok {
+				out.Rehashed++
+			} else {
+				out.Duplicates++
+			}
+		}
+	}
+}
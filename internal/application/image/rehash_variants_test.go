@@ -0,0 +1,53 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/domain/image"
+)
+
+func TestRehashVariants(t *testing.T) {
+	spec := func(format string, rotate int) *image.TransformationSpec {
+		return &image.TransformationSpec{Format: &format, Rotate: &rotate}
+	}
+	legacy := func(s *image.TransformationSpec) string {
+		h, err := s.LegacyHash()
+		require.NoError(t, err)
+		return h
+	}
+	current := func(s *image.TransformationSpec) string {
+		h, err := s.Hash()
+		require.NoError(t, err)
+		return h
+	}
+
+	// IDs in the order the repository lists them
+	id := func(n int) uuid.UUID {
+		return uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", n))
+	}
+
+	stale := &image.Variant{ID: id(1), Spec: spec("png", 360), SpecHashVersion: 1}
+	stale.SpecHash = legacy(stale.Spec)
+	duplicate := &image.Variant{ID: id(2), Spec: spec("webp", 0), SpecHashVersion: 1}
+	duplicate.SpecHash = legacy(duplicate.Spec)
+	rendered := &image.Variant{ID: id(3), Spec: spec("webp", 360), SpecHashVersion: image.SpecHashVersion}
+	rendered.SpecHash = current(rendered.Spec)
+	unknown := &image.Variant{ID: id(4), SpecHash: "v1", SpecHashVersion: 1}
+	repo := &fakeImageRepository{variants: []*image.Variant{stale, duplicate, rendered, unknown}}
+
+	out, err := NewRehashVariantsUseCase(repo).Execute(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, &RehashOutput{Rehashed: 1, Duplicates: 1}, out)
+
+	assert.Equal(t, current(stale.Spec), stale.SpecHash)
+	assert.Equal(t, image.SpecHashVersion, stale.SpecHashVersion)
+	assert.Equal(t, legacy(duplicate.Spec), duplicate.SpecHash, "the normalized spec was rendered already")
+	assert.Equal(t, 1, duplicate.SpecHashVersion)
+	assert.Equal(t, "v1", unknown.SpecHash, "variants without a spec keep their hash")
+}
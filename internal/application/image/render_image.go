@@ -0,0 +1,108 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+// RenderImageUseCase serves the bytes of a variant, rendering it through
+// TransformImageSyncUseCase when no variant with the spec's hash exists yet.
+type RenderImageUseCase struct {
+	imageRepo ports.ImageRepository
+	storage   ports.ObjectStorage
+	transform *TransformImageSyncUseCase
+}
+
+func NewRenderImageUseCase(
+	imageRepo ports.ImageRepository,
+	storage ports.ObjectStorage,
+	transform *TransformImageSyncUseCase,
+) *RenderImageUseCase {
+	return &RenderImageUseCase{
+		imageRepo: imageRepo,
+		storage:   storage,
+		transform: transform,
+	}
+}
+
+type RenderInput struct {
+	ImageID image.ImageID
+	OwnerID user.UserID
+	Spec    image.TransformationSpec
+	// IfNoneMatch is the client's If-None-Match header, if any.
+	IfNoneMatch string
+	// Accept is the client's Accept header, which format auto picks from.
+	Accept string
+}
+
+type RenderOutput struct {
+	Variant *TransformOutput
+	// ETag identifies the variant's bytes; it is the quoted spec hash.
+	ETag string
+	// Content streams the variant. It is nil when IfNoneMatch matched ETag
+	// and the client's copy is current; otherwise the caller closes it.
+	Content io.ReadCloser
+}
+
+// Execute returns the variant of the image for the spec. Images of other
+// owners are reported as ErrImageNotFound.
+func (uc *RenderImageUseCase) Execute(ctx context.Context, input RenderInput) (*RenderOutput, error) {
+	return uc.serve(ctx, input.ImageID, input.OwnerID, input.Spec, input.IfNoneMatch, input.Accept)
+}
+
+// serveShared renders on behalf of the image's owner, for callers the owner
+// has granted access to, such as holders of a signed URL.
+func (uc *RenderImageUseCase) serveShared(ctx context.Context, imageID image.ImageID, spec image.TransformationSpec, ifNoneMatch, accept string) (*RenderOutput, error) {
+	img, err := uc.imageRepo.GetByID(ctx, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	if img == nil {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, imageID)
+	}
+	return uc.serve(ctx, imageID, img.OwnerID, spec, ifNoneMatch, accept)
+}
+
+func (uc *RenderImageUseCase) serve(ctx context.Context, imageID image.ImageID, ownerID user.UserID, spec image.TransformationSpec, ifNoneMatch, accept string) (*RenderOutput, error) {
+	variant, err := uc.transform.Execute(ctx, SyncTransformInput{
+		ImageID: imageID,
+		OwnerID: ownerID,
+		Spec:    spec,
+		Accept:  accept,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &RenderOutput{
+		Variant: variant,
+		ETag:    fmt.Sprintf("%q", variant.SpecHash),
+	}
+	if etagMatches(ifNoneMatch, out.ETag) {
+		return out, nil
+	}
+
+	out.Content, err = uc.storage.Get(ctx, variant.VariantKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open variant: %w", err)
+	}
+	return out, nil
+}
+
+// etagMatches applies the weak comparison of If-None-Match to a header
+// listing entity tags, or "*".
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
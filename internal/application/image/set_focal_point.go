@@ -0,0 +1,49 @@
+package image
+
+import (
+	"context"
+	"fmt"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+type SetFocalPointUseCase struct {
+	repo  ports.ImageRepository
+	cache ports.Cache
+}
+
+func NewSetFocalPointUseCase(repo ports.ImageRepository, cache ports.Cache) *SetFocalPointUseCase {
+	return &SetFocalPointUseCase{
+		repo:  repo,
+		cache: cache,
+	}
+}
+
+type SetFocalPointInput struct {
+	ImageID    image.ImageID
+	OwnerID    user.UserID
+	FocalPoint *image.FocalPoint
+}
+
+// Execute stores the focal point of an image of the owner; a nil point
+// clears it. Images of other owners are reported as ErrImageNotFound.
+// Existing variants are kept: their hashes include the focal point they
+// were rendered with, so later requests render new ones.
+func (uc *SetFocalPointUseCase) Execute(ctx context.Context, input SetFocalPointInput) (*image.Image, error) {
+	img, err := ownedImage(ctx, uc.repo, input.ImageID, input.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := img.SetFocalPoint(input.FocalPoint); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.Update(ctx, img); err != nil {
+		return nil, fmt.Errorf("failed to save focal point: %w", err)
+	}
+
+	_ = uc.cache.Delete(ctx, fmt.Sprintf("image:%s", img.ID))
+	return img, nil
+}
@@ -0,0 +1,128 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+var (
+	ErrSigningDisabled  = errors.New("signed URLs are not configured")
+	ErrSignatureExpired = fmt.Errorf("%w: signed URL has expired", ErrForbidden)
+	ErrInvalidTTL       = errors.New("invalid signed URL lifetime")
+)
+
+// renderURLPayload is what a signed render URL vouches for: the image, the
+// spec as parsed from the URL and the expiry.
+func renderURLPayload(imageID image.ImageID, spec *image.TransformationSpec, expiresAt time.Time) string {
+	return fmt.Sprintf("%s\n%s\n%d", imageID, spec.String(), expiresAt.Unix())
+}
+
+// SignRenderURLUseCase mints signatures for public render URLs of the
+// caller's images.
+type SignRenderURLUseCase struct {
+	imageRepo  ports.ImageRepository
+	signer     ports.URLSigner
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+// NewSignRenderURLUseCase builds the use case; a nil signer disables it.
+func NewSignRenderURLUseCase(imageRepo ports.ImageRepository, signer ports.URLSigner, defaultTTL, maxTTL time.Duration) *SignRenderURLUseCase {
+	return &SignRenderURLUseCase{
+		imageRepo:  imageRepo,
+		signer:     signer,
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+	}
+}
+
+type SignRenderURLInput struct {
+	ImageID image.ImageID
+	OwnerID user.UserID
+	Spec    image.TransformationSpec
+	// TTL is how long the URL stays valid; zero means the default.
+	TTL time.Duration
+}
+
+type SignRenderURLOutput struct {
+	KeyID     string
+	Signature string
+	ExpiresAt time.Time
+}
+
+func (uc *SignRenderURLUseCase) Execute(ctx context.Context, input SignRenderURLInput) (*SignRenderURLOutput, error) {
+	if uc.signer == nil {
+		return nil, ErrSigningDisabled
+	}
+
+	ttl := input.TTL
+	if ttl == 0 {
+		ttl = uc.defaultTTL
+	}
+	if ttl < time.Second || ttl > uc.maxTTL {
+		return nil, fmt.Errorf("%w: must be between 1s and %s", ErrInvalidTTL, uc.maxTTL)
+	}
+
+	img, err := ownedImage(ctx, uc.imageRepo, input.ImageID, input.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second).UTC()
+	keyID, signature := uc.signer.Sign(renderURLPayload(img.ID, &input.Spec, expiresAt))
+	return &SignRenderURLOutput{
+		KeyID:     keyID,
+		Signature: signature,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// RenderSignedImageUseCase serves render URLs minted by
+// SignRenderURLUseCase to anonymous callers.
+type RenderSignedImageUseCase struct {
+	signer ports.URLSigner
+	render *RenderImageUseCase
+}
+
+// NewRenderSignedImageUseCase builds the use case; a nil signer disables it.
+func NewRenderSignedImageUseCase(signer ports.URLSigner, render *RenderImageUseCase) *RenderSignedImageUseCase {
+	return &RenderSignedImageUseCase{
+		signer: signer,
+		render: render,
+	}
+}
+
+type RenderSignedInput struct {
+	ImageID     image.ImageID
+	Spec        image.TransformationSpec
+	ExpiresAt   time.Time
+	KeyID       string
+	Signature   string
+	IfNoneMatch string
+	Accept      string
+}
+
+// Execute verifies the signature and expiry before rendering on behalf of
+// the image's owner. Forged and tampered URLs fail with ErrForbidden
+// wrapping ports.ErrInvalidSignature, expired ones with ErrSignatureExpired.
+func (uc *RenderSignedImageUseCase) Execute(ctx context.Context, input RenderSignedInput) (*RenderOutput, error) {
+	if uc.signer == nil {
+		return nil, ErrSigningDisabled
+	}
+
+	payload := renderURLPayload(input.ImageID, &input.Spec, input.ExpiresAt)
+	if err := uc.signer.Verify(payload, input.KeyID, input.Signature); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrForbidden, err)
+	}
+	if !time.Now().Before(input.ExpiresAt) {
+		return nil, ErrSignatureExpired
+	}
+
+	return uc.render.serveShared(ctx, input.ImageID, input.Spec, input.IfNoneMatch, input.Accept)
+}
@@ -0,0 +1,104 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"image-processing-service/internal/adapters/monitoring"
+	"image-processing-service/internal/ports"
+)
+
+// storageDeletionLease hides a claimed deletion from other processes while
+// one of them calls the storage backend.
+const storageDeletionLease = 5 * time.Minute
+
+// StorageCleaner deletes the stored objects of deleted images and variants.
+// Objects the storage backend fails to delete are recorded and retried in
+// the background by RetryDue, so a partial failure does not orphan them.
+type StorageCleaner struct {
+	storage   ports.ObjectStorage
+	deletions ports.StorageDeletionRepository
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func NewStorageCleaner(storage ports.ObjectStorage, deletions ports.StorageDeletionRepository, baseDelay, maxDelay time.Duration) *StorageCleaner {
+	return &StorageCleaner{
+		storage:   storage,
+		deletions: deletions,
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+	}
+}
+
+// Remove deletes the objects and schedules the ones that fail for a retry.
+// It only fails when the retries cannot be recorded.
+func (c *StorageCleaner) Remove(ctx context.Context, keys []string) error {
+	var failed []string
+	for _, key := range keys {
+		if err := c.storage.Delete(ctx, key); err != nil {
+			failed = append(failed, key)
+			continue
+		}
+		monitoring.RecordStorageDeletion("deleted")
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	if err := c.deletions.Add(ctx, failed, time.Now().Add(c.baseDelay)); err != nil {
+		for range failed {
+			monitoring.RecordStorageDeletion("orphaned")
+		}
+		return fmt.Errorf("failed to schedule %d storage deletions: %w", len(failed), err)
+	}
+	for range failed {
+		monitoring.RecordStorageDeletion("retry")
+	}
+	return nil
+}
+
+// RetryDue retries up to limit scheduled deletions that are due and returns
+// how many objects it deleted. Failures back off exponentially up to the
+// maximum delay and are retried until they succeed.
+func (c *StorageCleaner) RetryDue(ctx context.Context, limit int) (int, error) {
+	due, err := c.deletions.Claim(ctx, limit, storageDeletionLease)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, d := range due {
+		if err := c.storage.Delete(ctx, d.Key); err != nil {
+			monitoring.RecordStorageDeletion("retry")
+			next := time.Now().Add(c.backoff(d.Attempts))
+			if rerr := c.deletions.Reschedule(ctx, d.Key, next, err.Error()); rerr != nil {
+				return deleted, rerr
+			}
+			continue
+		}
+		monitoring.RecordStorageDeletion("deleted")
+		if err := c.deletions.Remove(ctx, d.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// backoff returns baseDelay * 2^attempts, capped at maxDelay. The first
+// attempt was made inline by Remove and is not counted.
+func (c *StorageCleaner) backoff(attempts int) time.Duration {
+	delay := c.baseDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for i := 0; i < attempts; i++ {
+		delay *= 2
+		if c.maxDelay > 0 && delay >= c.maxDelay {
+			return c.maxDelay
+		}
+	}
+	return delay
+}
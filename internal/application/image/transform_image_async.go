@@ -7,66 +7,110 @@ import (
 
 	"image-processing-service/internal/adapters/monitoring"
 	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/domain/user"
 	"image-processing-service/internal/ports"
-
-	"github.com/google/uuid"
 )
 
 type AsyncTransformOutput struct {
-	ID string
+	ID     string
+	Status job.Status
+	// Negotiated is set when format auto picked the format from the Accept
+	// header.
+	Negotiated bool
 }
 
 type AsyncTransformImageUseCase struct {
-	imageRepo ports.ImageRepository
-	queue     ports.Queue
+	imageRepo  ports.ImageRepository
+	presetRepo ports.PresetRepository
+	jobRepo    ports.JobRepository
+	queue      ports.Queue
+	processor  ports.ImageProcessor
+	auto       AutoOptions
 }
 
-func NewAsyncTransformImageUseCase(imageRepo ports.ImageRepository, queue ports.Queue) *AsyncTransformImageUseCase {
+// NewAsyncTransformImageUseCase takes the processor the workers render with
+// to reject output formats they cannot encode before queueing; auto enables
+// the auto values of specs.
+func NewAsyncTransformImageUseCase(imageRepo ports.ImageRepository, presetRepo ports.PresetRepository, jobRepo ports.JobRepository, queue ports.Queue, processor ports.ImageProcessor, auto AutoOptions) *AsyncTransformImageUseCase {
 	return &AsyncTransformImageUseCase{
-		imageRepo: imageRepo,
-		queue:     queue,
+		imageRepo:  imageRepo,
+		presetRepo: presetRepo,
+		jobRepo:    jobRepo,
+		queue:      queue,
+		processor:  processor,
+		auto:       auto,
 	}
 }
 
 type AsyncTransformInput struct {
 	ImageID image.ImageID
+	OwnerID user.UserID
 	Spec    image.TransformationSpec
+	// Preset, when set, is the base spec and Spec only overrides it.
+	Preset PresetRef
+	// Accept is the client's Accept header, which format auto picks from.
+	Accept string
 }
 
+// Execute queues a variant of an image of the owner. Images of other owners
+// are reported as ErrImageNotFound and unknown presets as ErrPresetNotFound.
+// The job records the spec the preset resolved to, so later revisions of
+// the preset do not affect it, and the format auto was negotiated to.
 func (uc *AsyncTransformImageUseCase) Execute(ctx context.Context, input AsyncTransformInput) (*AsyncTransformOutput, error) {
-	// 1. Validate Image exists
-	img, err := uc.imageRepo.GetByID(ctx, input.ImageID)
+	// 1. Validate the image exists and belongs to the caller
+	img, err := ownedImage(ctx, uc.imageRepo, input.ImageID, input.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Resolve the preset, the focal point and reject crops that cannot
+	// fit, unsupported formats and watermarks that reference someone else's
+	// image up front
+	spec, err := applyPreset(ctx, uc.presetRepo, input.OwnerID, input.Preset, input.Spec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get image: %w", err)
+		return nil, err
 	}
-	if img == nil {
-		return nil, fmt.Errorf("image not found: %s", input.ImageID)
+	negotiated := resolveAuto(img, &spec, uc.processor, input.Accept, uc.auto)
+	if err := prepareSpec(img, &spec, uc.processor); err != nil {
+		return nil, err
+	}
+	if _, err := resolveWatermarkImage(ctx, uc.imageRepo, img, &spec); err != nil {
+		return nil, err
 	}
 
-	// 2. Hash spec
-	specHash, err := input.Spec.Hash()
+	// 3. Hash spec
+	specHash, err := spec.Hash()
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash spec: %w", err)
 	}
 
-	// 3. Create Job ID
-	jobID := uuid.New().String()
+	// 4. Record the job so clients can poll it
+	j, err := job.New(img.ID, img.OwnerID, &spec, specHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	if err := uc.jobRepo.Save(ctx, j); err != nil {
+		return nil, fmt.Errorf("failed to record job: %w", err)
+	}
 
-	// 4. Publish to Queue
-	job := &ports.TransformJob{
-		JobID:     jobID,
+	// 5. Publish to Queue
+	msg := &ports.TransformJob{
+		JobID:     string(j.ID),
 		ImageID:   string(img.ID),
 		OwnerID:   string(img.OwnerID),
-		Spec:      &input.Spec,
+		Spec:      &spec,
 		SpecHash:  specHash,
 		CreatedAt: time.Now().UTC(),
 	}
 
-	if err := uc.queue.Publish(ctx, job); err != nil {
+	if err := uc.queue.Publish(ctx, msg); err != nil {
 		monitoring.RecordTransformation("async", "failure")
+		j.Fail(err)
+		_ = uc.jobRepo.Update(ctx, j)
 		return nil, fmt.Errorf("failed to publish job: %w", err)
 	}
 
 	monitoring.RecordTransformation("async", "success")
-	return &AsyncTransformOutput{ID: jobID}, nil
+	return &AsyncTransformOutput{ID: string(j.ID), Status: j.Status, Negotiated: negotiated}, nil
 }
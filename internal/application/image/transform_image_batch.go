@@ -0,0 +1,351 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"image-processing-service/internal/adapters/monitoring"
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+// ErrInvalidBatch is returned for batches that name no specs, too many specs
+// or an unknown preset.
+var ErrInvalidBatch = errors.New("invalid transformation batch")
+
+// batchPresets are the named sets of specs a batch can ask for instead of
+// listing them. Each call returns fresh specs as they are modified in place.
+var batchPresets = map[string]func() []BatchSpec{
+	// responsive renders WebP widths for srcset, never upscaling.
+	"responsive": func() []BatchSpec {
+		var specs []BatchSpec
+		for _, w := range []int{320, 640, 960, 1280, 1920} {
+			specs = append(specs, BatchSpec{Spec: image.TransformationSpec{
+				Resize: &image.ResizeSpec{Width: w, WithoutEnlargement: true},
+				Format: stringPtr("webp"),
+			}})
+		}
+		return specs
+	},
+	// thumbnails renders square JPEG thumbnails centred on the focal point.
+	"thumbnails": func() []BatchSpec {
+		var specs []BatchSpec
+		for _, size := range []int{64, 150, 300} {
+			specs = append(specs, BatchSpec{Spec: image.TransformationSpec{
+				Resize: &image.ResizeSpec{Width: size, Height: size, Fit: image.FitCover},
+				Format: stringPtr("jpeg"),
+			}})
+		}
+		return specs
+	},
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// BatchSpec is one spec of a batch; with a Preset, Spec only overrides the
+// preset's spec.
+type BatchSpec struct {
+	Spec   image.TransformationSpec
+	Preset PresetRef
+}
+
+type BatchTransformInput struct {
+	ImageID image.ImageID
+	OwnerID user.UserID
+	Specs   []BatchSpec
+	// Preset names a built-in set of specs; it is exclusive with Specs.
+	Preset string
+	// Async queues the unique specs as one grouped message instead of
+	// rendering them before returning.
+	Async bool
+	// Accept is the client's Accept header, which format auto picks from.
+	Accept string
+}
+
+// BatchItemResult reports one spec of a batch. Specs hashing the same as an
+// earlier one share its outcome and point at it with DuplicateOf.
+type BatchItemResult struct {
+	Index       int              `json:"index"`
+	SpecHash    string           `json:"spec_hash,omitempty"`
+	Status      job.Status       `json:"status"`
+	Variant     *TransformOutput `json:"variant,omitempty"`
+	JobID       string           `json:"job_id,omitempty"`
+	DuplicateOf *int             `json:"duplicate_of,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+type BatchTransformOutput struct {
+	// Results holds one entry per requested spec, in request order.
+	Results []BatchItemResult
+	// Negotiated is set when format auto picked the format of a spec from
+	// the Accept header.
+	Negotiated bool
+}
+
+// TransformImageBatchUseCase renders or queues several specs of one image.
+// Sync batches render on a bounded pool of goroutines; async batches record
+// a job per unique spec and publish them in a single queue message.
+type TransformImageBatchUseCase struct {
+	imageRepo  ports.ImageRepository
+	presetRepo ports.PresetRepository
+	jobRepo    ports.JobRepository
+	queue      ports.Queue
+	transform  *TransformImageSyncUseCase
+	workers    int
+	maxSpecs   int
+}
+
+func NewTransformImageBatchUseCase(
+	imageRepo ports.ImageRepository,
+	presetRepo ports.PresetRepository,
+	jobRepo ports.JobRepository,
+	queue ports.Queue,
+	transform *TransformImageSyncUseCase,
+	workers, maxSpecs int,
+) *TransformImageBatchUseCase {
+	if workers < 1 {
+		workers = 1
+	}
+	return &TransformImageBatchUseCase{
+		imageRepo:  imageRepo,
+		presetRepo: presetRepo,
+		jobRepo:    jobRepo,
+		queue:      queue,
+		transform:  transform,
+		workers:    workers,
+		maxSpecs:   maxSpecs,
+	}
+}
+
+// batchItem is a unique spec of a batch and its outcome.
+type batchItem struct {
+	spec   image.TransformationSpec
+	result BatchItemResult
+}
+
+// Execute reports images of other owners as ErrImageNotFound and malformed
+// batches as ErrInvalidBatch. Specs that cannot be rendered or name an
+// unknown preset fail on their own and are reported in their result rather
+// than failing the batch.
+func (uc *TransformImageBatchUseCase) Execute(ctx context.Context, input BatchTransformInput) (*BatchTransformOutput, error) {
+	// 1. Resolve and bound the specs
+	specs, err := uc.resolveSpecs(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Check the caller owns the image
+	img, err := ownedImage(ctx, uc.imageRepo, input.ImageID, input.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Resolve, prepare and hash every spec, keeping the first of each hash
+	out := &BatchTransformOutput{Results: make([]BatchItemResult, len(specs))}
+	var unique []*batchItem
+	first := make(map[string]int)
+	duplicates := make(map[int]int)
+	for i := range specs {
+		spec, hash, negotiated, err := uc.prepare(ctx, img, specs[i], input)
+		out.Negotiated = out.Negotiated || negotiated
+		if err != nil {
+			out.Results[i] = failedItem(i, "", err)
+			continue
+		}
+		if j, ok := first[hash]; ok {
+			duplicates[i] = j
+			continue
+		}
+		first[hash] = i
+		unique = append(unique, &batchItem{
+			spec:   spec,
+			result: BatchItemResult{Index: i, SpecHash: hash},
+		})
+	}
+
+	// 4. Render or queue the unique specs
+	if input.Async {
+		err = uc.enqueue(ctx, img, unique)
+	} else {
+		uc.render(ctx, img, unique)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Report duplicates with the outcome of their first occurrence
+	for _, item := range unique {
+		out.Results[item.result.Index] = item.result
+	}
+	for i, j := range duplicates {
+		dup := out.Results[j]
+		dup.Index = i
+		dup.DuplicateOf = &j
+		out.Results[i] = dup
+	}
+	return out, nil
+}
+
+func (uc *TransformImageBatchUseCase) resolveSpecs(input BatchTransformInput) ([]BatchSpec, error) {
+	specs := input.Specs
+	if input.Preset != "" {
+		if len(specs) > 0 {
+			return nil, fmt.Errorf("%w: specs and preset are exclusive", ErrInvalidBatch)
+		}
+		preset, ok := batchPresets[input.Preset]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown preset %q", ErrInvalidBatch, input.Preset)
+		}
+		specs = preset()
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("%w: no specs given", ErrInvalidBatch)
+	}
+	if err := uc.checkSize(len(specs)); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// checkSize rejects batches of more than maxSpecs specs.
+func (uc *TransformImageBatchUseCase) checkSize(n int) error {
+	if uc.maxSpecs > 0 && n > uc.maxSpecs {
+		return fmt.Errorf("%w: %d specs exceed the limit of %d", ErrInvalidBatch, n, uc.maxSpecs)
+	}
+	return nil
+}
+
+// prepare resolves the preset, the auto values and the focal point, rejects
+// specs that can never render and returns the spec with its hash and
+// whether its format was negotiated. Async batches check watermarks up front
+// as the worker cannot report them to the client.
+func (uc *TransformImageBatchUseCase) prepare(ctx context.Context, img *image.Image, bs BatchSpec, input BatchTransformInput) (image.TransformationSpec, string, bool, error) {
+	spec, err := applyPreset(ctx, uc.presetRepo, img.OwnerID, bs.Preset, bs.Spec)
+	if err != nil {
+		return spec, "", false, err
+	}
+	processor := uc.transform.pipeline.processor
+	negotiated := resolveAuto(img, &spec, processor, input.Accept, uc.transform.auto)
+	if err := prepareSpec(img, &spec, processor); err != nil {
+		return spec, "", negotiated, err
+	}
+	if input.Async {
+		if _, err := resolveWatermarkImage(ctx, uc.imageRepo, img, &spec); err != nil {
+			return spec, "", negotiated, err
+		}
+	}
+	hash, err := spec.Hash()
+	if err != nil {
+		return spec, "", negotiated, fmt.Errorf("failed to hash transformation spec: %w", err)
+	}
+	return spec, hash, negotiated, nil
+}
+
+// render runs the sync transform of every item on at most uc.workers
+// goroutines.
+func (uc *TransformImageBatchUseCase) render(ctx context.Context, img *image.Image, items []*batchItem) {
+	work := make(chan *batchItem)
+	var wg sync.WaitGroup
+	for w := 0; w < min(uc.workers, len(items)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				variant, err := uc.transform.render(ctx, img, item.spec)
+				if err != nil {
+					item.result = failedItem(item.result.Index, item.result.SpecHash, err)
+					continue
+				}
+				item.result.Status = job.StatusSucceeded
+				item.result.Variant = variant
+			}
+		}()
+	}
+	for _, item := range items {
+		work <- item
+	}
+	close(work)
+	wg.Wait()
+}
+
+// enqueue records a job per item whose variant does not exist yet and
+// publishes them together. A failed publish fails every job and the batch.
+func (uc *TransformImageBatchUseCase) enqueue(ctx context.Context, img *image.Image, items []*batchItem) error {
+	// 1. Answer items already rendered and record jobs for the rest
+	var jobs []*job.Job
+	msg := &ports.TransformJob{
+		JobID:     uuid.New().String(),
+		ImageID:   string(img.ID),
+		OwnerID:   string(img.OwnerID),
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, item := range items {
+		existing, err := findVariant(ctx, uc.imageRepo, img.ID, &item.spec, item.result.SpecHash)
+		if err == nil && existing != nil {
+			item.result.Status = job.StatusSucceeded
+			item.result.Variant = toTransformOutput(existing)
+			continue
+		}
+
+		j, err := job.New(img.ID, img.OwnerID, &item.spec, item.result.SpecHash)
+		if err == nil {
+			err = uc.jobRepo.Save(ctx, j)
+		}
+		if err != nil {
+			item.result = failedItem(item.result.Index, item.result.SpecHash, fmt.Errorf("failed to record job: %w", err))
+			continue
+		}
+		jobs = append(jobs, j)
+		item.result.Status = j.Status
+		item.result.JobID = string(j.ID)
+		msg.Items = append(msg.Items, ports.TransformJobItem{
+			JobID:    string(j.ID),
+			Spec:     j.Spec,
+			SpecHash: j.SpecHash,
+		})
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	// 2. Publish the batch
+	if err := uc.queue.Publish(ctx, msg); err != nil {
+		for _, j := range jobs {
+			monitoring.RecordTransformation("async", "failure")
+			j.Fail(err)
+			_ = uc.jobRepo.Update(ctx, j)
+		}
+		return fmt.Errorf("failed to publish batch: %w", err)
+	}
+
+	for range jobs {
+		monitoring.RecordTransformation("async", "success")
+	}
+	return nil
+}
+
+// failedItem reports an item that failed with err. Specs that cannot be
+// rendered, unknown presets and invalid batches are explained; anything
+// else is internal and reported as such, as it may carry details of the
+// database or storage.
+func failedItem(index int, specHash string, err error) BatchItemResult {
+	msg := "internal error"
+	if isSpecError(err) || errors.Is(err, ErrPresetNotFound) || errors.Is(err, ErrInvalidBatch) {
+		msg = err.Error()
+	}
+	return BatchItemResult{
+		Index:    index,
+		SpecHash: specHash,
+		Status:   job.StatusFailed,
+		Error:    msg,
+	}
+}
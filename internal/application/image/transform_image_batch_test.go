@@ -0,0 +1,36 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/ports"
+)
+
+func TestFailedItem(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"crop", fmt.Errorf("%w: 10x10 does not fit the 5x5 image", image.ErrCropOutOfBounds), "crop area is outside the image: 10x10 does not fit the 5x5 image"},
+		{"format", &UnsupportedFormatError{Format: "avif", Available: []string{"jpeg"}}, "operation not supported by image processor: avif output; available formats: jpeg"},
+		{"watermark", ErrWatermarkImageNotFound, "watermark image not found"},
+		{"preset", fmt.Errorf("%w: avatar-64", ErrPresetNotFound), "preset not found: avatar-64"},
+		{"batch", fmt.Errorf("%w: 30 specs exceed the limit of 20", ErrInvalidBatch), "invalid transformation batch: 30 specs exceed the limit of 20"},
+		{"database", fmt.Errorf("failed to record job: %w", errors.New(`pq: relation "transform_jobs" does not exist`)), "internal error"},
+		{"storage", fmt.Errorf("failed to upload variant: %w", errors.New("s3: access denied")), "internal error"},
+		{"permanent", fmt.Errorf("%w: image not found", ports.ErrPermanentFailure), "internal error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := failedItem(3, "hash", tt.err)
+			assert.Equal(t, BatchItemResult{Index: 3, SpecHash: "hash", Status: job.StatusFailed, Error: tt.want}, item)
+		})
+	}
+}
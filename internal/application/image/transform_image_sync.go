@@ -1,138 +1,116 @@
 package image
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 
 	"image-processing-service/internal/adapters/monitoring"
 	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
 	"image-processing-service/internal/ports"
 )
 
 type SyncTransformInput struct {
 	ImageID image.ImageID
+	OwnerID user.UserID
 	Spec    image.TransformationSpec
+	// Preset, when set, is the base spec and Spec only overrides it.
+	Preset PresetRef
+	// Accept is the client's Accept header, which format auto picks from.
+	Accept string
 }
 
 type TransformOutput struct {
 	ID         string `json:"id"`
 	VariantKey string `json:"variant_key"`
+	SpecHash   string `json:"spec_hash"`
 	MimeType   string `json:"mime_type"`
 	Width      int    `json:"width"`
 	Height     int    `json:"height"`
 	Size       int64  `json:"size"`
+	Quality    int    `json:"quality,omitempty"`
+	// Negotiated is set when format auto picked the format from the Accept
+	// header, so the response varies with it.
+	Negotiated bool `json:"-"`
 }
 
 type TransformImageSyncUseCase struct {
-	imageRepo ports.ImageRepository
-	storage   ports.ObjectStorage
-	processor ports.ImageProcessor
+	imageRepo  ports.ImageRepository
+	presetRepo ports.PresetRepository
+	pipeline   *TransformPipeline
+	auto       AutoOptions
 }
 
+// NewTransformImageSyncUseCase builds the use case; auto enables the auto
+// values of specs.
 func NewTransformImageSyncUseCase(
 	imageRepo ports.ImageRepository,
+	presetRepo ports.PresetRepository,
 	storage ports.ObjectStorage,
 	processor ports.ImageProcessor,
+	auto AutoOptions,
 ) *TransformImageSyncUseCase {
 	return &TransformImageSyncUseCase{
-		imageRepo: imageRepo,
-		storage:   storage,
-		processor: processor,
+		imageRepo:  imageRepo,
+		presetRepo: presetRepo,
+		pipeline:   NewTransformPipeline(imageRepo, storage, processor),
+		auto:       auto,
 	}
 }
 
+// Execute renders the variant of an image of the owner. Images of other
+// owners are reported as ErrImageNotFound and unknown presets as
+// ErrPresetNotFound.
 func (uc *TransformImageSyncUseCase) Execute(ctx context.Context, input SyncTransformInput) (*TransformOutput, error) {
-	// 1. Generate spec hash for deduplication
-	specHash, err := input.Spec.Hash()
+	// 1. Get original image metadata to find storage key
+	img, err := ownedImage(ctx, uc.imageRepo, input.ImageID, input.OwnerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash transformation spec: %w", err)
-	}
-
-	// 2. Check if variant already exists
-	existing, err := uc.imageRepo.GetVariantBySpecHash(ctx, input.ImageID, specHash)
-	if err == nil && existing != nil {
-		monitoring.RecordTransformation("sync", "success")
-		return &TransformOutput{
-			ID:         existing.ID.String(),
-			VariantKey: existing.VariantKey,
-			MimeType:   existing.MimeType,
-			Width:      existing.Width,
-			Height:     existing.Height,
-			Size:       existing.Size,
-		}, nil
+		return nil, err
 	}
 
-	// 3. Get original image metadata to find storage key
-	img, err := uc.imageRepo.GetByID(ctx, input.ImageID)
+	spec, err := applyPreset(ctx, uc.presetRepo, input.OwnerID, input.Preset, input.Spec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get image metadata: %w", err)
-	}
-	if img == nil {
-		return nil, fmt.Errorf("image not found: %s", input.ImageID)
+		return nil, err
 	}
-
-	// 4. Download original image
-	srcReader, err := uc.storage.Get(ctx, img.OriginalKey)
+	negotiated := resolveAuto(img, &spec, uc.pipeline.processor, input.Accept, uc.auto)
+	out, err := uc.render(ctx, img, spec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download original image: %w", err)
+		return nil, err
 	}
-	defer func() {
-		_ = srcReader.Close()
-	}()
+	out.Negotiated = negotiated
+	return out, nil
+}
 
-	// 5. Transform image
-	processed, err := uc.processor.Transform(ctx, srcReader, &input.Spec)
-	if err != nil {
-		monitoring.RecordTransformation("sync", "failure")
-		return nil, fmt.Errorf("transformation failed: %w", err)
+// render produces the variant of an already loaded and owner-checked image,
+// reusing a stored variant with the same spec hash. Format auto must have
+// been negotiated already.
+func (uc *TransformImageSyncUseCase) render(ctx context.Context, img *image.Image, spec image.TransformationSpec) (*TransformOutput, error) {
+	// 1. Resolve the focal point and reject crops that cannot fit and
+	// formats that cannot be encoded
+	if err := prepareSpec(img, &spec, uc.pipeline.processor); err != nil {
+		return nil, err
 	}
 
-	// 6. Upload variant
-	ext := uc.getExtension(processed.MimeType)
-	variantKey := fmt.Sprintf("variants/%s/%s%s", input.ImageID, specHash, ext)
-
-	_, err = uc.storage.Put(ctx, variantKey, bytes.NewReader(processed.Data), processed.MimeType, processed.Size)
+	// 2. Generate spec hash for deduplication
+	specHash, err := spec.Hash()
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload variant: %w", err)
+		return nil, fmt.Errorf("failed to hash transformation spec: %w", err)
 	}
 
-	return uc.saveAndReturn(ctx, img.ID, variantKey, specHash, processed)
-}
-
-func (uc *TransformImageSyncUseCase) saveAndReturn(ctx context.Context, imageID image.ImageID, key, hash string, processed *ports.ProcessedImage) (*TransformOutput, error) {
-	// 7. Save variant metadata
-	variant, err := image.NewVariant(key, hash, processed.MimeType, processed.Size, processed.Width, processed.Height)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create variant domain object: %w", err)
+	// 3. Check if variant already exists
+	existing, err := findVariant(ctx, uc.imageRepo, img.ID, &spec, specHash)
+	if err == nil && existing != nil {
+		monitoring.RecordTransformation("sync", "success")
+		return toTransformOutput(existing), nil
 	}
 
-	err = uc.imageRepo.SaveVariant(ctx, imageID, variant)
+	// 4. Render and store the variant
+	variant, err := uc.pipeline.Run(ctx, img, &spec, specHash)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save variant metadata: %w", err)
+		monitoring.RecordTransformation("sync", "failure")
+		return nil, err
 	}
 
-	return &TransformOutput{
-		ID:         variant.ID.String(),
-		VariantKey: variant.VariantKey,
-		MimeType:   variant.MimeType,
-		Width:      variant.Width,
-		Height:     variant.Height,
-		Size:       variant.Size,
-	}, nil
-}
-
-func (uc *TransformImageSyncUseCase) getExtension(mimeType string) string {
-	switch mimeType {
-	case "image/jpeg", "jpeg", "jpg":
-		return ".jpg"
-	case "image/png", "png":
-		return ".png"
-	case "image/webp", "webp":
-		return ".webp"
-	case "image/gif", "gif":
-		return ".gif"
-	default:
-		return ""
-	}
+	monitoring.RecordTransformation("sync", "success")
+	return toTransformOutput(variant), nil
 }
@@ -0,0 +1,186 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+// TransformPipeline renders a TransformationSpec against an image's original
+// and persists the result as a variant. Both the sync use case and the
+// background worker run through it so they produce identical variants.
+type TransformPipeline struct {
+	imageRepo ports.ImageRepository
+	storage   ports.ObjectStorage
+	processor ports.ImageProcessor
+}
+
+func NewTransformPipeline(
+	imageRepo ports.ImageRepository,
+	storage ports.ObjectStorage,
+	processor ports.ImageProcessor,
+) *TransformPipeline {
+	return &TransformPipeline{
+		imageRepo: imageRepo,
+		storage:   storage,
+		processor: processor,
+	}
+}
+
+// Run downloads the original, applies the spec, uploads the result to
+// variants/{imageID}/{specHash} and records the variant.
+func (p *TransformPipeline) Run(ctx context.Context, img *image.Image, spec *image.TransformationSpec, specHash string) (*image.Variant, error) {
+	// 1. Download original image
+	srcReader, err := p.storage.Get(ctx, img.OriginalKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download original image: %w", err)
+	}
+	defer func() {
+		_ = srcReader.Close()
+	}()
+
+	// 2. Load images the spec refers to
+	assets, err := p.loadAssets(ctx, img, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Transform image
+	processed, err := p.processor.Transform(ctx, srcReader, spec, assets)
+	if err != nil {
+		return nil, fmt.Errorf("transformation failed: %w", err)
+	}
+
+	// 4. Upload variant
+	variantKey := fmt.Sprintf("variants/%s/%s%s", img.ID, specHash, extensionFor(processed.MimeType))
+
+	_, err = p.storage.Put(ctx, variantKey, bytes.NewReader(processed.Data), processed.MimeType, processed.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload variant: %w", err)
+	}
+
+	// 5. Save variant metadata
+	variant, err := image.NewVariant(variantKey, specHash, processed.MimeType, processed.Size, processed.Width, processed.Height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create variant domain object: %w", err)
+	}
+	normalized := spec.Normalize()
+	variant.Spec = &normalized
+	variant.Quality = processed.Quality
+
+	if err := p.imageRepo.SaveVariant(ctx, img.ID, variant); err != nil {
+		return nil, fmt.Errorf("failed to save variant metadata: %w", err)
+	}
+
+	// A concurrent render may have won the insert; return the stored row so
+	// callers never reference a variant ID that was not persisted.
+	stored, err := p.imageRepo.GetVariantBySpecHash(ctx, img.ID, specHash)
+	if err == nil && stored != nil {
+		return stored, nil
+	}
+
+	return variant, nil
+}
+
+// findVariant returns the variant of img stored for spec, or nil. Variants
+// stored under the version 1 hash of the spec are found too until they are
+// rehashed.
+func findVariant(ctx context.Context, repo ports.ImageRepository, imageID image.ImageID, spec *image.TransformationSpec, specHash string) (*image.Variant, error) {
+	v, err := repo.GetVariantBySpecHash(ctx, imageID, specHash)
+	if err != nil || v != nil || spec == nil {
+		return v, err
+	}
+	legacy, err := spec.LegacyHash()
+	if err != nil || legacy == specHash {
+		return nil, err
+	}
+	return repo.GetVariantBySpecHash(ctx, imageID, legacy)
+}
+
+// prepareSpec completes spec with the image's focal point and rejects crops
+// that cannot fit the original, frames it does not have and formats the
+// processor cannot encode before anything is downloaded. It runs before the
+// spec is hashed.
+func prepareSpec(img *image.Image, spec *image.TransformationSpec, processor ports.ImageProcessor) error {
+	spec.ResolveFocalPoint(img.FocalPoint)
+	if err := checkFormat(processor, spec); err != nil {
+		return err
+	}
+	if err := spec.CheckFrame(img); err != nil {
+		return err
+	}
+	return spec.CheckBounds(img.Width, img.Height, img.Orientation)
+}
+
+// isSpecError reports whether err means the spec can never be rendered,
+// however often it is retried.
+func isSpecError(err error) bool {
+	return errors.Is(err, ErrWatermarkImageNotFound) ||
+		errors.Is(err, ports.ErrUnsupportedOperation) ||
+		errors.Is(err, image.ErrCropOutOfBounds) ||
+		errors.Is(err, image.ErrFrameOutOfRange) ||
+		errors.Is(err, image.ErrMaxBytesUnreachable)
+}
+
+// loadAssets fetches the watermark image, checking it belongs to img's owner.
+func (p *TransformPipeline) loadAssets(ctx context.Context, img *image.Image, spec *image.TransformationSpec) (*ports.TransformAssets, error) {
+	assets := &ports.TransformAssets{}
+
+	wm, err := resolveWatermarkImage(ctx, p.imageRepo, img, spec)
+	if err != nil || wm == nil {
+		return assets, err
+	}
+
+	reader, err := p.storage.Get(ctx, wm.OriginalKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download watermark image: %w", err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	assets.WatermarkImage, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watermark image: %w", err)
+	}
+	return assets, nil
+}
+
+func extensionFor(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg", "jpeg", "jpg":
+		return ".jpg"
+	case "image/png", "png":
+		return ".png"
+	case "image/webp", "webp":
+		return ".webp"
+	case "image/gif", "gif":
+		return ".gif"
+	case "image/avif", "avif":
+		return ".avif"
+	case "image/heif", "heif":
+		return ".heif"
+	case "image/tiff", "tiff":
+		return ".tiff"
+	default:
+		return ""
+	}
+}
+
+func toTransformOutput(v *image.Variant) *TransformOutput {
+	return &TransformOutput{
+		ID:         v.ID.String(),
+		VariantKey: v.VariantKey,
+		SpecHash:   v.SpecHash,
+		MimeType:   v.MimeType,
+		Width:      v.Width,
+		Height:     v.Height,
+		Size:       v.Size,
+		Quality:    v.Quality,
+	}
+}
@@ -0,0 +1,163 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+	"image-processing-service/internal/ports"
+)
+
+// TrashedImage is an image in the trash with the time it is purged.
+type TrashedImage struct {
+	*image.Image
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// ListTrashUseCase lists the owner's images that can still be restored.
+type ListTrashUseCase struct {
+	repo      ports.ImageRepository
+	retention time.Duration
+}
+
+func NewListTrashUseCase(repo ports.ImageRepository, retention time.Duration) *ListTrashUseCase {
+	return &ListTrashUseCase{
+		repo:      repo,
+		retention: retention,
+	}
+}
+
+type ListTrashInput struct {
+	OwnerID user.UserID
+	Offset  int
+	Limit   int
+}
+
+type ListTrashOutput struct {
+	Images []TrashedImage `json:"images"`
+	Total  int            `json:"total"`
+}
+
+func (uc *ListTrashUseCase) Execute(ctx context.Context, input ListTrashInput) (*ListTrashOutput, error) {
+	if input.Limit <= 0 {
+		input.Limit = 10
+	}
+	if input.Limit > 100 {
+		input.Limit = 100
+	}
+
+	images, total, err := uc.repo.ListTrashed(ctx, input.OwnerID, input.Offset, input.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	out := &ListTrashOutput{
+		Images: make([]TrashedImage, 0, len(images)),
+		Total:  total,
+	}
+	for _, img := range images {
+		out.Images = append(out.Images, TrashedImage{Image: img, PurgeAt: img.PurgeAt(uc.retention)})
+	}
+	return out, nil
+}
+
+// RestoreImageUseCase takes an image of the owner out of the trash.
+type RestoreImageUseCase struct {
+	repo      ports.ImageRepository
+	cache     ports.Cache
+	retention time.Duration
+}
+
+func NewRestoreImageUseCase(repo ports.ImageRepository, cache ports.Cache, retention time.Duration) *RestoreImageUseCase {
+	return &RestoreImageUseCase{
+		repo:      repo,
+		cache:     cache,
+		retention: retention,
+	}
+}
+
+type RestoreImageInput struct {
+	ImageID image.ImageID
+	OwnerID user.UserID
+}
+
+// Execute reports images that are not in the trash, that belong to other
+// owners or whose retention has passed as ErrImageNotFound.
+func (uc *RestoreImageUseCase) Execute(ctx context.Context, input RestoreImageInput) (*image.Image, error) {
+	img, err := uc.repo.GetTrashed(ctx, input.ImageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	if img == nil || img.OwnerID != input.OwnerID {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, input.ImageID)
+	}
+
+	restored, err := uc.repo.Restore(ctx, img.ID, time.Now().Add(-uc.retention))
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, input.ImageID)
+	}
+	_ = uc.cache.Delete(ctx, fmt.Sprintf("image:%s", img.ID))
+
+	img.DeletedAt = nil
+	return img, nil
+}
+
+// PurgeTrashUseCase permanently removes images whose retention in the
+// trash has passed, with their stored objects.
+type PurgeTrashUseCase struct {
+	repo      ports.ImageRepository
+	cache     ports.Cache
+	cleaner   *StorageCleaner
+	retention time.Duration
+}
+
+func NewPurgeTrashUseCase(repo ports.ImageRepository, cache ports.Cache, cleaner *StorageCleaner, retention time.Duration) *PurgeTrashUseCase {
+	return &PurgeTrashUseCase{
+		repo:      repo,
+		cache:     cache,
+		cleaner:   cleaner,
+		retention: retention,
+	}
+}
+
+// Execute purges up to limit expired images and returns how many it purged.
+// The stored objects go first, so a failure leaves the rows for the next
+// run rather than orphaning the objects. Expired images can no longer be
+// restored, so the objects are not needed anymore.
+func (uc *PurgeTrashUseCase) Execute(ctx context.Context, limit int) (int, error) {
+	cutoff := time.Now().Add(-uc.retention)
+	ids, err := uc.repo.ListExpiredTrash(ctx, cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		img, err := uc.repo.GetTrashed(ctx, id)
+		if err != nil {
+			return purged, fmt.Errorf("failed to get image: %w", err)
+		}
+		if img == nil {
+			continue
+		}
+
+		if err := uc.cleaner.Remove(ctx, storedObjects(img)); err != nil {
+			return purged, err
+		}
+		ok, err := uc.repo.Purge(ctx, id, cutoff)
+		if err != nil {
+			return purged, err
+		}
+		if !ok {
+			continue
+		}
+		_ = uc.cache.Delete(ctx, fmt.Sprintf("image:%s", id))
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,50 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/domain/image"
+)
+
+func trashedImage() *image.Image {
+	return &image.Image{
+		ID:          "img-1",
+		OriginalKey: "original",
+		Variants:    []image.Variant{{VariantKey: "variant"}},
+	}
+}
+
+func TestPurgeTrashRemovesObjectsThenRows(t *testing.T) {
+	repo := &fakeImageRepository{trashed: map[image.ImageID]*image.Image{"img-1": trashedImage()}}
+	storage := &fakeStorage{failing: map[string]bool{"variant": true}}
+	deletions := &fakeDeletions{}
+	cleaner := NewStorageCleaner(storage, deletions, time.Minute, time.Hour)
+	uc := NewPurgeTrashUseCase(repo, fakeCache{}, cleaner, time.Hour)
+
+	purged, err := uc.Execute(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Equal(t, []image.ImageID{"img-1"}, repo.purged)
+	assert.Equal(t, []string{"original"}, storage.deleted)
+	assert.Equal(t, []string{"variant"}, deletions.keys, "failed deletions are retried")
+}
+
+func TestPurgeTrashKeepsRowsWhenRetriesCannotBeRecorded(t *testing.T) {
+	repo := &fakeImageRepository{trashed: map[image.ImageID]*image.Image{"img-1": trashedImage()}}
+	storage := &fakeStorage{failing: map[string]bool{"variant": true}}
+	deletions := &fakeDeletions{err: errors.New("database unavailable")}
+	cleaner := NewStorageCleaner(storage, deletions, time.Minute, time.Hour)
+	uc := NewPurgeTrashUseCase(repo, fakeCache{}, cleaner, time.Hour)
+
+	purged, err := uc.Execute(context.Background(), 10)
+	assert.Error(t, err)
+	assert.Zero(t, purged)
+	assert.Empty(t, repo.purged, "the rows stay for the next run")
+	assert.Contains(t, repo.trashed, image.ImageID("img-1"))
+}
@@ -11,16 +11,26 @@ import (
 )
 
 type UploadImageUseCase struct {
-	imageRepo ports.ImageRepository
-	storage   ports.ObjectStorage
-	processor ports.ImageProcessor
+	imageRepo  ports.ImageRepository
+	presetRepo ports.PresetRepository
+	storage    ports.ObjectStorage
+	processor  ports.ImageProcessor
+	eager      *TransformImageBatchUseCase
 }
 
-func NewUploadImageUseCase(imageRepo ports.ImageRepository, storage ports.ObjectStorage, processor ports.ImageProcessor) *UploadImageUseCase {
+func NewUploadImageUseCase(
+	imageRepo ports.ImageRepository,
+	presetRepo ports.PresetRepository,
+	storage ports.ObjectStorage,
+	processor ports.ImageProcessor,
+	eager *TransformImageBatchUseCase,
+) *UploadImageUseCase {
 	return &UploadImageUseCase{
-		imageRepo: imageRepo,
-		storage:   storage,
-		processor: processor,
+		imageRepo:  imageRepo,
+		presetRepo: presetRepo,
+		storage:    storage,
+		processor:  processor,
+		eager:      eager,
 	}
 }
 
@@ -30,10 +40,32 @@ type UploadInput struct {
 	File     multipart.File
 	Size     int64
 	MimeType string
+	// Eager lists specs to queue once the image is saved, in addition to
+	// the owner's eager presets.
+	Eager []BatchSpec
 }
 
-func (uc *UploadImageUseCase) Execute(ctx context.Context, input UploadInput) (*image.Image, error) {
-	width, height := 0, 0
+type UploadOutput struct {
+	Image *image.Image
+	// Jobs reports the eager specs as an async batch would, in the order of
+	// Eager followed by the eager presets by name.
+	Jobs []BatchItemResult
+}
+
+// Execute stores the original and queues its eager specs. Too many eager
+// specs fail with ErrInvalidBatch before anything is stored; once the image
+// is saved, eager specs that cannot be queued are reported in Jobs and do
+// not fail the upload.
+func (uc *UploadImageUseCase) Execute(ctx context.Context, input UploadInput) (*UploadOutput, error) {
+	eager, err := uc.eagerSpecs(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height, orientation := 0, 0, 0
+	var hasAlpha *bool
+	var animation *image.Animation
+	var photo *image.PhotoMetadata
 	if uc.processor != nil {
 		meta, err := uc.processor.ExtractMetadata(ctx, input.File)
 		if err != nil {
@@ -41,7 +73,11 @@ func (uc *UploadImageUseCase) Execute(ctx context.Context, input UploadInput) (*
 		}
 		width = meta.Width
 		height = meta.Height
+		orientation = meta.Orientation
 		input.MimeType = meta.MimeType
+		hasAlpha = &meta.HasAlpha
+		animation = meta.Animation
+		photo = meta.Photo
 		if _, err := input.File.Seek(0, 0); err != nil {
 			return nil, fmt.Errorf("failed to reset file pointer: %w", err)
 		}
@@ -52,6 +88,11 @@ func (uc *UploadImageUseCase) Execute(ctx context.Context, input UploadInput) (*
 		return nil, err
 	}
 
+	tempImg.HasAlpha = hasAlpha
+	tempImg.Orientation = orientation
+	tempImg.Animation = animation
+	tempImg.Photo = photo
+
 	key := fmt.Sprintf("users/%s/images/%s/original", input.OwnerID, tempImg.ID)
 	tempImg.OriginalKey = key
 
@@ -64,5 +105,43 @@ func (uc *UploadImageUseCase) Execute(ctx context.Context, input UploadInput) (*
 		return nil, err
 	}
 
-	return tempImg, nil
+	out := &UploadOutput{Image: tempImg}
+	if len(eager) == 0 {
+		return out, nil
+	}
+	batch, err := uc.eager.Execute(ctx, BatchTransformInput{
+		ImageID: tempImg.ID,
+		OwnerID: tempImg.OwnerID,
+		Specs:   eager,
+		Async:   true,
+	})
+	if err != nil {
+		out.Jobs = make([]BatchItemResult, len(eager))
+		for i := range eager {
+			out.Jobs[i] = failedItem(i, "", err)
+		}
+		return out, nil
+	}
+	out.Jobs = batch.Results
+	return out, nil
+}
+
+// eagerSpecs returns the specs of the upload followed by the owner's eager
+// presets.
+func (uc *UploadImageUseCase) eagerSpecs(ctx context.Context, input UploadInput) ([]BatchSpec, error) {
+	presets, err := uc.presetRepo.List(ctx, input.OwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list presets: %w", err)
+	}
+
+	specs := input.Eager
+	for _, p := range presets {
+		if p.Eager {
+			specs = append(specs, BatchSpec{Preset: PresetRef{Name: p.Name, Version: p.Version}})
+		}
+	}
+	if err := uc.eager.checkSize(len(specs)); err != nil {
+		return nil, err
+	}
+	return specs, nil
 }
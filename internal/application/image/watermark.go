@@ -0,0 +1,31 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/ports"
+)
+
+var ErrWatermarkImageNotFound = errors.New("watermark image not found")
+
+// resolveWatermarkImage returns the image a spec's watermark overlays, or nil
+// for text-only or missing watermarks. Only images of img's owner qualify;
+// anything else is reported as not found so foreign image IDs cannot be probed.
+func resolveWatermarkImage(ctx context.Context, repo ports.ImageRepository, img *image.Image, spec *image.TransformationSpec) (*image.Image, error) {
+	if spec.Watermark == nil || spec.Watermark.ImageID == "" {
+		return nil, nil
+	}
+
+	id := image.ImageID(spec.Watermark.ImageID)
+	wm, err := repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watermark image: %w", err)
+	}
+	if wm == nil || wm.OwnerID != img.OwnerID {
+		return nil, fmt.Errorf("%w: %s", ErrWatermarkImageNotFound, id)
+	}
+	return wm, nil
+}
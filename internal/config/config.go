@@ -13,7 +13,11 @@ type Config struct {
 	Upstash    UpstashConfig
 	Cloudinary CloudinaryConfig
 	CloudAMQP  CloudAMQPConfig
+	Queue      QueueConfig
+	Processor  ProcessorConfig
 	JWT        JWTConfig
+	Signing    SigningConfig
+	Cleanup    CleanupConfig
 	Limits     LimitsConfig
 }
 
@@ -49,10 +53,43 @@ type CloudinaryConfig struct {
 }
 
 type CloudAMQPConfig struct {
-	URL           string
-	QueueName     string
-	QueueDurable  bool
-	PrefetchCount int
+	URL             string
+	QueueName       string
+	QueueDurable    bool
+	PrefetchCount   int
+	MaxAttempts     int
+	RetryBaseDelay  time.Duration
+	RetryMaxDelay   time.Duration
+	DeadLetterQueue string
+}
+
+// Queue drivers selectable through QUEUE_DRIVER.
+const (
+	QueueDriverCloudAMQP = "cloudamqp"
+	QueueDriverMemory    = "memory"
+)
+
+type QueueConfig struct {
+	Driver           string
+	MemoryBufferSize int
+	MemoryWorkers    int
+	DrainTimeout     time.Duration
+}
+
+// Image processors selectable through PROCESSOR_DRIVER.
+const (
+	ProcessorDriverBimg   = "bimg"
+	ProcessorDriverStdLib = "stdlib"
+)
+
+type ProcessorConfig struct {
+	Driver string
+	// AutoFormat picks the format of format=auto from the Accept header;
+	// without it format=auto is always JPEG or PNG.
+	AutoFormat bool
+	// AutoQuality searches the encoder quality for quality=auto; without it
+	// quality=auto is the default quality.
+	AutoQuality bool
 }
 
 type JWTConfig struct {
@@ -61,6 +98,30 @@ type JWTConfig struct {
 	Issuer string
 }
 
+// SigningConfig configures signed public render URLs. Keys maps key IDs to
+// secrets; ActiveKeyID signs new URLs and every key verifies. Signing is
+// disabled when no keys are configured.
+type SigningConfig struct {
+	Keys        map[string]string
+	ActiveKeyID string
+	DefaultTTL  time.Duration
+	MaxTTL      time.Duration
+	// BaseURL prefixes minted URLs; they are relative paths when empty.
+	BaseURL string
+}
+
+// CleanupConfig configures the background cleanup: purging images whose
+// TrashRetention has passed and retrying stored objects that could not be
+// deleted along with their image or variant. Deletions skip the trash when
+// TrashRetention is zero.
+type CleanupConfig struct {
+	Interval       time.Duration
+	BatchSize      int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	TrashRetention time.Duration
+}
+
 type LimitsConfig struct {
 	MaxUploadSize       int64
 	MaxImageWidth       int
@@ -68,6 +129,10 @@ type LimitsConfig struct {
 	RateLimitUploads    int
 	RateLimitTransforms int
 	RateLimitWindow     time.Duration
+	// MaxBatchSpecs caps the specs of one batch transform; BatchWorkers
+	// bounds how many of a sync batch render at once.
+	MaxBatchSpecs int
+	BatchWorkers  int
 }
 
 func LoadConfig() (*Config, error) {
@@ -94,17 +159,40 @@ func LoadConfig() (*Config, error) {
 	v.SetDefault("QUEUE_NAME", "image-transform-jobs")
 	v.SetDefault("QUEUE_DURABLE", true)
 	v.SetDefault("QUEUE_PREFETCH_COUNT", 5)
+	v.SetDefault("QUEUE_MAX_ATTEMPTS", 5)
+	v.SetDefault("QUEUE_RETRY_BASE_DELAY", 10*time.Second)
+	v.SetDefault("QUEUE_RETRY_MAX_DELAY", 10*time.Minute)
+
+	v.SetDefault("QUEUE_DRIVER", QueueDriverCloudAMQP)
+	v.SetDefault("QUEUE_MEMORY_BUFFER_SIZE", 100)
+	v.SetDefault("QUEUE_MEMORY_WORKERS", 2)
+	v.SetDefault("QUEUE_DRAIN_TIMEOUT", 30*time.Second)
+
+	v.SetDefault("PROCESSOR_DRIVER", ProcessorDriverBimg)
+	v.SetDefault("PROCESSOR_AUTO_FORMAT", true)
+	v.SetDefault("PROCESSOR_AUTO_QUALITY", true)
 
 	v.SetDefault("JWT_SECRET", "secret")
 	v.SetDefault("JWT_EXPIRY", 24*time.Hour)
 	v.SetDefault("JWT_ISSUER", "image-processing-service")
 
+	v.SetDefault("URL_SIGNING_DEFAULT_TTL", time.Hour)
+	v.SetDefault("URL_SIGNING_MAX_TTL", 7*24*time.Hour)
+
+	v.SetDefault("CLEANUP_INTERVAL", time.Minute)
+	v.SetDefault("CLEANUP_BATCH_SIZE", 100)
+	v.SetDefault("CLEANUP_RETRY_BASE_DELAY", time.Minute)
+	v.SetDefault("CLEANUP_RETRY_MAX_DELAY", 6*time.Hour)
+	v.SetDefault("TRASH_RETENTION", 30*24*time.Hour)
+
 	v.SetDefault("MAX_UPLOAD_SIZE", 20971520)
 	v.SetDefault("MAX_IMAGE_WIDTH", 8000)
 	v.SetDefault("MAX_IMAGE_HEIGHT", 8000)
 	v.SetDefault("RATE_LIMIT_UPLOADS", 100)
 	v.SetDefault("RATE_LIMIT_TRANSFORMS", 500)
 	v.SetDefault("RATE_LIMIT_WINDOW", time.Hour)
+	v.SetDefault("MAX_BATCH_SPECS", 20)
+	v.SetDefault("BATCH_TRANSFORM_WORKERS", 4)
 
 	// Environment mapping
 	v.AutomaticEnv()
@@ -115,6 +203,12 @@ func LoadConfig() (*Config, error) {
 	v.SetConfigType("env")
 	_ = v.ReadInConfig()
 
+	signingKeys, firstKeyID := parseSigningKeys(v.GetString("URL_SIGNING_KEYS"))
+	activeKeyID := v.GetString("URL_SIGNING_ACTIVE_KEY")
+	if activeKeyID == "" {
+		activeKeyID = firstKeyID
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Port:        v.GetString("PORT"),
@@ -144,16 +238,45 @@ func LoadConfig() (*Config, error) {
 			UseAutoQuality: v.GetBool("CLOUDINARY_USE_AUTO_QUALITY"),
 		},
 		CloudAMQP: CloudAMQPConfig{
-			URL:           v.GetString("CLOUDAMQP_URL"),
-			QueueName:     v.GetString("QUEUE_NAME"),
-			QueueDurable:  v.GetBool("QUEUE_DURABLE"),
-			PrefetchCount: v.GetInt("QUEUE_PREFETCH_COUNT"),
+			URL:             v.GetString("CLOUDAMQP_URL"),
+			QueueName:       v.GetString("QUEUE_NAME"),
+			QueueDurable:    v.GetBool("QUEUE_DURABLE"),
+			PrefetchCount:   v.GetInt("QUEUE_PREFETCH_COUNT"),
+			MaxAttempts:     v.GetInt("QUEUE_MAX_ATTEMPTS"),
+			RetryBaseDelay:  v.GetDuration("QUEUE_RETRY_BASE_DELAY"),
+			RetryMaxDelay:   v.GetDuration("QUEUE_RETRY_MAX_DELAY"),
+			DeadLetterQueue: v.GetString("QUEUE_DEAD_LETTER_NAME"),
+		},
+		Queue: QueueConfig{
+			Driver:           strings.ToLower(v.GetString("QUEUE_DRIVER")),
+			MemoryBufferSize: v.GetInt("QUEUE_MEMORY_BUFFER_SIZE"),
+			MemoryWorkers:    v.GetInt("QUEUE_MEMORY_WORKERS"),
+			DrainTimeout:     v.GetDuration("QUEUE_DRAIN_TIMEOUT"),
+		},
+		Processor: ProcessorConfig{
+			Driver:      strings.ToLower(v.GetString("PROCESSOR_DRIVER")),
+			AutoFormat:  v.GetBool("PROCESSOR_AUTO_FORMAT"),
+			AutoQuality: v.GetBool("PROCESSOR_AUTO_QUALITY"),
 		},
 		JWT: JWTConfig{
 			Secret: v.GetString("JWT_SECRET"),
 			Expiry: v.GetDuration("JWT_EXPIRY"),
 			Issuer: v.GetString("JWT_ISSUER"),
 		},
+		Signing: SigningConfig{
+			Keys:        signingKeys,
+			ActiveKeyID: activeKeyID,
+			DefaultTTL:  v.GetDuration("URL_SIGNING_DEFAULT_TTL"),
+			MaxTTL:      v.GetDuration("URL_SIGNING_MAX_TTL"),
+			BaseURL:     strings.TrimSuffix(v.GetString("URL_SIGNING_BASE_URL"), "/"),
+		},
+		Cleanup: CleanupConfig{
+			Interval:       v.GetDuration("CLEANUP_INTERVAL"),
+			BatchSize:      v.GetInt("CLEANUP_BATCH_SIZE"),
+			RetryBaseDelay: v.GetDuration("CLEANUP_RETRY_BASE_DELAY"),
+			RetryMaxDelay:  v.GetDuration("CLEANUP_RETRY_MAX_DELAY"),
+			TrashRetention: v.GetDuration("TRASH_RETENTION"),
+		},
 		Limits: LimitsConfig{
 			MaxUploadSize:       v.GetInt64("MAX_UPLOAD_SIZE"),
 			MaxImageWidth:       v.GetInt("MAX_IMAGE_WIDTH"),
@@ -161,6 +284,26 @@ func LoadConfig() (*Config, error) {
 			RateLimitUploads:    v.GetInt("RATE_LIMIT_UPLOADS"),
 			RateLimitTransforms: v.GetInt("RATE_LIMIT_TRANSFORMS"),
 			RateLimitWindow:     v.GetDuration("RATE_LIMIT_WINDOW"),
+			MaxBatchSpecs:       v.GetInt("MAX_BATCH_SPECS"),
+			BatchWorkers:        v.GetInt("BATCH_TRANSFORM_WORKERS"),
 		},
 	}, nil
 }
+
+// parseSigningKeys reads "id:secret,id:secret" and also returns the first
+// key ID, which signs when no active key is named.
+func parseSigningKeys(list string) (map[string]string, string) {
+	keys := make(map[string]string)
+	first := ""
+	for _, entry := range strings.Split(list, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			continue
+		}
+		if first == "" {
+			first = id
+		}
+		keys[id] = secret
+	}
+	return keys, first
+}
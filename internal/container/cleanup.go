@@ -0,0 +1,57 @@
+package container
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	appImage "image-processing-service/internal/application/image"
+	"image-processing-service/internal/config"
+)
+
+// runCleanup purges expired trash and retries failed storage deletions
+// every interval until ctx is cancelled. Full batches are followed by
+// another one straight away.
+func runCleanup(ctx context.Context, logger *zap.Logger, cfg config.CleanupConfig, purge *appImage.PurgeTrashUseCase, cleaner *appImage.StorageCleaner) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		drain(ctx, logger, "Trash purge", batchSize, purge.Execute)
+		drain(ctx, logger, "Storage cleanup", batchSize, cleaner.RetryDue)
+	}
+}
+
+// drain runs step until it handles less than a full batch.
+func drain(ctx context.Context, logger *zap.Logger, name string, batchSize int, step func(context.Context, int) (int, error)) {
+	for ctx.Err() == nil {
+		done, err := step(ctx, batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error(name+" failed", zap.Error(err))
+			}
+			return
+		}
+		if done > 0 {
+			logger.Info(name+" completed", zap.Int("count", done))
+		}
+		if done < batchSize {
+			return
+		}
+	}
+}
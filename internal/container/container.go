@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
@@ -16,6 +17,7 @@ import (
 	"image-processing-service/internal/adapters/persistence"
 	"image-processing-service/internal/adapters/processor"
 	"image-processing-service/internal/adapters/queue"
+	"image-processing-service/internal/adapters/signing"
 	"image-processing-service/internal/adapters/storage"
 	appAuth "image-processing-service/internal/application/auth"
 	appImage "image-processing-service/internal/application/image"
@@ -27,68 +29,61 @@ type Container struct {
 	Config *config.Config
 	Logger *zap.Logger
 	DB     *pgxpool.Pool
+	Queue  ports.Queue
 
 	AuthHandler    *handlers.AuthHandler
 	AuthMiddleware *middleware.AuthMiddleware
 
-	ImageHandler *handlers.ImageHandler
+	ImageHandler  *handlers.ImageHandler
+	PresetHandler *handlers.PresetHandler
+	PublicHandler *handlers.PublicHandler
 
 	RateLimitMiddleware *middleware.RateLimitMiddleware
+
+	stopBackground context.CancelFunc
+	background     sync.WaitGroup
 }
 
 func NewContainer() (*Container, error) {
-	cfg, err := config.LoadConfig()
+	cfg, logger, err := newBase()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+		return nil, err
 	}
 
-	logger, lerr := logging.NewLogger(cfg.Server.Environment)
-	if lerr != nil {
-		return nil, fmt.Errorf("failed to init logger: %w", lerr)
-	}
-	zap.ReplaceGlobals(logger)
-
-	dbConfig, dberr := pgxpool.ParseConfig(cfg.Supabase.DBURL)
-	if dberr != nil {
-		return nil, fmt.Errorf("failed to parse db config: %w", dberr)
-	}
-	// #nosec G115
-	dbConfig.MaxConns = int32(cfg.Supabase.MaxConns)
-	// #nosec G115
-	dbConfig.MinConns = int32(cfg.Supabase.MinConns)
-	dbConfig.MaxConnLifetime = cfg.Supabase.MaxConnLifetime
-	dbConfig.MaxConnIdleTime = cfg.Supabase.MaxConnIdleTime
-
-	pool, p_err := pgxpool.NewWithConfig(context.Background(), dbConfig)
-	if p_err != nil {
-		return nil, fmt.Errorf("failed to connect to db: %w", p_err)
-	}
-
-	if perr := pool.Ping(context.Background()); perr != nil {
-		log.Printf("Warning: Failed to ping database: %v", perr)
+	pool, err := newDBPool(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	userRepo := persistence.NewPostgresUserRepository(pool)
 	imageRepo := persistence.NewPostgresImageRepository(pool)
+	jobRepo := persistence.NewPostgresJobRepository(pool)
+	deletionRepo := persistence.NewPostgresStorageDeletionRepository(pool)
+	presetRepo := persistence.NewPostgresPresetRepository(pool)
 
-	var storageSvc ports.ObjectStorage
-	var serr error
-	if cfg.Cloudinary.CloudName != "" && cfg.Cloudinary.APIKey != "" {
-		storageSvc, serr = storage.NewCloudinaryStorage(cfg.Cloudinary)
-	} else {
-		log.Println("Warning: Cloudinary config missing. Using LocalStorage fallback.")
-		storageSvc, serr = storage.NewLocalStorage("/tmp/image-service")
+	storageSvc, err := newStorage(cfg)
+	if err != nil {
+		return nil, err
 	}
+	cleaner := appImage.NewStorageCleaner(storageSvc, deletionRepo, cfg.Cleanup.RetryBaseDelay, cfg.Cleanup.RetryMaxDelay)
 
-	if serr != nil {
-		return nil, fmt.Errorf("failed to init storage: %w", serr)
+	imgProcessor, err := newProcessor(cfg, logger)
+	if err != nil {
+		return nil, err
 	}
 
-	imgProcessor := processor.NewBimgProcessor()
+	q, err := newQueue(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	q, qerr := queue.NewCloudAMQPQueue(cfg.CloudAMQP)
-	if qerr != nil {
-		return nil, fmt.Errorf("failed to init queue: %w", qerr)
+	// Without a broker there is no standalone worker, so jobs run in-process.
+	if cfg.Queue.Driver == config.QueueDriverMemory {
+		processJobUC := appImage.NewProcessTransformJobUseCase(imageRepo, jobRepo, storageSvc, imgProcessor)
+		ctx := context.Background()
+		if cerr := q.Consume(ctx, NewJobHandler(ctx, logger, processJobUC)); cerr != nil {
+			return nil, fmt.Errorf("failed to start in-process consumer: %w", cerr)
+		}
 	}
 
 	// Redis Cache & Rate Limiting (Optional/Resilient)
@@ -105,37 +100,209 @@ func NewContainer() (*Container, error) {
 		rateLimiter = cache.NewRedisRateLimiter(redisSvc.Client())
 	}
 
+	urlSigner, err := newURLSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	jwtProvider := auth.NewJWTProvider(cfg.JWT)
 	hasher := auth.NewBcryptPasswordHasher()
 
 	registerUC := appAuth.NewRegisterUserUseCase(userRepo)
 	loginUC := appAuth.NewLoginUserUseCase(userRepo, hasher, jwtProvider)
 
-	uploadUC := appImage.NewUploadImageUseCase(imageRepo, storageSvc, imgProcessor)
-	asyncTransformUC := appImage.NewAsyncTransformImageUseCase(imageRepo, q)
-	syncTransformUC := appImage.NewTransformImageSyncUseCase(imageRepo, storageSvc, imgProcessor)
+	auto := appImage.AutoOptions{Format: cfg.Processor.AutoFormat, Quality: cfg.Processor.AutoQuality}
+	asyncTransformUC := appImage.NewAsyncTransformImageUseCase(imageRepo, presetRepo, jobRepo, q, imgProcessor, auto)
+	syncTransformUC := appImage.NewTransformImageSyncUseCase(imageRepo, presetRepo, storageSvc, imgProcessor, auto)
+	batchTransformUC := appImage.NewTransformImageBatchUseCase(imageRepo, presetRepo, jobRepo, q, syncTransformUC, cfg.Limits.BatchWorkers, cfg.Limits.MaxBatchSpecs)
+	uploadUC := appImage.NewUploadImageUseCase(imageRepo, presetRepo, storageSvc, imgProcessor, batchTransformUC)
 	getUC := appImage.NewGetImageUseCase(imageRepo, cacheSvc)
 	listUC := appImage.NewListImagesUseCase(imageRepo, cacheSvc)
+	getJobUC := appImage.NewGetJobUseCase(jobRepo)
+	setFocalPointUC := appImage.NewSetFocalPointUseCase(imageRepo, cacheSvc)
+	renderUC := appImage.NewRenderImageUseCase(imageRepo, storageSvc, syncTransformUC)
+	signRenderURLUC := appImage.NewSignRenderURLUseCase(imageRepo, urlSigner, cfg.Signing.DefaultTTL, cfg.Signing.MaxTTL)
+	renderSignedUC := appImage.NewRenderSignedImageUseCase(urlSigner, renderUC)
+	contentUC := appImage.NewGetImageContentUseCase(imageRepo, storageSvc)
+	metadataUC := appImage.NewGetImageMetadataUseCase(imageRepo)
+	deleteUC := appImage.NewDeleteImageUseCase(imageRepo, cacheSvc, cleaner, cfg.Cleanup.TrashRetention)
+	deleteVariantUC := appImage.NewDeleteVariantUseCase(imageRepo, cacheSvc, cleaner)
+	listTrashUC := appImage.NewListTrashUseCase(imageRepo, cfg.Cleanup.TrashRetention)
+	restoreUC := appImage.NewRestoreImageUseCase(imageRepo, cacheSvc, cfg.Cleanup.TrashRetention)
+	purgeUC := appImage.NewPurgeTrashUseCase(imageRepo, cacheSvc, cleaner, cfg.Cleanup.TrashRetention)
+	createPresetUC := appImage.NewCreatePresetUseCase(presetRepo, cfg.Limits.MaxBatchSpecs)
+	updatePresetUC := appImage.NewUpdatePresetUseCase(presetRepo, cfg.Limits.MaxBatchSpecs)
+	getPresetUC := appImage.NewGetPresetUseCase(presetRepo)
+	listPresetsUC := appImage.NewListPresetsUseCase(presetRepo)
+	deletePresetUC := appImage.NewDeletePresetUseCase(presetRepo)
+	capabilitiesUC := appImage.NewGetCapabilitiesUseCase(imgProcessor)
 
 	authHandler := handlers.NewAuthHandler(registerUC, loginUC, hasher)
 	authMiddleware := middleware.NewAuthMiddleware(jwtProvider)
-	imageHandler := handlers.NewImageHandler(uploadUC, asyncTransformUC, syncTransformUC, getUC, listUC)
+	imageHandler := handlers.NewImageHandler(uploadUC, asyncTransformUC, syncTransformUC, getUC, listUC, getJobUC, setFocalPointUC, renderUC, signRenderURLUC, cfg.Signing.BaseURL, contentUC, deleteUC, deleteVariantUC, listTrashUC, restoreUC, batchTransformUC, metadataUC)
+	presetHandler := handlers.NewPresetHandler(createPresetUC, updatePresetUC, getPresetUC, listPresetsUC, deletePresetUC)
+	publicHandler := handlers.NewPublicHandler(renderSignedUC, capabilitiesUC)
 
 	rateLimitMiddleware := middleware.NewRateLimitMiddleware(rateLimiter)
 
-	return &Container{
+	c := &Container{
 		Config:              cfg,
 		Logger:              logger,
 		DB:                  pool,
+		Queue:               q,
 		AuthHandler:         authHandler,
 		AuthMiddleware:      authMiddleware,
 		ImageHandler:        imageHandler,
+		PresetHandler:       presetHandler,
+		PublicHandler:       publicHandler,
 		RateLimitMiddleware: rateLimitMiddleware,
-	}, nil
+	}
+
+	// Purge expired trash and retry stored objects that could not be
+	// deleted with their image.
+	bgCtx, stop := context.WithCancel(context.Background())
+	c.stopBackground = stop
+	c.background.Add(1)
+	go func() {
+		defer c.background.Done()
+		runCleanup(bgCtx, logger, cfg.Cleanup, purgeUC, cleaner)
+	}()
+
+	return c, nil
 }
 
 func (c *Container) Close() {
+	if c.stopBackground != nil {
+		c.stopBackground()
+		c.background.Wait()
+	}
+	if c.Queue != nil {
+		if err := c.Queue.Close(); err != nil {
+			c.Logger.Warn("Failed to close queue", zap.Error(err))
+		}
+	}
 	if c.DB != nil {
 		c.DB.Close()
 	}
 }
+
+func newBase() (*config.Config, *zap.Logger, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	logger, lerr := logging.NewLogger(cfg.Server.Environment)
+	if lerr != nil {
+		return nil, nil, fmt.Errorf("failed to init logger: %w", lerr)
+	}
+	zap.ReplaceGlobals(logger)
+
+	return cfg, logger, nil
+}
+
+func newDBPool(cfg *config.Config) (*pgxpool.Pool, error) {
+	dbConfig, dberr := pgxpool.ParseConfig(cfg.Supabase.DBURL)
+	if dberr != nil {
+		return nil, fmt.Errorf("failed to parse db config: %w", dberr)
+	}
+	// #nosec G115
+	dbConfig.MaxConns = int32(cfg.Supabase.MaxConns)
+	// #nosec G115
+	dbConfig.MinConns = int32(cfg.Supabase.MinConns)
+	dbConfig.MaxConnLifetime = cfg.Supabase.MaxConnLifetime
+	dbConfig.MaxConnIdleTime = cfg.Supabase.MaxConnIdleTime
+
+	pool, p_err := pgxpool.NewWithConfig(context.Background(), dbConfig)
+	if p_err != nil {
+		return nil, fmt.Errorf("failed to connect to db: %w", p_err)
+	}
+
+	if perr := pool.Ping(context.Background()); perr != nil {
+		log.Printf("Warning: Failed to ping database: %v", perr)
+	}
+
+	return pool, nil
+}
+
+func newStorage(cfg *config.Config) (ports.ObjectStorage, error) {
+	var storageSvc ports.ObjectStorage
+	var serr error
+	if cfg.Cloudinary.CloudName != "" && cfg.Cloudinary.APIKey != "" {
+		storageSvc, serr = storage.NewCloudinaryStorage(cfg.Cloudinary)
+	} else {
+		log.Println("Warning: Cloudinary config missing. Using LocalStorage fallback.")
+		storageSvc, serr = storage.NewLocalStorage("/tmp/image-service")
+	}
+
+	if serr != nil {
+		return nil, fmt.Errorf("failed to init storage: %w", serr)
+	}
+	return storageSvc, nil
+}
+
+// newProcessor returns the configured processor and logs the formats it
+// supports.
+func newProcessor(cfg *config.Config, logger *zap.Logger) (ports.ImageProcessor, error) {
+	driver := cfg.Processor.Driver
+	if driver == "" {
+		driver = config.ProcessorDriverBimg
+	}
+
+	var p ports.ImageProcessor
+	switch driver {
+	case config.ProcessorDriverStdLib:
+		p = processor.NewStdLibImageProcessor()
+	case config.ProcessorDriverBimg:
+		if !processor.BimgAvailable {
+			return nil, fmt.Errorf("processor driver %q needs a cgo build with libvips; set PROCESSOR_DRIVER=%s", config.ProcessorDriverBimg, config.ProcessorDriverStdLib)
+		}
+		p = processor.NewBimgProcessor()
+	default:
+		return nil, fmt.Errorf("unknown processor driver %q", driver)
+	}
+
+	caps := p.Capabilities()
+	logger.Info("Image processor ready",
+		zap.String("driver", driver),
+		zap.Strings("input_formats", caps.Input),
+		zap.Strings("output_formats", caps.Output),
+	)
+	return p, nil
+}
+
+// newURLSigner returns nil, disabling signed URLs, when no keys are set.
+func newURLSigner(cfg *config.Config) (ports.URLSigner, error) {
+	if len(cfg.Signing.Keys) == 0 {
+		log.Println("Warning: URL_SIGNING_KEYS not set. Signed render URLs are DISABLED.")
+		return nil, nil
+	}
+	signer, err := signing.NewHMACURLSigner(cfg.Signing.Keys, cfg.Signing.ActiveKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init URL signer: %w", err)
+	}
+	return signer, nil
+}
+
+func newQueue(cfg *config.Config) (ports.Queue, error) {
+	switch cfg.Queue.Driver {
+	case config.QueueDriverMemory:
+		log.Println("Warning: Using in-memory queue. Jobs are lost on restart.")
+		return queue.NewMemoryQueue(queue.MemoryQueueOptions{
+			BufferSize:     cfg.Queue.MemoryBufferSize,
+			Workers:        cfg.Queue.MemoryWorkers,
+			MaxAttempts:    cfg.CloudAMQP.MaxAttempts,
+			RetryBaseDelay: cfg.CloudAMQP.RetryBaseDelay,
+			RetryMaxDelay:  cfg.CloudAMQP.RetryMaxDelay,
+			DrainTimeout:   cfg.Queue.DrainTimeout,
+		}), nil
+	case "", config.QueueDriverCloudAMQP:
+		q, err := queue.NewCloudAMQPQueue(cfg.CloudAMQP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init queue: %w", err)
+		}
+		return q, nil
+	default:
+		return nil, fmt.Errorf("unknown queue driver %q", cfg.Queue.Driver)
+	}
+}
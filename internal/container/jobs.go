@@ -0,0 +1,82 @@
+package container
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	appImage "image-processing-service/internal/application/image"
+	"image-processing-service/internal/ports"
+)
+
+// NewJobHandler adapts ProcessTransformJobUseCase to a ports.Queue handler.
+// It is used by the standalone worker and by the API when it runs jobs
+// in-process with the memory queue driver. Batched messages run through
+// ExecuteBatch.
+func NewJobHandler(ctx context.Context, logger *zap.Logger, uc *appImage.ProcessTransformJobUseCase) func(*ports.TransformJob) error {
+	return func(job *ports.TransformJob) error {
+		if job.IsBatch() {
+			return handleBatch(ctx, logger, uc, job)
+		}
+
+		logger.Info("Processing Job",
+			zap.String("job_id", job.JobID),
+			zap.String("image_id", job.ImageID),
+			zap.String("spec_hash", job.SpecHash),
+			zap.Int("attempt", job.Attempt),
+		)
+
+		result, err := uc.Execute(ctx, job)
+		if err != nil {
+			logger.Error("Job failed",
+				zap.String("job_id", job.JobID),
+				zap.String("image_id", job.ImageID),
+				zap.Int("attempt", job.Attempt),
+				zap.Bool("final_attempt", job.IsFinalAttempt()),
+				zap.Error(err),
+			)
+			return err
+		}
+		if result == nil {
+			logger.Info("Job already completed; its variant has since been deleted",
+				zap.String("job_id", job.JobID),
+			)
+			return nil
+		}
+
+		logger.Info("Job completed",
+			zap.String("job_id", job.JobID),
+			zap.String("variant_id", result.ID),
+			zap.String("variant_key", result.VariantKey),
+		)
+		return nil
+	}
+}
+
+func handleBatch(ctx context.Context, logger *zap.Logger, uc *appImage.ProcessTransformJobUseCase, job *ports.TransformJob) error {
+	logger.Info("Processing Batch",
+		zap.String("batch_id", job.JobID),
+		zap.String("image_id", job.ImageID),
+		zap.Int("items", len(job.Items)),
+		zap.Int("attempt", job.Attempt),
+	)
+
+	succeeded, err := uc.ExecuteBatch(ctx, job)
+	if err != nil {
+		logger.Error("Batch failed",
+			zap.String("batch_id", job.JobID),
+			zap.String("image_id", job.ImageID),
+			zap.Int("succeeded", succeeded),
+			zap.Int("attempt", job.Attempt),
+			zap.Bool("final_attempt", job.IsFinalAttempt()),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	logger.Info("Batch completed",
+		zap.String("batch_id", job.JobID),
+		zap.Int("succeeded", succeeded),
+	)
+	return nil
+}
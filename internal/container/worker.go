@@ -0,0 +1,81 @@
+package container
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"image-processing-service/internal/adapters/persistence"
+	appImage "image-processing-service/internal/application/image"
+	"image-processing-service/internal/config"
+	"image-processing-service/internal/ports"
+)
+
+// Worker holds the dependencies of the background transform worker. It is
+// wired from the same building blocks as Container so variants rendered by
+// the worker match the ones produced by the API.
+type Worker struct {
+	Config *config.Config
+	Logger *zap.Logger
+	DB     *pgxpool.Pool
+	Queue  ports.Queue
+
+	ProcessJobUC *appImage.ProcessTransformJobUseCase
+}
+
+func NewWorker() (*Worker, error) {
+	cfg, logger, err := newBase()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Queue.Driver == config.QueueDriverMemory {
+		return nil, fmt.Errorf("QUEUE_DRIVER=%s runs jobs inside the API process; the standalone worker needs a broker", config.QueueDriverMemory)
+	}
+
+	pool, err := newDBPool(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	imageRepo := persistence.NewPostgresImageRepository(pool)
+	jobRepo := persistence.NewPostgresJobRepository(pool)
+
+	storageSvc, err := newStorage(cfg)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	imgProcessor, err := newProcessor(cfg, logger)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	q, err := newQueue(cfg)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &Worker{
+		Config:       cfg,
+		Logger:       logger,
+		DB:           pool,
+		Queue:        q,
+		ProcessJobUC: appImage.NewProcessTransformJobUseCase(imageRepo, jobRepo, storageSvc, imgProcessor),
+	}, nil
+}
+
+func (w *Worker) Close() {
+	if w.Queue != nil {
+		_ = w.Queue.Close()
+	}
+	if w.DB != nil {
+		w.DB.Close()
+	}
+	if w.Logger != nil {
+		_ = w.Logger.Sync()
+	}
+}
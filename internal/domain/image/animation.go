@@ -0,0 +1,37 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Animation describes the frames of an animated GIF or WebP.
+type Animation struct {
+	Frames int `json:"frames"`
+	// LoopCount is how often the animation plays; zero plays it forever.
+	LoopCount int `json:"loop_count"`
+	// DurationMS is the time one play takes, in milliseconds.
+	DurationMS int `json:"duration_ms"`
+}
+
+// ErrFrameOutOfRange is returned for specs picking a frame the image does
+// not have.
+var ErrFrameOutOfRange = errors.New("frame is outside the animation")
+
+// CheckFrame verifies the frame the spec picks exists in img. Still images
+// have frame 0 only. GIFs and WebPs without a recorded animation may be
+// animations uploaded before animations were recorded, so their frames are
+// left to the processor to check.
+func (s *TransformationSpec) CheckFrame(img *Image) error {
+	if s.Frame == nil || (img.Animation == nil && img.MayAnimate()) {
+		return nil
+	}
+	frames := 1
+	if img.Animation != nil {
+		frames = img.Animation.Frames
+	}
+	if *s.Frame >= frames {
+		return fmt.Errorf("%w: frame %d of %d", ErrFrameOutOfRange, *s.Frame, frames)
+	}
+	return nil
+}
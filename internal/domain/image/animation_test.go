@@ -0,0 +1,44 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckFrame(t *testing.T) {
+	animated := &Image{MimeType: "image/gif", Animation: &Animation{Frames: 3}}
+	still := &Image{MimeType: "image/jpeg"}
+	unknown := &Image{MimeType: "image/webp"}
+
+	tests := []struct {
+		name  string
+		img   *Image
+		frame *int
+		ok    bool
+	}{
+		{"no frame", still, nil, true},
+		{"last frame", animated, intPtr(2), true},
+		{"past the last frame", animated, intPtr(3), false},
+		{"first frame of a still", still, intPtr(0), true},
+		{"second frame of a still", still, intPtr(1), false},
+		{"unrecorded animation", unknown, intPtr(5), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&TransformationSpec{Frame: tt.frame}).CheckFrame(tt.img)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrFrameOutOfRange)
+			}
+		})
+	}
+}
+
+func TestMayAnimate(t *testing.T) {
+	assert.True(t, (&Image{MimeType: "image/gif"}).MayAnimate())
+	assert.True(t, (&Image{MimeType: "image/webp"}).MayAnimate())
+	assert.True(t, (&Image{MimeType: "image/png", Animation: &Animation{Frames: 2}}).MayAnimate())
+	assert.False(t, (&Image{MimeType: "image/png"}).MayAnimate())
+}
@@ -0,0 +1,101 @@
+package image
+
+import "slices"
+
+// Output formats a spec can ask for. Which of them can be rendered depends
+// on the processor; see ports.ProcessorCapabilities.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatGIF  = "gif"
+	FormatAVIF = "avif"
+	FormatHEIF = "heif"
+	FormatTIFF = "tiff"
+)
+
+// FormatAuto asks for the best format the client accepts, see ChooseFormat.
+// It is replaced by the chosen format before the spec is hashed.
+const FormatAuto = "auto"
+
+// Formats lists the canonical output formats.
+var Formats = []string{FormatJPEG, FormatPNG, FormatWebP, FormatGIF, FormatAVIF, FormatHEIF, FormatTIFF}
+
+// formatAliases maps alternative format names to the canonical ones.
+var formatAliases = map[string]string{
+	"jpg":  FormatJPEG,
+	"heic": FormatHEIF,
+	"tif":  FormatTIFF,
+}
+
+// lossyFormats are the output formats that Quality applies to.
+var lossyFormats = []string{FormatJPEG, FormatWebP, FormatAVIF, FormatHEIF}
+
+// CanonicalFormat folds an alias into the format it stands for.
+func CanonicalFormat(format string) string {
+	if canonical, ok := formatAliases[format]; ok {
+		return canonical
+	}
+	return format
+}
+
+// IsLossyFormat reports whether Quality applies to the output format.
+func IsLossyFormat(format string) bool {
+	return slices.Contains(lossyFormats, CanonicalFormat(format))
+}
+
+// ChooseFormat picks the format FormatAuto stands for: AVIF, else WebP,
+// when accepts allows it, otherwise JPEG, or PNG to keep transparency.
+func ChooseFormat(accepts func(format string) bool, hasAlpha bool) string {
+	for _, format := range []string{FormatAVIF, FormatWebP} {
+		if accepts(format) {
+			return format
+		}
+	}
+	if hasAlpha {
+		return FormatPNG
+	}
+	return FormatJPEG
+}
+
+// CarriesMetadata reports whether variants in format keep EXIF, ICC and
+// XMP metadata. Other formats are written without any.
+func CarriesMetadata(format string) bool {
+	switch CanonicalFormat(format) {
+	case FormatJPEG, FormatPNG, FormatWebP:
+		return true
+	}
+	return false
+}
+
+// ChooseAnimationFormat picks the format auto resolves to for animations:
+// WebP when accepted, otherwise GIF, so that every frame is kept.
+func ChooseAnimationFormat(accepts func(format string) bool) string {
+	if accepts(FormatWebP) {
+		return FormatWebP
+	}
+	return FormatGIF
+}
+
+// MimeTypeFor returns the MIME type of an output format, or "" for unknown
+// formats.
+func MimeTypeFor(format string) string {
+	switch CanonicalFormat(format) {
+	case FormatJPEG:
+		return "image/jpeg"
+	case FormatPNG:
+		return "image/png"
+	case FormatWebP:
+		return "image/webp"
+	case FormatGIF:
+		return "image/gif"
+	case FormatAVIF:
+		return "image/avif"
+	case FormatHEIF:
+		return "image/heif"
+	case FormatTIFF:
+		return "image/tiff"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,73 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalFormat(t *testing.T) {
+	tests := map[string]string{
+		"jpg":  FormatJPEG,
+		"heic": FormatHEIF,
+		"tif":  FormatTIFF,
+		"jpeg": FormatJPEG,
+		"webp": FormatWebP,
+		"bmp":  "bmp",
+	}
+	for format, want := range tests {
+		assert.Equal(t, want, CanonicalFormat(format), format)
+	}
+}
+
+func TestIsLossyFormat(t *testing.T) {
+	for _, format := range []string{"jpeg", "jpg", "webp", "avif", "heif", "heic"} {
+		assert.True(t, IsLossyFormat(format), format)
+	}
+	for _, format := range []string{"png", "gif", "tiff", "tif"} {
+		assert.False(t, IsLossyFormat(format), format)
+	}
+}
+
+func TestFormatValidation(t *testing.T) {
+	for _, format := range append([]string{"auto", "jpg", "heic", "tif"}, Formats...) {
+		spec := &TransformationSpec{Format: strPtr(format)}
+		assert.NoError(t, binding.Validator.ValidateStruct(spec), format)
+	}
+	for _, format := range []string{"jxl", "bmp"} {
+		spec := &TransformationSpec{Format: strPtr(format)}
+		assert.Error(t, binding.Validator.ValidateStruct(spec), format)
+	}
+}
+
+func TestChooseFormat(t *testing.T) {
+	accepting := func(formats ...string) func(string) bool {
+		return func(format string) bool {
+			for _, f := range formats {
+				if f == format {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	tests := []struct {
+		name     string
+		accepts  func(string) bool
+		hasAlpha bool
+		want     string
+	}{
+		{"avif first", accepting(FormatWebP, FormatAVIF), false, FormatAVIF},
+		{"webp without avif", accepting(FormatWebP), true, FormatWebP},
+		{"jpeg fallback", accepting(), false, FormatJPEG},
+		{"png keeps transparency", accepting(), true, FormatPNG},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ChooseFormat(tt.accepts, tt.hasAlpha))
+		})
+	}
+	assert.Equal(t, FormatWebP, ChooseAnimationFormat(accepting(FormatWebP, FormatAVIF)))
+	assert.Equal(t, FormatGIF, ChooseAnimationFormat(accepting(FormatAVIF)))
+}
@@ -22,12 +22,39 @@ type Image struct {
 	Height      int         `json:"height"`
 	Variants    []Variant   `json:"variants"`
 	CreatedAt   time.Time   `json:"created_at"`
+	// FocalPoint marks the subject of the image. Focal crops and cover
+	// resizes centre on it; without one they centre on the image.
+	FocalPoint *FocalPoint `json:"focal_point,omitempty"`
+	// DeletedAt is set while the image is in the trash, until it is
+	// restored or purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// HasAlpha reports whether the image has an alpha channel. It is nil
+	// for images uploaded before it was recorded.
+	HasAlpha *bool `json:"has_alpha,omitempty"`
+	// Orientation is the EXIF orientation of the stored original, 1 to 8.
+	// Width and Height are as displayed, once it is applied. It is 0 for
+	// images uploaded before it was recorded, whose Width and Height may be
+	// those of the stored pixels.
+	Orientation int `json:"-"`
+	// Animation is set for animated GIFs and WebPs.
+	Animation *Animation `json:"animation,omitempty"`
+	// Photo is the metadata read from the original on upload. It may hold
+	// the GPS position, so it is only served by the metadata endpoint.
+	Photo *PhotoMetadata `json:"-"`
+}
+
+// FocalPoint is a point of interest in coordinates relative to the image
+// as displayed: 0,0 is the top-left corner and 1,1 the bottom-right one.
+type FocalPoint struct {
+	X float64 `json:"x" binding:"min=0,max=1"`
+	Y float64 `json:"y" binding:"min=0,max=1"`
 }
 
 var (
-	ErrInvalidImageID  = errors.New("invalid image ID")
-	ErrInvalidOwnerID  = errors.New("invalid owner ID")
-	ErrInvalidFilename = errors.New("invalid filename")
+	ErrInvalidImageID    = errors.New("invalid image ID")
+	ErrInvalidOwnerID    = errors.New("invalid owner ID")
+	ErrInvalidFilename   = errors.New("invalid filename")
+	ErrInvalidFocalPoint = errors.New("focal point must lie within 0..1")
 )
 
 func New(ownerID user.UserID, filename, originalKey, mimeType string, size int64, width, height int) (*Image, error) {
@@ -55,6 +82,24 @@ func New(ownerID user.UserID, filename, originalKey, mimeType string, size int64
 	}, nil
 }
 
+// MayHaveAlpha reports whether the image may be transparent. Without a
+// recorded HasAlpha only JPEGs are known to be opaque.
+func (i *Image) MayHaveAlpha() bool {
+	if i.HasAlpha != nil {
+		return *i.HasAlpha
+	}
+	return i.MimeType != "image/jpeg"
+}
+
+// MayAnimate reports whether the image may be an animation. Without a
+// recorded Animation any GIF or WebP may be.
+func (i *Image) MayAnimate() bool {
+	if i.Animation != nil {
+		return true
+	}
+	return i.MimeType == "image/gif" || i.MimeType == "image/webp"
+}
+
 func (i *Image) AddVariant(v Variant) bool {
 	for _, existing := range i.Variants {
 		if existing.SpecHash == v.SpecHash {
@@ -65,6 +110,16 @@ func (i *Image) AddVariant(v Variant) bool {
 	return true
 }
 
+// FindVariant returns the variant with the given ID, or nil.
+func (i *Image) FindVariant(id uuid.UUID) *Variant {
+	for _, v := range i.Variants {
+		if v.ID == id {
+			return &v
+		}
+	}
+	return nil
+}
+
 func (i *Image) GetVariant(specHash string) *Variant {
 	for _, v := range i.Variants {
 		if v.SpecHash == specHash {
@@ -73,3 +128,26 @@ func (i *Image) GetVariant(specHash string) *Variant {
 	}
 	return nil
 }
+
+// IsTrashed reports whether the image was deleted and can still be restored.
+func (i *Image) IsTrashed() bool {
+	return i.DeletedAt != nil
+}
+
+// PurgeAt returns when a trashed image is removed for good, given the
+// retention of the trash.
+func (i *Image) PurgeAt(retention time.Duration) time.Time {
+	if i.DeletedAt == nil {
+		return time.Time{}
+	}
+	return i.DeletedAt.Add(retention)
+}
+
+// SetFocalPoint replaces the focal point; nil clears it.
+func (i *Image) SetFocalPoint(fp *FocalPoint) error {
+	if fp != nil && (fp.X < 0 || fp.X > 1 || fp.Y < 0 || fp.Y > 1) {
+		return ErrInvalidFocalPoint
+	}
+	i.FocalPoint = fp
+	return nil
+}
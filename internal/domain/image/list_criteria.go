@@ -0,0 +1,96 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"image-processing-service/internal/domain/user"
+)
+
+// SortField names the column images are listed by.
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortBySize      SortField = "size"
+	SortByFilename  SortField = "filename"
+)
+
+var ErrInvalidListCriteria = errors.New("invalid list criteria")
+
+// ListCriteria selects, orders and pages an owner's images. Zero values
+// leave a filter out. Ties in the sort field are broken by ID so every
+// image has a stable position.
+type ListCriteria struct {
+	OwnerID user.UserID
+
+	// MimeTypes matches any of the listed types.
+	MimeTypes     []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	MinWidth      int
+	MaxWidth      int
+	MinHeight     int
+	MaxHeight     int
+	// FilenameContains matches case-insensitively.
+	FilenameContains string
+
+	SortBy     SortField
+	Descending bool
+
+	Limit int
+	// Offset skips images; it is ignored when After is set.
+	Offset int
+	// After continues a listing from the position of an image, keyset
+	// style, so pages stay consistent while images are added.
+	After *ListCursor
+}
+
+// ListCursor is the position of an image in a listing. It carries every
+// sortable value so it can resume whichever sort it was made for.
+type ListCursor struct {
+	SortBy     SortField `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	CreatedAt  time.Time `json:"c"`
+	Size       int64     `json:"z"`
+	Filename   string    `json:"f"`
+	ID         ImageID   `json:"id"`
+}
+
+// CursorAfter returns the cursor that continues the listing after img.
+func (c *ListCriteria) CursorAfter(img *Image) *ListCursor {
+	return &ListCursor{
+		SortBy:     c.SortBy,
+		Descending: c.Descending,
+		CreatedAt:  img.CreatedAt,
+		Size:       img.Size,
+		Filename:   img.Filename,
+		ID:         img.ID,
+	}
+}
+
+// Validate checks the criteria and that After was made for the same sort.
+func (c *ListCriteria) Validate() error {
+	switch c.SortBy {
+	case SortByCreatedAt, SortBySize, SortByFilename:
+	default:
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidListCriteria, c.SortBy)
+	}
+	if c.Limit <= 0 || c.Offset < 0 {
+		return fmt.Errorf("%w: limit must be positive and offset not negative", ErrInvalidListCriteria)
+	}
+	if c.MinWidth < 0 || c.MaxWidth < 0 || c.MinHeight < 0 || c.MaxHeight < 0 {
+		return fmt.Errorf("%w: dimensions cannot be negative", ErrInvalidListCriteria)
+	}
+	if (c.MaxWidth > 0 && c.MinWidth > c.MaxWidth) || (c.MaxHeight > 0 && c.MinHeight > c.MaxHeight) {
+		return fmt.Errorf("%w: minimum dimension exceeds maximum", ErrInvalidListCriteria)
+	}
+	if c.CreatedAfter != nil && c.CreatedBefore != nil && !c.CreatedAfter.Before(*c.CreatedBefore) {
+		return fmt.Errorf("%w: created_after must be before created_before", ErrInvalidListCriteria)
+	}
+	if c.After != nil && (c.After.SortBy != c.SortBy || c.After.Descending != c.Descending) {
+		return fmt.Errorf("%w: cursor belongs to a different sort order", ErrInvalidListCriteria)
+	}
+	return nil
+}
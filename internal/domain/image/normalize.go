@@ -0,0 +1,103 @@
+package image
+
+import "strings"
+
+// SpecHashVersion identifies how Hash derives spec hashes. Version 1 hashed
+// the spec verbatim; version 2 hashes its Normalize form. Specs that were
+// already in normal form hash the same under both.
+const SpecHashVersion = 2
+
+// Normalize returns the canonical form of the spec, which renders the same
+// image. It folds aliases and letter case, leaves defaults unset and drops
+// operations and fields that have no effect, so that specs asking for the
+// same image hash the same. The spec itself is left untouched.
+func (s TransformationSpec) Normalize() TransformationSpec {
+	if s.Rotate != nil && *s.Rotate%360 == 0 {
+		s.Rotate = nil
+	}
+	if !s.Flipped() {
+		s.Flip = nil
+	}
+	if !s.Mirrored() {
+		s.Mirror = nil
+	}
+
+	if s.Format != nil {
+		format := strings.ToLower(*s.Format)
+		format = CanonicalFormat(format)
+		s.Format = &format
+		if format == "" {
+			s.Format = nil
+		}
+	}
+
+	if s.Format != nil && !IsLossyFormat(*s.Format) {
+		s.Quality = nil
+		s.MaxBytes = 0
+	}
+	if s.Quality != nil && *s.Quality == DefaultQuality {
+		s.Quality = nil
+	}
+
+	if s.Resize != nil {
+		r := *s.Resize
+		if r.Width == 0 || r.Height == 0 || r.Fit == FitContain {
+			r.Fit = ""
+		}
+		if r.Width != 0 && r.Height != 0 && r.Fit == "" {
+			// Contain never enlarges anyway.
+			r.WithoutEnlargement = false
+		}
+		r.Background = strings.ToLower(r.Background)
+		s.Resize = &r
+	}
+
+	if s.Crop != nil {
+		c := *s.Crop
+		c.Gravity = c.EffectiveGravity()
+		if c.Gravity != "" {
+			c.X, c.Y = 0, 0
+		}
+		s.Crop = &c
+	}
+
+	if s.Watermark != nil {
+		w := *s.Watermark
+		if w.EffectiveOpacity() == DefaultWatermarkOpacity {
+			w.Opacity = 0
+		}
+		w.Gravity = w.EffectiveGravity()
+		if w.Gravity == GravitySouthEast {
+			w.Gravity = ""
+		}
+		s.Watermark = &w
+	}
+
+	if s.Filters != nil {
+		f := *s.Filters
+		if f.Gamma == 1 {
+			f.Gamma = 0
+		}
+		f.Tint = strings.ToLower(f.Tint)
+		s.Filters = &f
+		if f == (FilterSpec{}) {
+			s.Filters = nil
+		}
+	}
+
+	if s.Frame != nil || (s.Format != nil && *s.Format != FormatGIF && *s.Format != FormatWebP) {
+		s.MaxFrames = 0
+	}
+
+	if s.Metadata != nil {
+		s.Metadata = s.Metadata.normalize()
+	}
+	if s.Format != nil && !CarriesMetadata(*s.Format) {
+		s.Metadata = nil
+	}
+
+	if !s.UsesFocalPoint() {
+		s.FocalPoint = nil
+	}
+	return s
+}
@@ -0,0 +1,153 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func qualityPtr(q Quality) *Quality {
+	return &q
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestNormalizeHashesEquivalentSpecsAlike(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b TransformationSpec
+	}{
+		{
+			"format alias and case",
+			TransformationSpec{Format: strPtr("JPG")},
+			TransformationSpec{Format: strPtr("jpeg")},
+		},
+		{
+			"default quality",
+			TransformationSpec{Format: strPtr("webp"), Quality: qualityPtr(DefaultQuality)},
+			TransformationSpec{Format: strPtr("webp")},
+		},
+		{
+			"quality of a lossless format",
+			TransformationSpec{Format: strPtr("png"), Quality: qualityPtr(40), MaxBytes: 1000},
+			TransformationSpec{Format: strPtr("png")},
+		},
+		{
+			"rotation by whole turns",
+			TransformationSpec{Rotate: intPtr(0), Flip: boolPtr(false), Mirror: boolPtr(false)},
+			TransformationSpec{},
+		},
+		{
+			"contain is the default fit",
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Height: 100, Fit: FitContain}},
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Height: 100}},
+		},
+		{
+			"contain never enlarges",
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Height: 100, WithoutEnlargement: true}},
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Height: 100}},
+		},
+		{
+			"fit of a single dimension",
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Fit: FitCover}},
+			TransformationSpec{Resize: &ResizeSpec{Width: 100}},
+		},
+		{
+			"background case",
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Background: "#FFF"}},
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Background: "#fff"}},
+		},
+		{
+			"crop gravity spelling and ignored offsets",
+			TransformationSpec{Crop: &CropSpec{Width: 10, Height: 10, X: 5, Y: 5, Gravity: "centre"}},
+			TransformationSpec{Crop: &CropSpec{Width: 10, Height: 10, Gravity: GravityCenter}},
+		},
+		{
+			"neutral filters",
+			TransformationSpec{Filters: &FilterSpec{Gamma: 1}},
+			TransformationSpec{},
+		},
+		{
+			"max frames of a still format",
+			TransformationSpec{Format: strPtr("jpeg"), MaxFrames: 5},
+			TransformationSpec{Format: strPtr("jpeg")},
+		},
+		{
+			"metadata of a format that carries none",
+			TransformationSpec{Format: strPtr("gif"), Metadata: &MetadataSpec{EXIF: boolPtr(false)}},
+			TransformationSpec{Format: strPtr("gif")},
+		},
+		{
+			"focal point the spec does not use",
+			TransformationSpec{Resize: &ResizeSpec{Width: 100}, FocalPoint: &FocalPoint{X: 0.2, Y: 0.3}},
+			TransformationSpec{Resize: &ResizeSpec{Width: 100}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSameHash(t, &tt.a, &tt.b)
+		})
+	}
+}
+
+func TestNormalizeKeepsDistinctSpecsApart(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b TransformationSpec
+	}{
+		{
+			"without enlargement of a single dimension",
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, WithoutEnlargement: true}},
+			TransformationSpec{Resize: &ResizeSpec{Width: 100}},
+		},
+		{
+			"without enlargement of cover",
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Height: 100, Fit: FitCover, WithoutEnlargement: true}},
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Height: 100, Fit: FitCover}},
+		},
+		{
+			"quality of a lossy format",
+			TransformationSpec{Format: strPtr("webp"), Quality: qualityPtr(40)},
+			TransformationSpec{Format: strPtr("webp")},
+		},
+		{
+			"flip",
+			TransformationSpec{Flip: boolPtr(true)},
+			TransformationSpec{},
+		},
+		{
+			"focal point of a cover resize",
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Height: 50, Fit: FitCover}, FocalPoint: &FocalPoint{X: 0.2, Y: 0.3}},
+			TransformationSpec{Resize: &ResizeSpec{Width: 100, Height: 50, Fit: FitCover}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ha, err := tt.a.Hash()
+			require.NoError(t, err)
+			hb, err := tt.b.Hash()
+			require.NoError(t, err)
+			assert.NotEqual(t, ha, hb)
+		})
+	}
+}
+
+func TestNormalizeLeavesTheSpecUntouched(t *testing.T) {
+	spec := TransformationSpec{
+		Format: strPtr("JPG"),
+		Resize: &ResizeSpec{Width: 100, Height: 100, Fit: FitContain},
+	}
+	n := spec.Normalize()
+
+	assert.Equal(t, "jpeg", *n.Format)
+	assert.Equal(t, "JPG", *spec.Format)
+	assert.Equal(t, FitContain, spec.Resize.Fit)
+	assert.Equal(t, n, n.Normalize(), "normalizing is idempotent")
+}
@@ -0,0 +1,102 @@
+package image
+
+// PhotoMetadata is the descriptive metadata read from the EXIF and ICC
+// profile of an original when it is uploaded.
+type PhotoMetadata struct {
+	Camera *Camera `json:"camera,omitempty"`
+	// CapturedAt is the EXIF capture time as 2006-01-02T15:04:05, followed
+	// by the UTC offset when the camera recorded one.
+	CapturedAt   string        `json:"captured_at,omitempty"`
+	GPS          *GPS          `json:"gps,omitempty"`
+	ColorProfile *ColorProfile `json:"color_profile,omitempty"`
+	// Orientation is the EXIF orientation, 2–8, the original was recorded
+	// with. Uploads are turned upright, so it is informational.
+	Orientation int `json:"orientation,omitempty"`
+}
+
+// Camera describes the camera and exposure of a photo.
+type Camera struct {
+	Make  string `json:"make,omitempty"`
+	Model string `json:"model,omitempty"`
+	Lens  string `json:"lens,omitempty"`
+	// FocalLength is in millimetres.
+	FocalLength float64 `json:"focal_length,omitempty"`
+	FNumber     float64 `json:"f_number,omitempty"`
+	// ExposureTime is in seconds, as a fraction for short exposures.
+	ExposureTime string `json:"exposure_time,omitempty"`
+	ISO          int    `json:"iso,omitempty"`
+}
+
+// GPS is the position a photo was taken at, in decimal degrees.
+type GPS struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Altitude is in metres above sea level.
+	Altitude *float64 `json:"altitude,omitempty"`
+}
+
+// ColorProfile describes the colour space of an image.
+type ColorProfile struct {
+	// Name is the description of the embedded ICC profile, or sRGB for
+	// images that declare it in EXIF only.
+	Name string `json:"name,omitempty"`
+	// ColorSpace is the data colour space of the ICC profile, e.g. RGB,
+	// CMYK or GRAY.
+	ColorSpace string `json:"color_space,omitempty"`
+}
+
+// MetadataSpec picks the metadata of the original a variant keeps. A field
+// set to true keeps that metadata and false strips it. Unset, EXIF, the ICC
+// profile and XMP are kept but the GPS position is stripped from EXIF.
+// Kept EXIF always has its orientation reset, as variants are upright.
+type MetadataSpec struct {
+	EXIF *bool `json:"exif,omitempty"`
+	ICC  *bool `json:"icc,omitempty"`
+	XMP  *bool `json:"xmp,omitempty"`
+	// GPS keeps the GPS position in kept EXIF and XMP.
+	GPS *bool `json:"gps,omitempty"`
+}
+
+// KeepsEXIF reports whether variants keep EXIF. A nil spec keeps the
+// defaults.
+func (m *MetadataSpec) KeepsEXIF() bool {
+	return m == nil || m.EXIF == nil || *m.EXIF
+}
+
+// KeepsICC reports whether variants keep the ICC profile.
+func (m *MetadataSpec) KeepsICC() bool {
+	return m == nil || m.ICC == nil || *m.ICC
+}
+
+// KeepsXMP reports whether variants keep XMP.
+func (m *MetadataSpec) KeepsXMP() bool {
+	return m == nil || m.XMP == nil || *m.XMP
+}
+
+// KeepsGPS reports whether variants keep the GPS position.
+func (m *MetadataSpec) KeepsGPS() bool {
+	return m.KeepsEXIF() && m != nil && m.GPS != nil && *m.GPS
+}
+
+// normalize leaves the defaults unset and returns nil when nothing differs
+// from them.
+func (m *MetadataSpec) normalize() *MetadataSpec {
+	no, yes := false, true
+	var n MetadataSpec
+	if !m.KeepsEXIF() {
+		n.EXIF = &no
+	}
+	if !m.KeepsICC() {
+		n.ICC = &no
+	}
+	if !m.KeepsXMP() {
+		n.XMP = &no
+	}
+	if m.KeepsGPS() {
+		n.GPS = &yes
+	}
+	if n == (MetadataSpec{}) {
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,58 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// DefaultQuality is the encoder quality used when a spec sets none, the
+// libvips default.
+const DefaultQuality = 75
+
+// Quality is an encoder quality from 1 to 100, or QualityAuto, which JSON
+// writes as "auto". Specs leave it unset with a nil *Quality.
+type Quality int
+
+// QualityAuto asks the processor for the lowest quality that keeps the
+// variant visually indistinguishable from a lossless render.
+const QualityAuto Quality = -1
+
+// ErrMaxBytesUnreachable is returned for specs whose MaxBytes even the
+// lowest quality does not fit.
+var ErrMaxBytesUnreachable = errors.New("max_bytes cannot be met")
+
+var errInvalidQuality = errors.New(`quality must be an integer from 1 to 100 or "auto"`)
+
+// ParseQuality reads a quality as written in render URLs.
+func ParseQuality(s string) (Quality, error) {
+	if s == "auto" {
+		return QualityAuto, nil
+	}
+	var q Quality
+	return q, q.UnmarshalJSON([]byte(s))
+}
+
+// IsAuto reports whether q is QualityAuto.
+func (q Quality) IsAuto() bool {
+	return q == QualityAuto
+}
+
+func (q Quality) MarshalJSON() ([]byte, error) {
+	if q.IsAuto() {
+		return []byte(`"auto"`), nil
+	}
+	return json.Marshal(int(q))
+}
+
+func (q *Quality) UnmarshalJSON(data []byte) error {
+	if string(data) == `"auto"` {
+		*q = QualityAuto
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil || n < 1 || n > 100 {
+		return errInvalidQuality
+	}
+	*q = Quality(n)
+	return nil
+}
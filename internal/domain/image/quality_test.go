@@ -0,0 +1,39 @@
+package image
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQualityJSON(t *testing.T) {
+	var spec TransformationSpec
+	require.NoError(t, json.Unmarshal([]byte(`{"quality": "auto"}`), &spec))
+	require.NotNil(t, spec.Quality)
+	assert.True(t, spec.Quality.IsAuto())
+	out, err := json.Marshal(spec)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"quality": "auto"}`, string(out))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"quality": 80}`), &spec))
+	assert.Equal(t, Quality(80), *spec.Quality)
+	assert.False(t, spec.Quality.IsAuto())
+
+	require.NoError(t, json.Unmarshal([]byte(`{}`), &spec))
+	for _, invalid := range []string{`0`, `-1`, `101`, `"best"`, `50.5`} {
+		assert.Error(t, json.Unmarshal([]byte(`{"quality": `+invalid+`}`), &spec), invalid)
+	}
+}
+
+func TestParseQuality(t *testing.T) {
+	q, err := ParseQuality("auto")
+	require.NoError(t, err)
+	assert.Equal(t, QualityAuto, q)
+	q, err = ParseQuality("1")
+	require.NoError(t, err)
+	assert.Equal(t, Quality(1), q)
+	_, err = ParseQuality("0")
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
@@ -13,40 +14,296 @@ type TransformationSpec struct {
 	Resize    *ResizeSpec    `json:"resize,omitempty"`
 	Crop      *CropSpec      `json:"crop,omitempty"`
 	Rotate    *int           `json:"rotate,omitempty" binding:"omitempty,oneof=0 90 180 270"`
-	Flip      bool           `json:"flip,omitempty"`
-	Mirror    bool           `json:"mirror,omitempty"`
+	Flip      *bool          `json:"flip,omitempty"`
+	Mirror    *bool          `json:"mirror,omitempty"`
 	Watermark *WatermarkSpec `json:"watermark,omitempty"`
-	Quality   *int           `json:"quality,omitempty" binding:"omitempty,min=1,max=100"`
-	Format    *string        `json:"format,omitempty" binding:"omitempty,oneof=jpeg png webp gif"`
-	Filters   *FilterSpec    `json:"filters,omitempty"`
+	Quality   *Quality       `json:"quality,omitempty" binding:"omitempty,max=100"`
+	// MaxBytes caps the size of the variant: the highest quality, up to
+	// Quality, whose output fits is used. Rendering fails with
+	// ErrMaxBytesUnreachable when none does.
+	MaxBytes int         `json:"max_bytes,omitempty" binding:"omitempty,min=1"`
+	Format   *string     `json:"format,omitempty" binding:"omitempty,oneof=auto jpeg jpg png webp gif avif heif heic tiff tif"`
+	Filters  *FilterSpec `json:"filters,omitempty"`
+	// Frame renders the frame of that index, counting from 0, of an
+	// animation as a still image. Without it, GIF and WebP output keeps
+	// every frame.
+	Frame *int `json:"frame,omitempty" binding:"omitempty,min=0"`
+	// MaxFrames caps the frames an animation keeps, dropping frames evenly
+	// while keeping its duration.
+	MaxFrames int `json:"max_frames,omitempty" binding:"omitempty,min=1"`
+	// Metadata picks the EXIF, ICC and XMP metadata the variant keeps.
+	Metadata *MetadataSpec `json:"metadata,omitempty"`
+	// FocalPoint overrides the image's focal point for focal crops and
+	// cover resizes. ResolveFocalPoint fills it in from the image before the
+	// spec is hashed, so moving the focal point yields new variants.
+	FocalPoint *FocalPoint `json:"focal_point,omitempty"`
 }
 
+// ResizeSpec scales the image into a Width×Height box according to Fit.
+// When only one dimension is given the other follows the aspect ratio and
+// Fit is ignored. Background colours the letterbox of FitContain and any
+// transparent areas.
 type ResizeSpec struct {
-	Width  int `json:"width" binding:"required_with=Height,min=1,max=8000"`
-	Height int `json:"height" binding:"required_with=Width,min=1,max=8000"`
+	Width              int    `json:"width" binding:"required_without=Height,omitempty,min=1,max=8000"`
+	Height             int    `json:"height" binding:"required_without=Width,omitempty,min=1,max=8000"`
+	Fit                string `json:"fit,omitempty" binding:"omitempty,oneof=cover contain fill inside outside"`
+	Background         string `json:"background,omitempty" binding:"omitempty,hexcolor,len=4|len=7"`
+	WithoutEnlargement bool   `json:"without_enlargement,omitempty"`
 }
 
+// Resize fit modes.
+const (
+	// FitCover fills the box and crops what overflows.
+	FitCover = "cover"
+	// FitContain fits inside the box and letterboxes to its exact size.
+	// It never enlarges: images smaller than the box keep their size.
+	FitContain = "contain"
+	// FitFill stretches to the box, ignoring the aspect ratio.
+	FitFill = "fill"
+	// FitInside fits inside the box; the result may be smaller than it.
+	FitInside = "inside"
+	// FitOutside covers the box without cropping; the result may be larger.
+	FitOutside = "outside"
+)
+
+// EffectiveFit returns the fit mode to render with. FitContain is the
+// default as it was the only behaviour before Fit existed.
+func (r *ResizeSpec) EffectiveFit() string {
+	if r.Fit == "" {
+		return FitContain
+	}
+	return r.Fit
+}
+
+// CropSpec cuts a Width×Height area out of the rotated and flipped image.
+// Without Gravity the area starts at X,Y. Otherwise X and Y are ignored and
+// the area is placed by a compass gravity, centred on the focal point
+// (CropFocal) or placed on the most interesting region (CropEntropy,
+// CropAttention).
 type CropSpec struct {
-	Width  int `json:"width" binding:"required,min=1,max=8000"`
-	Height int `json:"height" binding:"required,min=1,max=8000"`
-	X      int `json:"x" binding:"min=0"`
-	Y      int `json:"y" binding:"min=0"`
+	Width   int    `json:"width" binding:"required,min=1,max=8000"`
+	Height  int    `json:"height" binding:"required,min=1,max=8000"`
+	X       int    `json:"x" binding:"min=0"`
+	Y       int    `json:"y" binding:"min=0"`
+	Gravity string `json:"gravity,omitempty" binding:"omitempty,oneof=center centre north south east west northeast northwest southeast southwest focal entropy attention"`
 }
 
+// Crop strategies accepted as CropSpec.Gravity besides the compass ones.
+const (
+	// CropFocal centres the crop on the focal point, or on the image
+	// centre when there is none.
+	CropFocal = "focal"
+	// CropEntropy keeps the region with the most detail.
+	CropEntropy = "entropy"
+	// CropAttention keeps the region most likely to draw the eye: edges,
+	// saturated colours and skin tones.
+	CropAttention = "attention"
+)
+
+// ErrCropOutOfBounds is returned for crops that do not fit inside the image.
+var ErrCropOutOfBounds = errors.New("crop area is outside the image")
+
+// EffectiveGravity returns the placement to crop with, folding the British
+// spelling of centre. It is empty for crops positioned by X and Y.
+func (c *CropSpec) EffectiveGravity() string {
+	if c.Gravity == "centre" {
+		return GravityCenter
+	}
+	return c.Gravity
+}
+
+// IsSmart reports whether the crop is placed by analysing the pixels.
+func (c *CropSpec) IsSmart() bool {
+	return c.Gravity == CropEntropy || c.Gravity == CropAttention
+}
+
+// CheckBounds verifies the crop fits a width×height image. X and Y only
+// count for crops without a gravity.
+func (c *CropSpec) CheckBounds(width, height int) error {
+	right, bottom := c.Width, c.Height
+	if c.Gravity == "" {
+		right, bottom = c.X+c.Width, c.Y+c.Height
+	}
+	if right > width || bottom > height {
+		if c.Gravity == "" {
+			return fmt.Errorf("%w: %dx%d at %d,%d does not fit the %dx%d image", ErrCropOutOfBounds, c.Width, c.Height, c.X, c.Y, width, height)
+		}
+		return fmt.Errorf("%w: %dx%d does not fit the %dx%d image", ErrCropOutOfBounds, c.Width, c.Height, width, height)
+	}
+	return nil
+}
+
+// WatermarkSpec overlays either Text or another image of the same owner,
+// identified by ImageID. Opacity 0 means DefaultWatermarkOpacity and an
+// empty Gravity means GravitySouthEast.
 type WatermarkSpec struct {
-	Text    string  `json:"text,omitempty"`
-	ImageID string  `json:"image_id,omitempty"`
-	Opacity float64 `json:"opacity"` // 0.0-1.0
-	Gravity string  `json:"gravity"` // center, north, southeast, etc.
+	Text    string  `json:"text,omitempty" binding:"required_without=ImageID,excluded_with=ImageID,max=200"`
+	ImageID string  `json:"image_id,omitempty" binding:"omitempty,uuid"`
+	Opacity float64 `json:"opacity" binding:"min=0,max=1"`
+	Gravity string  `json:"gravity" binding:"omitempty,oneof=center centre north south east west northeast northwest southeast southwest"`
+}
+
+// Watermark placements.
+const (
+	GravityCenter    = "center"
+	GravityNorth     = "north"
+	GravitySouth     = "south"
+	GravityEast      = "east"
+	GravityWest      = "west"
+	GravityNorthEast = "northeast"
+	GravityNorthWest = "northwest"
+	GravitySouthEast = "southeast"
+	GravitySouthWest = "southwest"
+)
+
+const DefaultWatermarkOpacity = 0.5
+
+// EffectiveOpacity returns the opacity to render with.
+func (w *WatermarkSpec) EffectiveOpacity() float64 {
+	if w.Opacity <= 0 {
+		return DefaultWatermarkOpacity
+	}
+	return w.Opacity
+}
+
+// EffectiveGravity returns the placement to render with, folding the
+// British spelling of centre.
+func (w *WatermarkSpec) EffectiveGravity() string {
+	switch w.Gravity {
+	case "":
+		return GravitySouthEast
+	case "centre":
+		return GravityCenter
+	default:
+		return w.Gravity
+	}
 }
 
+// FilterSpec holds pixel filters. They run after the geometric operations
+// and before the watermark in a fixed order: blur, sharpen, gamma,
+// brightness, contrast, saturation, grayscale, sepia, tint, invert.
+// Zero values leave the image unchanged. New fields are omitted from JSON
+// when unset, so that the Normalize form, and with it the hash, of existing
+// specs stays the same.
 type FilterSpec struct {
-	Grayscale bool `json:"grayscale,omitempty"`
-	Sepia     bool `json:"sepia,omitempty"`
-	Blur      int  `json:"blur,omitempty"` // sigma
+	Grayscale  bool    `json:"grayscale,omitempty"`
+	Sepia      bool    `json:"sepia,omitempty"`
+	Blur       int     `json:"blur,omitempty" binding:"omitempty,min=0,max=100"`      // sigma
+	Brightness float64 `json:"brightness,omitempty" binding:"omitempty,min=-1,max=1"` // offset of full scale
+	Contrast   float64 `json:"contrast,omitempty" binding:"omitempty,min=-1,max=1"`   // -1 flat, 1 doubled
+	Saturation float64 `json:"saturation,omitempty" binding:"omitempty,min=-1,max=1"` // -1 gray, 1 doubled
+	Sharpen    float64 `json:"sharpen,omitempty" binding:"omitempty,min=0,max=10"`    // strength
+	Gamma      float64 `json:"gamma,omitempty" binding:"omitempty,min=0.1,max=10"`    // 1 is neutral
+	Invert     bool    `json:"invert,omitempty"`
+	Tint       string  `json:"tint,omitempty" binding:"omitempty,hexcolor,len=4|len=7"` // #rgb or #rrggbb
+}
+
+// UsesFocalPoint reports whether rendering the spec depends on a focal point.
+func (s *TransformationSpec) UsesFocalPoint() bool {
+	if s.Crop != nil && s.Crop.Gravity == CropFocal {
+		return true
+	}
+	return s.Resize != nil && s.Resize.Width > 0 && s.Resize.Height > 0 &&
+		s.Resize.EffectiveFit() == FitCover
 }
 
+// ResolveFocalPoint copies fp into a spec that uses a focal point and does
+// not set its own.
+func (s *TransformationSpec) ResolveFocalPoint(fp *FocalPoint) {
+	if s.FocalPoint == nil && fp != nil && s.UsesFocalPoint() {
+		p := *fp
+		s.FocalPoint = &p
+	}
+}
+
+// CheckBounds verifies the crop fits an image displayed at width×height
+// with the given EXIF orientation, once the spec's rotation has been
+// applied. As in the processors, an explicit rotation replaces the EXIF
+// orientation. An orientation of 0 is unknown: the size may not be the one
+// the crop applies to, so the check is left to the processor.
+func (s *TransformationSpec) CheckBounds(width, height, orientation int) error {
+	if s.Crop == nil || orientation == 0 {
+		return nil
+	}
+	if s.Rotate != nil && *s.Rotate > 0 {
+		if orientation >= 5 {
+			width, height = height, width
+		}
+		if *s.Rotate == 90 || *s.Rotate == 270 {
+			width, height = height, width
+		}
+	}
+	return s.Crop.CheckBounds(width, height)
+}
+
+// Flipped reports whether the image is flipped horizontally.
+func (s *TransformationSpec) Flipped() bool {
+	return s.Flip != nil && *s.Flip
+}
+
+// Mirrored reports whether the image is flipped vertically.
+func (s *TransformationSpec) Mirrored() bool {
+	return s.Mirror != nil && *s.Mirror
+}
+
+// Override returns the spec with every field set in o replacing the field
+// of the same name as a whole, so a Resize in o drops all of s's Resize.
+func (s TransformationSpec) Override(o TransformationSpec) TransformationSpec {
+	if o.Resize != nil {
+		s.Resize = o.Resize
+	}
+	if o.Crop != nil {
+		s.Crop = o.Crop
+	}
+	if o.Rotate != nil {
+		s.Rotate = o.Rotate
+	}
+	if o.Flip != nil {
+		s.Flip = o.Flip
+	}
+	if o.Mirror != nil {
+		s.Mirror = o.Mirror
+	}
+	if o.Watermark != nil {
+		s.Watermark = o.Watermark
+	}
+	if o.Quality != nil {
+		s.Quality = o.Quality
+	}
+	if o.MaxBytes != 0 {
+		s.MaxBytes = o.MaxBytes
+	}
+	if o.Format != nil {
+		s.Format = o.Format
+	}
+	if o.Filters != nil {
+		s.Filters = o.Filters
+	}
+	if o.Frame != nil {
+		s.Frame = o.Frame
+	}
+	if o.MaxFrames != 0 {
+		s.MaxFrames = o.MaxFrames
+	}
+	if o.Metadata != nil {
+		s.Metadata = o.Metadata
+	}
+	if o.FocalPoint != nil {
+		s.FocalPoint = o.FocalPoint
+	}
+	return s
+}
+
+// Hash identifies the variant the spec renders: the hash of its Normalize
+// form, following SpecHashVersion.
 func (s *TransformationSpec) Hash() (string, error) {
+	n := s.Normalize()
+	return n.LegacyHash()
+}
+
+// LegacyHash is the version 1 hash of the spec as given. Variants stored
+// before SpecHashVersion 2 are found by it until they are rehashed.
+func (s *TransformationSpec) LegacyHash() (string, error) {
 	bytes, err := json.Marshal(s)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal spec for hashing: %w", err)
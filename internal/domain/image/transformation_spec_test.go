@@ -0,0 +1,95 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHexColorValidation(t *testing.T) {
+	tests := []struct {
+		colour string
+		valid  bool
+	}{
+		{"#fff", true},
+		{"#A0b1C2", true},
+		{"#ffff", false},
+		{"#ffffff80", false},
+		{"ffffff", false},
+		{"#ggg", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.colour, func(t *testing.T) {
+			spec := &TransformationSpec{
+				Resize:  &ResizeSpec{Width: 10, Background: tt.colour},
+				Filters: &FilterSpec{Tint: tt.colour},
+			}
+			err := binding.Validator.ValidateStruct(spec)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckBounds(t *testing.T) {
+	rotate := func(deg int) *int { return &deg }
+	// A 300×100 crop at the origin fits the 400×200 image as displayed.
+	tests := []struct {
+		name        string
+		rotate      *int
+		orientation int
+		fits        bool
+	}{
+		{"upright", nil, 1, true},
+		{"exif rotated, already displayed upright", nil, 6, true},
+		{"explicit quarter turn", rotate(90), 1, false},
+		{"explicit half turn", rotate(180), 1, true},
+		{"explicit turn replaces exif", rotate(180), 6, false},
+		{"explicit quarter turn of exif rotated", rotate(270), 8, true},
+		{"unknown orientation", rotate(90), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &TransformationSpec{
+				Rotate: tt.rotate,
+				Crop:   &CropSpec{Width: 300, Height: 100},
+			}
+			err := spec.CheckBounds(400, 200, tt.orientation)
+			if tt.fits {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrCropOutOfBounds)
+			}
+		})
+	}
+}
+
+func TestOverrideFlipAndMirror(t *testing.T) {
+	on, off := true, false
+	preset := TransformationSpec{Flip: &on, Mirror: &on, Rotate: intPtr(90)}
+
+	s := preset.Override(TransformationSpec{Flip: &off})
+	assert.False(t, s.Flipped(), "an explicit false turns the preset's flip off")
+	assert.True(t, s.Mirrored(), "unset fields keep the preset's value")
+	assert.Equal(t, 90, *s.Rotate)
+
+	plain := TransformationSpec{Rotate: intPtr(90), Mirror: &on}
+	assertSameHash(t, &s, &plain)
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func assertSameHash(t *testing.T, a, b *TransformationSpec) {
+	t.Helper()
+	ha, err := a.Hash()
+	assert.NoError(t, err)
+	hb, err := b.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, ha, hb)
+}
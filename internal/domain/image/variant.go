@@ -17,6 +17,15 @@ type Variant struct {
 	Width      int       `json:"width"`
 	Height     int       `json:"height"`
 	CreatedAt  time.Time `json:"created_at"`
+	// Quality is the encoder quality the variant was saved at, the one
+	// picked for quality auto and max_bytes. It is zero for lossless formats
+	// and variants stored before it was recorded.
+	Quality int `json:"quality,omitempty"`
+	// Spec is the spec the variant was rendered from; it is nil for
+	// variants stored before specs were recorded.
+	Spec *TransformationSpec `json:"-"`
+	// SpecHashVersion is the SpecHashVersion SpecHash was computed with.
+	SpecHashVersion int `json:"-"`
 }
 
 var (
@@ -34,13 +43,14 @@ func NewVariant(key, specHash, mimeType string, size int64, width, height int) (
 	}
 
 	return &Variant{
-		ID:         uuid.New(),
-		VariantKey: key,
-		SpecHash:   specHash,
-		Size:       size,
-		MimeType:   mimeType,
-		Width:      width,
-		Height:     height,
-		CreatedAt:  time.Now().UTC(),
+		ID:              uuid.New(),
+		VariantKey:      key,
+		SpecHash:        specHash,
+		Size:            size,
+		MimeType:        mimeType,
+		Width:           width,
+		Height:          height,
+		CreatedAt:       time.Now().UTC(),
+		SpecHashVersion: SpecHashVersion,
 	}, nil
 }
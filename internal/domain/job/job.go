@@ -0,0 +1,114 @@
+package job
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+)
+
+// JobID is a strongly typed identifier for a transform job.
+type JobID string
+
+// Status is the lifecycle state of a transform job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Job tracks an asynchronous transformation from publication to completion.
+type Job struct {
+	ID          JobID                     `json:"id"`
+	ImageID     image.ImageID             `json:"image_id"`
+	OwnerID     user.UserID               `json:"owner_id"`
+	Spec        *image.TransformationSpec `json:"spec"`
+	SpecHash    string                    `json:"spec_hash"`
+	Status      Status                    `json:"status"`
+	Attempts    int                       `json:"attempts"`
+	Error       string                    `json:"error,omitempty"`
+	VariantID   *uuid.UUID                `json:"variant_id,omitempty"`
+	CreatedAt   time.Time                 `json:"created_at"`
+	UpdatedAt   time.Time                 `json:"updated_at"`
+	StartedAt   *time.Time                `json:"started_at,omitempty"`
+	CompletedAt *time.Time                `json:"completed_at,omitempty"`
+}
+
+var (
+	ErrInvalidImageID = errors.New("invalid image ID")
+	ErrInvalidSpec    = errors.New("invalid transformation spec")
+)
+
+// New creates a queued Job for the given image and spec.
+func New(imageID image.ImageID, ownerID user.UserID, spec *image.TransformationSpec, specHash string) (*Job, error) {
+	if imageID == "" {
+		return nil, ErrInvalidImageID
+	}
+	if spec == nil || specHash == "" {
+		return nil, ErrInvalidSpec
+	}
+
+	now := time.Now().UTC()
+	return &Job{
+		ID:        JobID(uuid.New().String()),
+		ImageID:   imageID,
+		OwnerID:   ownerID,
+		Spec:      spec,
+		SpecHash:  specHash,
+		Status:    StatusQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Start marks the job as running and counts the attempt.
+func (j *Job) Start() {
+	now := time.Now().UTC()
+	j.Status = StatusRunning
+	j.Attempts++
+	j.Error = ""
+	j.StartedAt = &now
+	j.UpdatedAt = now
+}
+
+// Succeed marks the job as done and records the produced variant.
+func (j *Job) Succeed(variantID uuid.UUID) {
+	now := time.Now().UTC()
+	j.Status = StatusSucceeded
+	j.Error = ""
+	j.VariantID = &variantID
+	j.CompletedAt = &now
+	j.UpdatedAt = now
+}
+
+// Retry puts the job back in the queued state after a failed attempt that
+// will be retried, keeping the cause visible to clients.
+func (j *Job) Retry(cause error) {
+	j.Status = StatusQueued
+	if cause != nil {
+		j.Error = cause.Error()
+	}
+	j.UpdatedAt = time.Now().UTC()
+}
+
+// Fail marks the job as failed with the given cause.
+func (j *Job) Fail(cause error) {
+	now := time.Now().UTC()
+	j.Status = StatusFailed
+	if cause != nil {
+		j.Error = cause.Error()
+	}
+	j.CompletedAt = &now
+	j.UpdatedAt = now
+}
+
+// IsTerminal reports whether the job will not change state anymore.
+func (j *Job) IsTerminal() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
@@ -0,0 +1,67 @@
+package preset
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+
+	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/user"
+)
+
+// PresetID is a strongly typed identifier for a preset.
+type PresetID string
+
+// Preset is a TransformationSpec a user saved under a name. Every change of
+// the spec is a new Version; earlier versions stay available.
+type Preset struct {
+	ID      PresetID                 `json:"id"`
+	OwnerID user.UserID              `json:"owner_id"`
+	Name    string                   `json:"name"`
+	Version int                      `json:"version"`
+	Spec    image.TransformationSpec `json:"spec"`
+	// Eager presets are queued for every image the owner uploads. The flag
+	// is not versioned.
+	Eager bool `json:"eager"`
+	// CreatedAt is when the preset was first saved and UpdatedAt when
+	// Version was.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var (
+	ErrInvalidName    = errors.New("preset name must be 1-64 letters, digits, '-' or '_', starting with a letter or digit")
+	ErrInvalidOwnerID = errors.New("invalid owner ID")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// New creates the first version of a preset.
+func New(ownerID user.UserID, name string, spec image.TransformationSpec) (*Preset, error) {
+	if ownerID == "" {
+		return nil, ErrInvalidOwnerID
+	}
+	if !namePattern.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	now := time.Now().UTC()
+	return &Preset{
+		ID:        PresetID(uuid.New().String()),
+		OwnerID:   ownerID,
+		Name:      name,
+		Version:   1,
+		Spec:      spec,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Revise replaces the spec as the next version.
+func (p *Preset) Revise(spec image.TransformationSpec) {
+	p.Version++
+	p.Spec = spec
+	p.UpdatedAt = time.Now().UTC()
+}
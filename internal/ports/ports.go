@@ -2,10 +2,16 @@ package ports
 
 import (
 	"context"
+	"errors"
 	"io"
+	"slices"
 	"time"
 
+	"github.com/google/uuid"
+
 	"image-processing-service/internal/domain/image"
+	"image-processing-service/internal/domain/job"
+	"image-processing-service/internal/domain/preset"
 	"image-processing-service/internal/domain/user"
 )
 
@@ -17,12 +23,74 @@ type UserRepository interface {
 }
 
 // ImageRepository defines persistence operations for images and variants.
+// GetByID and List never return images in the trash.
 type ImageRepository interface {
 	Save(ctx context.Context, img *image.Image) error
 	GetByID(ctx context.Context, id image.ImageID) (*image.Image, error)
-	List(ctx context.Context, ownerID user.UserID, offset, limit int) ([]*image.Image, int, error)
+	Update(ctx context.Context, img *image.Image) error
+	// Delete removes an image with its variants and jobs, trashed or not.
+	Delete(ctx context.Context, id image.ImageID) error
+	// DeleteVariant removes one variant of an image; it reports whether the
+	// variant existed.
+	DeleteVariant(ctx context.Context, imageID image.ImageID, variantID uuid.UUID) (bool, error)
+	// List returns a page of the images matching the criteria and the
+	// number of matches across all pages.
+	List(ctx context.Context, criteria image.ListCriteria) ([]*image.Image, int, error)
+
+	// Trash sets the image's DeletedAt; it reports false when the image does
+	// not exist or is already trashed.
+	Trash(ctx context.Context, id image.ImageID, deletedAt time.Time) (bool, error)
+	// Restore clears DeletedAt if it is after deletedAfter.
+	Restore(ctx context.Context, id image.ImageID, deletedAfter time.Time) (bool, error)
+	// GetTrashed returns an image in the trash, or nil.
+	GetTrashed(ctx context.Context, id image.ImageID) (*image.Image, error)
+	ListTrashed(ctx context.Context, ownerID user.UserID, offset, limit int) ([]*image.Image, int, error)
+	// ListExpiredTrash returns images trashed at or before deletedBefore.
+	ListExpiredTrash(ctx context.Context, deletedBefore time.Time, limit int) ([]image.ImageID, error)
+	// Purge deletes a trashed image if it was trashed at or before
+	// deletedBefore; it reports false when it was restored meanwhile.
+	Purge(ctx context.Context, id image.ImageID, deletedBefore time.Time) (bool, error)
+
 	SaveVariant(ctx context.Context, imageID image.ImageID, variant *image.Variant) error
 	GetVariantBySpecHash(ctx context.Context, imageID image.ImageID, specHash string) (*image.Variant, error)
+	// ListStaleVariants returns up to limit variants hashed with a
+	// SpecHashVersion below version whose spec is known, ordered by ID after
+	// the given one.
+	ListStaleVariants(ctx context.Context, version int, after uuid.UUID, limit int) ([]*image.Variant, error)
+	// RehashVariant sets a variant's spec hash and its version, along with
+	// the hash of the jobs that rendered it. It reports false when another
+	// variant of the image already has that hash.
+	RehashVariant(ctx context.Context, id uuid.UUID, specHash string, version int) (bool, error)
+}
+
+// JobRepository defines persistence operations for transform job status.
+type JobRepository interface {
+	Save(ctx context.Context, j *job.Job) error
+	GetByID(ctx context.Context, id job.JobID) (*job.Job, error)
+	Update(ctx context.Context, j *job.Job) error
+}
+
+// PresetRepository defines persistence operations for presets and their
+// versions. Presets are looked up by owner and name.
+type PresetRepository interface {
+	// Create stores the first version of a preset; it reports false when the
+	// owner already has a preset of that name.
+	Create(ctx context.Context, p *preset.Preset) (bool, error)
+	// Get returns a version of a preset, or nil. Version 0 is the latest.
+	Get(ctx context.Context, ownerID user.UserID, name string, version int) (*preset.Preset, error)
+	List(ctx context.Context, ownerID user.UserID) ([]*preset.Preset, error)
+	// ListVersions returns every version of a preset, newest first.
+	ListVersions(ctx context.Context, ownerID user.UserID, name string) ([]*preset.Preset, error)
+	// Revise stores p.Spec as version p.Version; it reports false when the
+	// latest stored version is not the one before, because the preset was
+	// revised or deleted meanwhile.
+	Revise(ctx context.Context, p *preset.Preset) (bool, error)
+	// SetEager changes whether a preset is queued for uploads without adding
+	// a version.
+	SetEager(ctx context.Context, id preset.PresetID, eager bool) error
+	// Delete removes a preset with all its versions; it reports whether the
+	// preset existed.
+	Delete(ctx context.Context, ownerID user.UserID, name string) (bool, error)
 }
 
 // ObjectStorage defines operations for storing and retrieving binary objects.
@@ -30,9 +98,33 @@ type ObjectStorage interface {
 	Put(ctx context.Context, key string, reader io.Reader, contentType string, size int64) (string, error)
 	Get(ctx context.Context, key string) (io.ReadCloser, error)
 	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// Delete removes an object; deleting a missing object is not an error.
 	Delete(ctx context.Context, key string) error
 }
 
+// StorageDeletion is an object whose deletion from ObjectStorage failed
+// and is retried in the background.
+type StorageDeletion struct {
+	Key      string
+	Attempts int
+}
+
+// StorageDeletionRepository persists pending ObjectStorage deletions so
+// they survive restarts.
+type StorageDeletionRepository interface {
+	// Add schedules keys for deletion at notBefore. Keys already pending keep
+	// their schedule.
+	Add(ctx context.Context, keys []string, notBefore time.Time) error
+	// Claim returns up to limit deletions that are due and counts an attempt
+	// for each. Claimed deletions are not returned again until lease passes,
+	// so several processes can retry concurrently.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]StorageDeletion, error)
+	// Reschedule records a failed attempt and when to try again.
+	Reschedule(ctx context.Context, key string, next time.Time, lastErr string) error
+	// Remove forgets a deletion once the object is gone.
+	Remove(ctx context.Context, key string) error
+}
+
 // ProcessedImage represents the result of an image transformation.
 type ProcessedImage struct {
 	Data     []byte
@@ -40,22 +132,73 @@ type ProcessedImage struct {
 	Width    int
 	Height   int
 	Size     int64
+	// Quality is the encoder quality the image was saved at; zero for
+	// lossless formats.
+	Quality int
 }
 
-// ImageMetadata represents basic metadata extracted from an image.
+// ImageMetadata represents basic metadata extracted from an image. Width and
+// Height are as displayed, after the EXIF orientation is applied.
 type ImageMetadata struct {
 	Width    int
 	Height   int
 	MimeType string
 	Size     int64
+	HasAlpha bool
+	// Orientation is the EXIF orientation, 1 to 8; 1 means upright.
+	Orientation int
+	// Animation is set for animated images.
+	Animation *image.Animation
+	// Photo is the camera, capture and colour metadata, nil when the image
+	// records none.
+	Photo *image.PhotoMetadata
+}
+
+// TransformAssets carries the inputs a TransformationSpec refers to by ID.
+// They are loaded by the application layer so processors never touch storage.
+type TransformAssets struct {
+	// WatermarkImage is the encoded image named by Watermark.ImageID.
+	WatermarkImage []byte
+}
+
+// ErrUnsupportedOperation is returned by an ImageProcessor for a spec it
+// cannot render, such as an output format its encoder lacks.
+var ErrUnsupportedOperation = errors.New("operation not supported by image processor")
+
+// ProcessorCapabilities lists the formats an ImageProcessor can decode and
+// encode, by the names a TransformationSpec uses.
+type ProcessorCapabilities struct {
+	Input  []string `json:"input"`
+	Output []string `json:"output"`
+}
+
+// CanOutput reports whether format is one of the output formats.
+func (c ProcessorCapabilities) CanOutput(format string) bool {
+	return slices.Contains(c.Output, image.CanonicalFormat(format))
 }
 
 // ImageProcessor defines operations for transforming images.
 type ImageProcessor interface {
-	Transform(ctx context.Context, srcReader io.Reader, spec *image.TransformationSpec) (*ProcessedImage, error)
+	Transform(ctx context.Context, srcReader io.Reader, spec *image.TransformationSpec, assets *TransformAssets) (*ProcessedImage, error)
 	ExtractMetadata(ctx context.Context, reader io.Reader) (*ImageMetadata, error)
+	// Capabilities reports the formats the processor supports. It does not
+	// change while the process runs.
+	Capabilities() ProcessorCapabilities
 }
 
+// URLSigner signs the parameters of public URLs with rotatable keys.
+type URLSigner interface {
+	// Sign returns the ID of the active key and the payload's signature.
+	Sign(payload string) (keyID, signature string)
+	// Verify checks a signature made with any configured key and returns
+	// ErrInvalidSignature when it does not match.
+	Verify(payload, keyID, signature string) error
+}
+
+// ErrInvalidSignature is returned by a URLSigner for forged or tampered
+// signatures and for unknown key IDs.
+var ErrInvalidSignature = errors.New("invalid signature")
+
 // Cache defines operations for temporary key-value storage.
 type Cache interface {
 	Get(ctx context.Context, key string) (string, error)
@@ -77,14 +220,49 @@ type TransformJob struct {
 	VariantID string                    `json:"variant_id"`
 	OwnerID   string                    `json:"owner_id"`
 	Spec      *image.TransformationSpec `json:"spec"`
-	SpecHash  string                    `json:"spec_hash"`
-	CreatedAt time.Time                 `json:"created_at"`
+	// SpecHash is the hash the publisher computed, for logs. Workers hash
+	// Spec themselves, as messages may be queued across a change of
+	// SpecHashVersion.
+	SpecHash  string    `json:"spec_hash"`
+	CreatedAt time.Time `json:"created_at"`
+	// Items turns the message into a batch of jobs on ImageID, processed one
+	// after the other; Spec and SpecHash are unused and JobID only names the
+	// batch in logs.
+	Items []TransformJobItem `json:"items,omitempty"`
+
+	// Attempt and MaxAttempts are filled in by the Queue on delivery and are
+	// not part of the message body. MaxAttempts is zero when retries are not
+	// bounded by the queue.
+	Attempt     int `json:"-"`
+	MaxAttempts int `json:"-"`
+}
+
+// ErrPermanentFailure marks handler errors that retrying cannot fix. Queues
+// dead-letter such jobs instead of scheduling another attempt.
+var ErrPermanentFailure = errors.New("permanent job failure")
+
+// IsFinalAttempt reports whether a failure of this delivery will not be retried.
+func (j *TransformJob) IsFinalAttempt() bool {
+	return j.MaxAttempts > 0 && j.Attempt >= j.MaxAttempts
+}
+
+// IsBatch reports whether the message carries Items.
+func (j *TransformJob) IsBatch() bool {
+	return len(j.Items) > 0
+}
+
+// TransformJobItem is one job of a batched TransformJob.
+type TransformJobItem struct {
+	JobID    string                    `json:"job_id"`
+	Spec     *image.TransformationSpec `json:"spec"`
+	SpecHash string                    `json:"spec_hash"`
 }
 
 // Queue defines operations for asynchronous job processing.
 type Queue interface {
 	Publish(ctx context.Context, job *TransformJob) error
 	Consume(ctx context.Context, handler func(*TransformJob) error) error
+	Close() error
 }
 
 // AuthProvider defines operations for token management.
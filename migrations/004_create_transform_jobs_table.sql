@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS transform_jobs (
+    id UUID PRIMARY KEY,
+    image_id UUID NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    spec JSONB NOT NULL,
+    spec_hash TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    variant_id UUID REFERENCES variants(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transform_jobs_image_id ON transform_jobs(image_id);
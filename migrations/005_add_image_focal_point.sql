@@ -0,0 +1,2 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS focal_x DOUBLE PRECISION;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS focal_y DOUBLE PRECISION;
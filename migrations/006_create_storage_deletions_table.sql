@@ -0,0 +1,11 @@
+-- Stored objects of deleted images and variants whose deletion failed,
+-- retried in the background
+CREATE TABLE IF NOT EXISTS storage_deletions (
+    object_key TEXT PRIMARY KEY,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_storage_deletions_next_attempt_at ON storage_deletions(next_attempt_at);
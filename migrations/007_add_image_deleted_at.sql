@@ -0,0 +1,4 @@
+-- Soft delete: trashed images keep their rows until the purger removes them
+ALTER TABLE images ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_images_deleted_at ON images(deleted_at) WHERE deleted_at IS NOT NULL;
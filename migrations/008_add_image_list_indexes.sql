@@ -0,0 +1,8 @@
+-- Keyset pagination over live images for each sort field
+CREATE INDEX IF NOT EXISTS idx_images_owner_created_at ON images(owner_id, created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_images_owner_size ON images(owner_id, size, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_images_owner_filename ON images(owner_id, filename, id) WHERE deleted_at IS NULL;
+
+-- Filename substring filter (ILIKE '%...%')
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_images_filename_trgm ON images USING GIN (filename gin_trgm_ops);
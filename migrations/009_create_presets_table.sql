@@ -0,0 +1,20 @@
+-- Named transformation specs saved by users; revising a preset adds a version
+CREATE TABLE IF NOT EXISTS presets (
+    id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    version INTEGER NOT NULL,
+    spec JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (owner_id, name)
+);
+
+-- Every version of every preset, including the latest one held in presets.
+CREATE TABLE IF NOT EXISTS preset_versions (
+    preset_id UUID NOT NULL REFERENCES presets(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    spec JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (preset_id, version)
+);
@@ -0,0 +1,2 @@
+-- Eager presets are rendered for every image their owner uploads
+ALTER TABLE presets ADD COLUMN IF NOT EXISTS eager BOOLEAN NOT NULL DEFAULT FALSE;
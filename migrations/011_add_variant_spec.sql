@@ -0,0 +1,10 @@
+-- Variants record their spec and the SpecHashVersion of their hash, so that
+-- `migrate -rehash` can move them to the current version. Existing rows hold
+-- version 1 hashes.
+ALTER TABLE variants ADD COLUMN IF NOT EXISTS spec JSONB;
+ALTER TABLE variants ADD COLUMN IF NOT EXISTS spec_hash_version INTEGER NOT NULL DEFAULT 1;
+
+-- Recover the spec of variants rendered by async jobs
+UPDATE variants v SET spec = j.spec
+FROM transform_jobs j
+WHERE j.variant_id = v.id AND j.spec_hash = v.spec_hash AND v.spec IS NULL;
@@ -0,0 +1,3 @@
+-- Whether the original has an alpha channel, for picking the format of
+-- format=auto; NULL for images uploaded before it was recorded
+ALTER TABLE images ADD COLUMN IF NOT EXISTS has_alpha BOOLEAN;
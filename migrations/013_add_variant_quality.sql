@@ -0,0 +1,3 @@
+-- The encoder quality a variant was saved at, as chosen for quality auto and
+-- max_bytes; NULL for lossless formats and variants stored before it
+ALTER TABLE variants ADD COLUMN IF NOT EXISTS quality INTEGER;
@@ -0,0 +1,5 @@
+-- Frames of animated GIF and WebP originals; NULL for still images and
+-- images uploaded before animations were recorded
+ALTER TABLE images ADD COLUMN IF NOT EXISTS frame_count INTEGER;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS loop_count INTEGER;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS duration_ms INTEGER;
@@ -0,0 +1,3 @@
+-- Camera, capture time, GPS position and colour profile read from the
+-- original; NULL when it records none or was uploaded before they were read
+ALTER TABLE images ADD COLUMN IF NOT EXISTS metadata JSONB;
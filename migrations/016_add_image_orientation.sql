@@ -0,0 +1,3 @@
+-- EXIF orientation of the stored original, for checking crops against the
+-- image as displayed; NULL for images uploaded before it was recorded
+ALTER TABLE images ADD COLUMN IF NOT EXISTS orientation SMALLINT;
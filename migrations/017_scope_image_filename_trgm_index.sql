@@ -0,0 +1,5 @@
+-- Filename substring filter, over the same rows as the list query: an
+-- owner's live images. btree_gin lets the GIN index lead with owner_id.
+CREATE EXTENSION IF NOT EXISTS btree_gin;
+CREATE INDEX IF NOT EXISTS idx_images_owner_filename_trgm ON images USING GIN (owner_id, filename gin_trgm_ops) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_images_filename_trgm;
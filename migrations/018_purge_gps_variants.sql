@@ -0,0 +1,22 @@
+-- Variants rendered before kept metadata had its GPS position stripped may
+-- carry the position of their original. Rows from then are not gps_checked;
+-- those of originals that record a position, or were uploaded before
+-- metadata was read, are deleted unless their spec keeps GPS. Their objects
+-- are deleted in the background and they are rendered anew on request.
+ALTER TABLE variants ADD COLUMN IF NOT EXISTS gps_checked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE variants ALTER COLUMN gps_checked SET DEFAULT TRUE;
+
+WITH purged AS (
+    DELETE FROM variants v
+    USING images i
+    WHERE v.image_id = i.id
+      AND NOT v.gps_checked
+      AND (i.metadata -> 'gps' IS NOT NULL OR i.orientation IS NULL)
+      AND COALESCE(v.spec #>> '{metadata,gps}', '') <> 'true'
+    RETURNING v.variant_key
+)
+INSERT INTO storage_deletions (object_key)
+SELECT variant_key FROM purged
+ON CONFLICT (object_key) DO NOTHING;
+
+UPDATE variants SET gps_checked = TRUE WHERE NOT gps_checked;
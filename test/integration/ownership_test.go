@@ -0,0 +1,146 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/container"
+	"image-processing-service/internal/database"
+)
+
+func TestImageOwnershipIntegration(t *testing.T) {
+	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
+		t.Skip("Skipping integration test; RUN_INTEGRATION_TESTS not set to true")
+	}
+	t.Setenv("QUEUE_DRIVER", "memory")
+	t.Setenv("URL_SIGNING_KEYS", "test:ownership-test-secret")
+
+	c, err := container.NewContainer()
+	require.NoError(t, err)
+	defer c.Close()
+
+	err = database.RunMigrations(context.Background(), c.DB, "../../migrations")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	authMiddleware := c.AuthMiddleware.Handle()
+	r.POST("/auth/register", c.AuthHandler.Register)
+	r.POST("/auth/login", c.AuthHandler.Login)
+	r.POST("/images", authMiddleware, c.ImageHandler.Upload)
+	r.GET("/images/:id", authMiddleware, c.ImageHandler.Get)
+	r.GET("/images/:id/metadata", authMiddleware, c.ImageHandler.GetMetadata)
+	r.POST("/images/:id/transform", authMiddleware, c.ImageHandler.Transform)
+	r.GET("/images/:id/render", authMiddleware, c.ImageHandler.Render)
+	r.GET("/images/:id/original", authMiddleware, c.ImageHandler.Original)
+	r.POST("/images/:id/signed-urls", authMiddleware, c.ImageHandler.SignRenderURL)
+	r.GET("/api/v1/public/images/:id/render", c.PublicHandler.Render)
+
+	owner := loginAs(t, r, fmt.Sprintf("owner_%d", os.Getpid()))
+	other := loginAs(t, r, fmt.Sprintf("other_%d", os.Getpid()))
+	imageID := uploadTestImage(t, r, owner)
+
+	spec := `{"resize":{"width":10,"height":10},"format":"png"}`
+	do := func(token, method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Owner Has Access", func(t *testing.T) {
+		w := do(owner, "GET", "/images/"+imageID, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = do(owner, "GET", fmt.Sprintf("/images/%s/metadata", imageID), "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = do(owner, "POST", fmt.Sprintf("/images/%s/transform?sync=true", imageID), spec)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	// Other users get the same answer as for an image that does not exist,
+	// including once the owner's request has cached the image.
+	t.Run("Other User Sees Not Found", func(t *testing.T) {
+		requests := []struct {
+			method, path, body string
+		}{
+			{"GET", "/images/" + imageID, ""},
+			{"GET", fmt.Sprintf("/images/%s/metadata", imageID), ""},
+			{"POST", fmt.Sprintf("/images/%s/transform?sync=true", imageID), spec},
+			{"POST", fmt.Sprintf("/images/%s/transform", imageID), spec},
+			{"GET", fmt.Sprintf("/images/%s/render?w=10&h=10", imageID), ""},
+			{"GET", fmt.Sprintf("/images/%s/original", imageID), ""},
+			{"POST", fmt.Sprintf("/images/%s/signed-urls?w=10", imageID), ""},
+		}
+		for _, tc := range requests {
+			w := do(other, tc.method, tc.path, tc.body)
+			assert.Equal(t, http.StatusNotFound, w.Code, "%s %s: %s", tc.method, tc.path, w.Body.String())
+
+			missing := strings.Replace(tc.path, imageID, "00000000-0000-0000-0000-000000000000", 1)
+			wMissing := do(other, tc.method, missing, tc.body)
+			assert.Equal(t, wMissing.Body.String(), w.Body.String(), "%s %s leaks existence", tc.method, tc.path)
+		}
+	})
+
+	t.Run("Tampered Signed URL Is Forbidden", func(t *testing.T) {
+		w := do(owner, "POST", fmt.Sprintf("/images/%s/signed-urls?w=10", imageID), "")
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var signed struct {
+			URL string `json:"url"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &signed))
+
+		w = do("", "GET", signed.URL, "")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = do("", "GET", strings.Replace(signed.URL, "w=10", "w=20", 1), "")
+		assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	})
+}
+
+// loginAs registers username if needed and returns a token for it. The
+// caller registers the auth routes.
+func loginAs(t *testing.T, r *gin.Engine, username string) string {
+	body := fmt.Sprintf(`{"username":"%s", "password":"Password123!"}`, username)
+
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated && w.Code != http.StatusConflict {
+		t.Fatalf("Expected 201 Created or 409 Conflict for register, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/auth/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var loginResp struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &loginResp))
+	return loginResp.Token
+}
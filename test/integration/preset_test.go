@@ -0,0 +1,160 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"image-processing-service/internal/container"
+	"image-processing-service/internal/database"
+)
+
+func TestPresetIntegration(t *testing.T) {
+	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
+		t.Skip("Skipping integration test; RUN_INTEGRATION_TESTS not set to true")
+	}
+	t.Setenv("QUEUE_DRIVER", "memory")
+
+	c, err := container.NewContainer()
+	require.NoError(t, err)
+	defer c.Close()
+
+	err = database.RunMigrations(context.Background(), c.DB, "../../migrations")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	authMiddleware := c.AuthMiddleware.Handle()
+	r.POST("/auth/register", c.AuthHandler.Register)
+	r.POST("/auth/login", c.AuthHandler.Login)
+	r.POST("/images", authMiddleware, c.ImageHandler.Upload)
+	r.POST("/images/:id/transform", authMiddleware, c.ImageHandler.Transform)
+	r.POST("/presets", authMiddleware, c.PresetHandler.Create)
+	r.GET("/presets", authMiddleware, c.PresetHandler.List)
+	r.GET("/presets/:name", authMiddleware, c.PresetHandler.Get)
+	r.GET("/presets/:name/versions", authMiddleware, c.PresetHandler.Versions)
+	r.PUT("/presets/:name", authMiddleware, c.PresetHandler.Update)
+	r.DELETE("/presets/:name", authMiddleware, c.PresetHandler.Delete)
+
+	token := loginAs(t, r, fmt.Sprintf("presets_%d", os.Getpid()))
+	imageID := uploadTestImage(t, r, token)
+	name := fmt.Sprintf("thumb-%d", os.Getpid())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	specHash := func(w *httptest.ResponseRecorder) string {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result struct {
+			SpecHash string `json:"spec_hash"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result.SpecHash
+	}
+	transform := fmt.Sprintf("/images/%s/transform?sync=true", imageID)
+
+	w := do("POST", "/presets", fmt.Sprintf(`{"name":"%s","spec":{"resize":{"width":20,"height":20},"format":"png"}}`, name))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = do("POST", "/presets", fmt.Sprintf(`{"name":"%s","spec":{"format":"png"}}`, name))
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	t.Run("Preset Dedupes With Inline Spec", func(t *testing.T) {
+		viaPreset := specHash(do("POST", transform, fmt.Sprintf(`{"preset":"%s"}`, name)))
+		inline := specHash(do("POST", transform, `{"resize":{"width":20,"height":20},"format":"png"}`))
+		assert.Equal(t, inline, viaPreset)
+
+		overridden := specHash(do("POST", transform, fmt.Sprintf(`{"preset":"%s","format":"jpeg"}`, name)))
+		inlineJPEG := specHash(do("POST", transform, `{"resize":{"width":20,"height":20},"format":"jpeg"}`))
+		assert.Equal(t, inlineJPEG, overridden)
+	})
+
+	t.Run("Updates Add Versions", func(t *testing.T) {
+		w := do("PUT", "/presets/"+name, `{"spec":{"resize":{"width":30,"height":30},"format":"png"}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var p struct {
+			Version int `json:"version"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, 2, p.Version)
+
+		v1 := specHash(do("POST", transform, fmt.Sprintf(`{"preset":"%s","preset_version":1}`, name)))
+		inline := specHash(do("POST", transform, `{"resize":{"width":20,"height":20},"format":"png"}`))
+		assert.Equal(t, inline, v1)
+
+		w = do("GET", "/presets/"+name+"/versions", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var versions struct {
+			Versions []struct {
+				Version int `json:"version"`
+			} `json:"versions"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &versions))
+		require.Len(t, versions.Versions, 2)
+		assert.Equal(t, 2, versions.Versions[0].Version)
+	})
+
+	t.Run("Eager Uploads", func(t *testing.T) {
+		w := do("PUT", "/presets/"+name, `{"eager":true}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "test.png")
+		_, _ = part.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x02\x00\x00\x00\x90wS\xde\x00\x00\x00\x0cIDAT\x08\xd7\x63\xf8\xff\xff\x3f\x00\x05\xfe\x02\xfe\xdc\x44\x74\x73\x00\x00\x00\x00IEND\xaeB`\x82"))
+		_ = writer.WriteField("eager", `[{"format":"jpeg"},{"preset":"does-not-exist"}]`)
+		_ = writer.Close()
+
+		req, _ := http.NewRequest("POST", "/images", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var resp struct {
+			Jobs []struct {
+				Status string `json:"status"`
+				JobID  string `json:"job_id"`
+			} `json:"jobs"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Jobs, 3)
+		assert.NotEmpty(t, resp.Jobs[0].JobID)
+		assert.Equal(t, "failed", resp.Jobs[1].Status)
+		assert.NotEmpty(t, resp.Jobs[2].JobID)
+
+		w = do("PUT", "/presets/"+name, `{"eager":false}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("Unknown Presets", func(t *testing.T) {
+		w := do("POST", transform, `{"preset":"does-not-exist"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+		w = do("DELETE", "/presets/"+name, "")
+		assert.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+		w = do("GET", "/presets/"+name, "")
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	})
+}
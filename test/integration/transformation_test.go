@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -23,6 +25,7 @@ func TestSyncTransformationIntegration(t *testing.T) {
 	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
 		t.Skip("Skipping integration test; RUN_INTEGRATION_TESTS not set to true")
 	}
+	t.Setenv("QUEUE_DRIVER", "memory")
 
 	c, err := container.NewContainer()
 	require.NoError(t, err)
@@ -94,6 +97,240 @@ func TestSyncTransformationIntegration(t *testing.T) {
 	assert.Equal(t, variantID1, result2["id"].(string), "Expected same variant ID (deduplication)")
 }
 
+func TestSyncTransformationNormalizationIntegration(t *testing.T) {
+	r, token, imageID := setupSyncTransform(t)
+
+	w, first := syncTransform(t, r, token, imageID, `{"resize":{"width":200,"height":200},"format":"webp","quality":80}`, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// The same transformation spelled differently
+	equivalent := `{"resize":{"width":200,"height":200,"fit":"contain"},"format":"webp","quality":80,"rotate":0,"filters":{"gamma":1}}`
+	w, result := syncTransform(t, r, token, imageID, equivalent, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, first["id"], result["id"], "Expected same variant ID for an equivalent spec")
+}
+
+func TestFormatAutoIntegration(t *testing.T) {
+	r, token, imageID := setupSyncTransform(t)
+
+	w, webp := syncTransform(t, r, token, imageID, `{"resize":{"width":200,"height":200},"format":"webp","quality":80}`, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// format auto picks from the Accept header and shares the variant of the
+	// format it picked
+	auto := `{"resize":{"width":200,"height":200},"format":"auto","quality":80}`
+	w, result := syncTransform(t, r, token, imageID, auto, "image/webp,image/*,*/*;q=0.8")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.Equal(t, webp["id"], result["id"], "Expected the WebP variant for format auto")
+
+	w, result = syncTransform(t, r, token, imageID, auto, "*/*")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotEqual(t, "image/webp", result["mime_type"], "Wildcards should not select WebP")
+}
+
+func TestQualityAutoIntegration(t *testing.T) {
+	r, token, imageID := setupSyncTransform(t)
+
+	// quality auto and max_bytes report the quality they picked
+	searched := `{"resize":{"width":200,"height":200},"format":"jpeg","quality":"auto","max_bytes":20000}`
+	w, result := syncTransform(t, r, token, imageID, searched, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.NotZero(t, result["quality"])
+	assert.LessOrEqual(t, result["size"], float64(20000))
+}
+
+func TestStillFrameIntegration(t *testing.T) {
+	r, token, imageID := setupSyncTransform(t)
+
+	// Still images have frame 0 only
+	w, _ := syncTransform(t, r, token, imageID, `{"frame":0}`, "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w, _ = syncTransform(t, r, token, imageID, `{"frame":1}`, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Frames the image does not have should be rejected")
+}
+
+func TestAsyncTransformationIntegration(t *testing.T) {
+	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
+		t.Skip("Skipping integration test; RUN_INTEGRATION_TESTS not set to true")
+	}
+
+	// Run jobs in-process so the test does not need a broker or a worker.
+	t.Setenv("QUEUE_DRIVER", "memory")
+	t.Setenv("QUEUE_RETRY_BASE_DELAY", "100ms")
+
+	c, err := container.NewContainer()
+	require.NoError(t, err)
+	defer c.Close()
+
+	migrationDir := "../../migrations"
+	err = database.RunMigrations(context.Background(), c.DB, migrationDir)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	authMiddleware := c.AuthMiddleware.Handle()
+	r.POST("/images", authMiddleware, c.ImageHandler.Upload)
+	r.POST("/images/:id/transform", authMiddleware, c.ImageHandler.Transform)
+	r.GET("/images/:id/jobs/:jobId", authMiddleware, c.ImageHandler.GetJob)
+
+	token := getTestToken(t, r, c)
+	imageID := uploadTestImage(t, r, token)
+
+	// 1. Request Async Transformation
+	specJSON := []byte(`{"resize":{"width":50,"height":50},"format":"png"}`)
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/images/%s/transform", imageID), bytes.NewBuffer(specJSON))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	var accepted struct {
+		JobID  string `json:"job_id"`
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	require.NotEmpty(t, accepted.JobID)
+	assert.Equal(t, "queued", accepted.Status)
+
+	// 2. Poll the job until it settles
+	var job struct {
+		Status    string `json:"status"`
+		Error     string `json:"error"`
+		VariantID string `json:"variant_id"`
+	}
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/images/%s/jobs/%s", imageID, accepted.JobID), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		if job.Status == "succeeded" || job.Status == "failed" {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	assert.Equal(t, "succeeded", job.Status, job.Error)
+	assert.NotEmpty(t, job.VariantID)
+}
+
+func TestBatchTransformationIntegration(t *testing.T) {
+	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
+		t.Skip("Skipping integration test; RUN_INTEGRATION_TESTS not set to true")
+	}
+	t.Setenv("QUEUE_DRIVER", "memory")
+
+	c, err := container.NewContainer()
+	require.NoError(t, err)
+	defer c.Close()
+
+	err = database.RunMigrations(context.Background(), c.DB, "../../migrations")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	authMiddleware := c.AuthMiddleware.Handle()
+	r.POST("/images", authMiddleware, c.ImageHandler.Upload)
+	r.POST("/images/:id/transform/batch", authMiddleware, c.ImageHandler.TransformBatch)
+	r.GET("/images/:id/jobs/:jobId", authMiddleware, c.ImageHandler.GetJob)
+
+	token := getTestToken(t, r, c)
+	imageID := uploadTestImage(t, r, token)
+
+	type result struct {
+		Index       int    `json:"index"`
+		SpecHash    string `json:"spec_hash"`
+		Status      string `json:"status"`
+		JobID       string `json:"job_id"`
+		DuplicateOf *int   `json:"duplicate_of"`
+		Error       string `json:"error"`
+		Variant     *struct {
+			ID string `json:"id"`
+		} `json:"variant"`
+	}
+	batch := func(query, body string) (int, []result) {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/images/%s/transform/batch%s", imageID, query), bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var resp struct {
+			Results []result `json:"results"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Results
+	}
+
+	t.Run("Sync Batch Dedupes And Reports Failures", func(t *testing.T) {
+		code, results := batch("?sync=true", `{"specs":[
+			{"resize":{"width":30,"height":30},"format":"png"},
+			{"resize":{"width":40,"height":40},"format":"png"},
+			{"resize":{"width":30,"height":30},"format":"png"},
+			{"crop":{"width":5000,"height":5000}}
+		]}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, results, 4)
+
+		assert.Equal(t, "succeeded", results[0].Status, results[0].Error)
+		assert.Equal(t, "succeeded", results[1].Status, results[1].Error)
+		require.NotNil(t, results[2].DuplicateOf)
+		assert.Equal(t, 0, *results[2].DuplicateOf)
+		require.NotNil(t, results[2].Variant)
+		assert.Equal(t, results[0].Variant.ID, results[2].Variant.ID)
+		assert.Equal(t, "failed", results[3].Status)
+		assert.NotEmpty(t, results[3].Error)
+	})
+
+	t.Run("Async Batch Queues One Job Per Spec", func(t *testing.T) {
+		code, results := batch("", `{"preset":"thumbnails"}`)
+		require.Equal(t, http.StatusAccepted, code)
+		require.Len(t, results, 3)
+
+		for _, res := range results {
+			require.NotEmpty(t, res.JobID, res.Error)
+
+			var job struct {
+				Status string `json:"status"`
+				Error  string `json:"error"`
+			}
+			deadline := time.Now().Add(15 * time.Second)
+			for time.Now().Before(deadline) {
+				req, _ := http.NewRequest("GET", fmt.Sprintf("/images/%s/jobs/%s", imageID, res.JobID), nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+				if job.Status == "succeeded" || job.Status == "failed" {
+					break
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			assert.Equal(t, "succeeded", job.Status, job.Error)
+		}
+	})
+
+	t.Run("Invalid Batches Are Rejected", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"preset":"nope"}`, `{"preset":"responsive","specs":[{"format":"png"}]}`} {
+			code, _ := batch("?sync=true", body)
+			assert.Equal(t, http.StatusBadRequest, code, body)
+		}
+	})
+}
+
 func getTestToken(t *testing.T, r *gin.Engine, c *container.Container) string {
 	username := fmt.Sprintf("testuser_%d", os.Getpid())
 	password := "Password123!"
@@ -155,3 +392,50 @@ func uploadTestImage(t *testing.T, r *gin.Engine, token string) string {
 	require.NoError(t, err)
 	return resp.ID
 }
+
+// setupSyncTransform routes uploads and transforms, running jobs in-process,
+// and uploads a test image. It returns the router, a token and the image ID.
+func setupSyncTransform(t *testing.T) (*gin.Engine, string, string) {
+	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
+		t.Skip("Skipping integration test; RUN_INTEGRATION_TESTS not set to true")
+	}
+	t.Setenv("QUEUE_DRIVER", "memory")
+
+	c, err := container.NewContainer()
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+
+	err = database.RunMigrations(context.Background(), c.DB, "../../migrations")
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	authMiddleware := c.AuthMiddleware.Handle()
+	r.POST("/images", authMiddleware, c.ImageHandler.Upload)
+	r.POST("/images/:id/transform", authMiddleware, c.ImageHandler.Transform)
+
+	token := getTestToken(t, r, c)
+	return r, token, uploadTestImage(t, r, token)
+}
+
+// syncTransform requests a sync transform of the image, sending accept as
+// the Accept header unless it is empty, and decodes the variant returned.
+func syncTransform(t *testing.T, r *gin.Engine, token, imageID, spec, accept string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/images/%s/transform?sync=true", imageID), strings.NewReader(spec))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var result map[string]interface{}
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	}
+	return w, result
+}